@@ -0,0 +1,126 @@
+package bearychattest
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	bearychat "github.com/nanmu42/bearychat-go"
+	"github.com/nanmu42/bearychat-go/openapi"
+)
+
+const (
+	// How long assertion helpers wait for asynchronous clients.
+	DEFAULT_WAIT_TIMEOUT = time.Second
+)
+
+// WaitTimeout is how long assertion helpers wait, DEFAULT_WAIT_TIMEOUT by default.
+var WaitTimeout = DEFAULT_WAIT_TIMEOUT
+
+// eventually polls cond until it holds or WaitTimeout passes.
+func eventually(cond func() bool) bool {
+	deadline := time.Now().Add(WaitTimeout)
+	for {
+		if cond() {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// WaitConnected waits for n websocket connections since started.
+func (s *Server) WaitConnected(t testing.TB, n int) {
+	t.Helper()
+
+	if !eventually(func() bool { return s.Connections() >= n }) {
+		t.Fatalf("expected %d connections, got %d", n, s.Connections())
+	}
+}
+
+// AssertReceived waits for a websocket message matching match, and returns it.
+func (s *Server) AssertReceived(t testing.TB, match func(m bearychat.RTMMessage) bool) bearychat.RTMMessage {
+	t.Helper()
+
+	var matched bearychat.RTMMessage
+	found := eventually(func() bool {
+		for _, m := range s.Received() {
+			if match(m) {
+				matched = m
+				return true
+			}
+		}
+		return false
+	})
+	if !found {
+		t.Fatalf("no matched message received, got %v", s.Received())
+	}
+	return matched
+}
+
+// AssertMessage waits for a message with text in vchannel, and returns it.
+func (s *Server) AssertMessage(t testing.TB, vchannelID, text string) *openapi.Message {
+	t.Helper()
+
+	var matched *openapi.Message
+	found := eventually(func() bool {
+		for _, m := range s.Messages(vchannelID) {
+			if m.Text != nil && *m.Text == text {
+				matched = m
+				return true
+			}
+		}
+		return false
+	})
+	if !found {
+		texts := []string{}
+		for _, m := range s.Messages(vchannelID) {
+			texts = append(texts, *m.Text)
+		}
+		t.Fatalf("no message %q in %s, got %q", text, vchannelID, texts)
+	}
+	return matched
+}
+
+// AssertRequested waits for a request to api path, e.g. `channel.create`, and returns it.
+func (s *Server) AssertRequested(t testing.TB, path string) Request {
+	t.Helper()
+
+	var matched Request
+	found := eventually(func() bool {
+		for _, r := range s.Requests() {
+			if r.Path == path {
+				matched = r
+				return true
+			}
+		}
+		return false
+	})
+	if !found {
+		t.Fatalf("%s not requested", path)
+	}
+	return matched
+}
+
+// AssertMembers checks channel members, regardless of order.
+func (s *Server) AssertMembers(t testing.TB, channelID string, members ...string) {
+	t.Helper()
+
+	for _, c := range s.Channels() {
+		if c.ID != channelID {
+			continue
+		}
+		got := append([]string{}, c.Members...)
+		expected := append([]string{}, members...)
+		sort.Strings(got)
+		sort.Strings(expected)
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected members %v of %s, got %v", expected, channelID, got)
+		}
+		return
+	}
+	t.Errorf("channel %s not found", channelID)
+}
@@ -0,0 +1,306 @@
+package bearychattest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// CassetteMode controls how a Cassette handles requests.
+type CassetteMode string
+
+const (
+	// Requests are sent with Transport and recorded.
+	CassetteModeRecord CassetteMode = "record"
+	// Requests are answered with recorded responses, nothing is sent.
+	CassetteModeReplay CassetteMode = "replay"
+	// Requests are sent with Transport, nothing is recorded.
+	CassetteModePassthrough CassetteMode = "passthrough"
+)
+
+const (
+	// Replacement of scrubbed secrets.
+	DEFAULT_CASSETTE_SCRUBBED = "SCRUBBED"
+)
+
+// CassetteRequest is a recorded http request.
+type CassetteRequest struct {
+	Method string `json:"method"`
+	// Path with query, e.g. `/v1/channel.info?channel_id=%3D1&token=SCRUBBED`
+	Path string `json:"path"`
+	Body string `json:"body,omitempty"`
+}
+
+// CassetteResponse is a recorded http response.
+type CassetteResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	// Set to `base64` when body is not valid utf-8, e.g. downloaded files.
+	BodyEncoding string `json:"body_encoding,omitempty"`
+}
+
+// CassetteInteraction is a recorded request and its response.
+type CassetteInteraction struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+// Cassette is a http.RoundTripper recording http exchanges to a file, and
+// replaying them offline later. Bind it to clients with its Client:
+//
+//      cassette, _ := bearychattest.NewCassette("testdata/channels.json", bearychattest.CassetteModeReplay)
+//      client := openapi.NewClient(token, openapi.NewClientWithHTTPClient(cassette.Client()))
+//      rtmClient, _ := bearychat.NewRTMClient(rtmToken, bearychat.WithRTMHTTPClient(cassette.Client()))
+//
+// Call Save after recording. Requests are matched with recorded ones on
+// method, path (with query) and body, each recorded interaction is replayed once
+// in recorded order. Token query parameters are scrubbed before recording
+// and matching, and so are Secrets wherever they appear.
+type Cassette struct {
+	// Where interactions are saved to and loaded from.
+	Path string
+
+	Mode CassetteMode
+
+	// Transport sending requests in record and passthrough mode.
+	// Use http.DefaultTransport by default.
+	Transport http.RoundTripper
+
+	// Query parameters to scrub, `token` by default.
+	ScrubParams []string
+
+	// Secrets to scrub from paths, bodies and headers, e.g. tokens in responses.
+	Secrets []string
+
+	lock         sync.Mutex // lock for properties below
+	interactions []CassetteInteraction
+	replayed     []bool
+}
+
+// NewCassette creates a cassette. Interactions are loaded from path in replay mode.
+func NewCassette(path string, mode CassetteMode) (*Cassette, error) {
+	c := &Cassette{
+		Path:        path,
+		Mode:        mode,
+		ScrubParams: []string{"token"},
+	}
+
+	switch mode {
+	case CassetteModeRecord, CassetteModePassthrough:
+	case CassetteModeReplay:
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("load cassette failed: %w", err)
+		}
+		if err := json.Unmarshal(b, &c.interactions); err != nil {
+			return nil, fmt.Errorf("decode cassette %s failed: %w", path, err)
+		}
+		c.replayed = make([]bool, len(c.interactions))
+	default:
+		return nil, fmt.Errorf("unknown cassette mode: %s", mode)
+	}
+
+	return c, nil
+}
+
+// Client returns a http client using the cassette.
+func (c *Cassette) Client() *http.Client {
+	return &http.Client{Transport: c}
+}
+
+// Interactions returns recorded or loaded interactions.
+func (c *Cassette) Interactions() []CassetteInteraction {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return append([]CassetteInteraction{}, c.interactions...)
+}
+
+// Unplayed returns loaded interactions not replayed yet.
+func (c *Cassette) Unplayed() []CassetteInteraction {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var unplayed []CassetteInteraction
+	for i, interaction := range c.interactions {
+		if !c.replayed[i] {
+			unplayed = append(unplayed, interaction)
+		}
+	}
+	return unplayed
+}
+
+// Save writes recorded interactions to Path, it does nothing unless in record mode.
+func (c *Cassette) Save() error {
+	if c.Mode != CassetteModeRecord {
+		return nil
+	}
+
+	c.lock.Lock()
+	b, err := json.MarshalIndent(c.interactions, "", "  ")
+	c.lock.Unlock()
+	if err != nil {
+		return fmt.Errorf("encode cassette failed: %w", err)
+	}
+
+	return os.WriteFile(c.Path, append(b, '\n'), 0644)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch c.Mode {
+	case CassetteModeRecord:
+		return c.record(req)
+	case CassetteModeReplay:
+		return c.replay(req)
+	default:
+		return c.transport().RoundTrip(req)
+	}
+}
+
+func (c *Cassette) transport() http.RoundTripper {
+	if c.Transport == nil {
+		return http.DefaultTransport
+	}
+	return c.Transport
+}
+
+func (c *Cassette) record(req *http.Request) (*http.Response, error) {
+	request, err := c.readRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.transport().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	response := CassetteResponse{
+		StatusCode: resp.StatusCode,
+		Header:     http.Header{},
+	}
+	for k, vs := range resp.Header {
+		for _, v := range vs {
+			response.Header.Add(k, c.scrub(v))
+		}
+	}
+	if utf8.Valid(body) {
+		response.Body = c.scrub(string(body))
+	} else {
+		response.Body = base64.StdEncoding.EncodeToString(body)
+		response.BodyEncoding = "base64"
+	}
+
+	c.lock.Lock()
+	c.interactions = append(c.interactions, CassetteInteraction{Request: request, Response: response})
+	c.replayed = append(c.replayed, false)
+	c.lock.Unlock()
+
+	return resp, nil
+}
+
+func (c *Cassette) replay(req *http.Request) (*http.Response, error) {
+	request, err := c.readRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for i, interaction := range c.interactions {
+		if c.replayed[i] || interaction.Request != request {
+			continue
+		}
+		c.replayed[i] = true
+
+		response := interaction.Response
+		body := []byte(response.Body)
+		if response.BodyEncoding == "base64" {
+			if body, err = base64.StdEncoding.DecodeString(response.Body); err != nil {
+				return nil, fmt.Errorf("decode recorded body failed: %w", err)
+			}
+		}
+		header := http.Header{}
+		for k, vs := range response.Header {
+			header[k] = append([]string{}, vs...)
+		}
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", response.StatusCode, http.StatusText(response.StatusCode)),
+			StatusCode:    response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no recorded interaction for %s %s", request.Method, request.Path)
+}
+
+// readRequest reads a scrubbed CassetteRequest from req, req.Body is restored.
+func (c *Cassette) readRequest(req *http.Request) (CassetteRequest, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return CassetteRequest{}, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	u := *req.URL
+	q := u.Query()
+	for _, param := range c.ScrubParams {
+		if _, present := q[param]; present {
+			q.Set(param, DEFAULT_CASSETTE_SCRUBBED)
+		}
+	}
+	u.RawQuery = q.Encode()
+	path := u.EscapedPath()
+	if u.RawQuery != "" {
+		path = path + "?" + u.RawQuery
+	}
+
+	return CassetteRequest{
+		Method: req.Method,
+		Path:   c.scrub(path),
+		Body:   c.scrub(strings.TrimSpace(string(body))),
+	}, nil
+}
+
+func (c *Cassette) scrub(s string) string {
+	for _, secret := range c.Secrets {
+		if secret == "" {
+			continue
+		}
+		s = strings.ReplaceAll(s, secret, DEFAULT_CASSETTE_SCRUBBED)
+		// secrets in paths are query escaped
+		if escaped := url.QueryEscape(secret); escaped != secret {
+			s = strings.ReplaceAll(s, escaped, DEFAULT_CASSETTE_SCRUBBED)
+		}
+	}
+	return s
+}
@@ -0,0 +1,104 @@
+package bearychattest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	bearychat "github.com/nanmu42/bearychat-go"
+	"github.com/nanmu42/bearychat-go/openapi"
+)
+
+func TestCassette(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	ctx := context.Background()
+
+	run := func(server *Server, cassette *Cassette) (*openapi.Channel, []*bearychat.User) {
+		client := openapi.NewClient(
+			server.Token,
+			openapi.NewClientWithBaseURL(server.OpenAPIClient().BaseURL),
+			openapi.NewClientWithHTTPClient(cassette.Client()),
+		)
+		rtmClient, _ := bearychat.NewRTMClient(
+			server.Token,
+			bearychat.WithRTMAPIBase(server.RTMAPIBase()),
+			bearychat.WithRTMHTTPClient(cassette.Client()),
+		)
+
+		channel, _, err := client.Channel.Create(ctx, &openapi.ChannelCreateOptions{Name: "ops"})
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		if _, _, err := client.Channel.Info(ctx, &openapi.ChannelInfoOptions{ChannelID: *channel.ID}); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		members, err := rtmClient.CurrentTeam.Members()
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		return channel, members
+	}
+
+	server := NewServer()
+	server.AddUser(User{Name: "alice"})
+	recorder, _ := NewCassette(path, CassetteModeRecord)
+	recordedChannel, recordedMembers := run(server, recorder)
+	if err := recorder.Save(); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	server.Close()
+
+	b, _ := os.ReadFile(path)
+	if strings.Contains(string(b), DEFAULT_TOKEN) {
+		t.Errorf("token should be scrubbed: %s", b)
+	}
+	if len(recorder.Interactions()) != 3 {
+		t.Errorf("unexpected interactions: %+v", recorder.Interactions())
+	}
+
+	// server is closed, responses come from cassette
+	player, err := NewCassette(path, CassetteModeReplay)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	channel, members := run(server, player)
+	if *channel.ID != *recordedChannel.ID || len(members) != len(recordedMembers) {
+		t.Errorf("unexpected replay: %+v %+v", channel, members)
+	}
+	if unplayed := player.Unplayed(); len(unplayed) != 0 {
+		t.Errorf("unexpected unplayed: %+v", unplayed)
+	}
+
+	// all recorded interactions are played
+	client := openapi.NewClient("another", openapi.NewClientWithBaseURL(server.OpenAPIClient().BaseURL), openapi.NewClientWithHTTPClient(player.Client()))
+	if _, _, err := client.Channel.Info(ctx, &openapi.ChannelInfoOptions{ChannelID: *channel.ID}); err == nil {
+		t.Errorf("expected error for unmatched request")
+	}
+}
+
+func TestCassette_Secrets(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	cassette, _ := NewCassette(filepath.Join(t.TempDir(), "cassette.json"), CassetteModeRecord)
+	cassette.Secrets = []string{server.WSHost()}
+	client, _ := bearychat.NewRTMClient(
+		server.Token,
+		bearychat.WithRTMAPIBase(server.RTMAPIBase()),
+		bearychat.WithRTMHTTPClient(cassette.Client()),
+	)
+	if _, wsHost, err := client.Start(); err != nil || wsHost != server.WSHost() {
+		t.Fatalf("unexpected start: %s %+v", wsHost, err)
+	}
+
+	interactions := cassette.Interactions()
+	if len(interactions) != 1 || strings.Contains(interactions[0].Response.Body, server.WSHost()) {
+		t.Errorf("secret should be scrubbed: %+v", interactions)
+	}
+
+	if _, err := NewCassette("cassette.json", "rewind"); err == nil {
+		t.Errorf("expected error for unknown mode")
+	}
+}
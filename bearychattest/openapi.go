@@ -0,0 +1,414 @@
+package bearychattest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/nanmu42/bearychat-go/openapi"
+)
+
+// errNotFound is replied as 404
+type errNotFound string
+
+func (e errNotFound) Error() string { return string(e) }
+
+// serveOpenAPI serves `/openapi/v1/<method>` with in-memory state.
+func (s *Server) serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	method := strings.TrimPrefix(r.URL.Path, "/openapi/v1/")
+	body, _ := io.ReadAll(r.Body)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.requests = append(s.requests, Request{
+		Method: r.Method,
+		Path:   method,
+		Query:  r.URL.Query(),
+		Body:   body,
+	})
+
+	if r.URL.Query().Get("token") != s.Token {
+		writeOpenAPIError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	handler, present := openAPIHandlers[method]
+	if !present {
+		writeOpenAPIError(w, http.StatusNotFound, fmt.Sprintf("unknown method %s", method))
+		return
+	}
+
+	result, err := handler(s, &openAPIRequest{r, body})
+	switch err.(type) {
+	case nil:
+	case errNotFound:
+		writeOpenAPIError(w, http.StatusNotFound, err.Error())
+		return
+	default:
+		writeOpenAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if result == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func writeOpenAPIError(w http.ResponseWriter, status int, reason string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"code":  status,
+		"error": reason,
+	})
+}
+
+type openAPIRequest struct {
+	*http.Request
+	body []byte
+}
+
+func (r *openAPIRequest) decode(v interface{}) error {
+	if len(bytes.TrimSpace(r.body)) == 0 {
+		return fmt.Errorf("request body is required")
+	}
+	return json.Unmarshal(r.body, v)
+}
+
+func (r *openAPIRequest) query(key string) string {
+	return r.URL.Query().Get(key)
+}
+
+type openAPIHandler func(s *Server, r *openAPIRequest) (interface{}, error)
+
+var openAPIHandlers = map[string]openAPIHandler{
+	"team.info": func(s *Server, r *openAPIRequest) (interface{}, error) {
+		return &openapi.Team{
+			ID:        strp(s.team.Id),
+			Subdomain: strp(s.team.Subdomain),
+			Name:      strp(s.team.Name),
+		}, nil
+	},
+
+	"user.info": func(s *Server, r *openAPIRequest) (interface{}, error) {
+		u := s.user(r.query("user_id"))
+		if u == nil {
+			return nil, errNotFound("user not found")
+		}
+		return u.openapiUser(s.team.Id), nil
+	},
+	"user.list": func(s *Server, r *openAPIRequest) (interface{}, error) {
+		users := make([]*openapi.User, 0, len(s.users))
+		for _, u := range s.users {
+			users = append(users, u.openapiUser(s.team.Id))
+		}
+		return users, nil
+	},
+	"user.me": func(s *Server, r *openAPIRequest) (interface{}, error) {
+		return s.user(DEFAULT_USER_ID).openapiUser(s.team.Id), nil
+	},
+
+	"channel.info": func(s *Server, r *openAPIRequest) (interface{}, error) {
+		c := s.channel(r.query("channel_id"))
+		if c == nil {
+			return nil, errNotFound("channel not found")
+		}
+		return c.openapiChannel(s.team.Id), nil
+	},
+	"channel.list": func(s *Server, r *openAPIRequest) (interface{}, error) {
+		channels := make([]*openapi.Channel, 0, len(s.channels))
+		for _, c := range s.channels {
+			channels = append(channels, c.openapiChannel(s.team.Id))
+		}
+		return channels, nil
+	},
+	"channel.create": func(s *Server, r *openAPIRequest) (interface{}, error) {
+		var opt openapi.ChannelCreateOptions
+		if err := r.decode(&opt); err != nil {
+			return nil, err
+		}
+		for _, c := range s.channels {
+			if c.Name == opt.Name {
+				return nil, fmt.Errorf("channel %s exists", opt.Name)
+			}
+		}
+		c := &Channel{
+			ID:         s.nextID("=c"),
+			VChannelID: s.nextID("=vc"),
+			Name:       opt.Name,
+			Members:    []string{DEFAULT_USER_ID},
+		}
+		if opt.Topic != nil {
+			c.Topic = *opt.Topic
+		}
+		if opt.Private != nil {
+			c.Private = *opt.Private
+		}
+		s.channels = append(s.channels, c)
+		return c.openapiChannel(s.team.Id), nil
+	},
+	"channel.archive": func(s *Server, r *openAPIRequest) (interface{}, error) {
+		var opt openapi.ChannelArchiveOptions
+		return s.updateChannel(r, &opt, &opt.ChannelID, func(c *Channel) { c.Archived = true })
+	},
+	"channel.unarchive": func(s *Server, r *openAPIRequest) (interface{}, error) {
+		var opt openapi.ChannelUnarchiveOptions
+		return s.updateChannel(r, &opt, &opt.ChannelID, func(c *Channel) { c.Archived = false })
+	},
+	"channel.join": func(s *Server, r *openAPIRequest) (interface{}, error) {
+		var opt openapi.ChannelJoinOptions
+		return s.updateChannel(r, &opt, &opt.ChannelID, func(c *Channel) {
+			c.Members = addMember(c.Members, DEFAULT_USER_ID)
+		})
+	},
+	"channel.leave": func(s *Server, r *openAPIRequest) (interface{}, error) {
+		var opt openapi.ChannelLeaveOptions
+		_, err := s.updateChannel(r, &opt, &opt.ChannelID, func(c *Channel) {
+			c.Members = removeMember(c.Members, DEFAULT_USER_ID)
+		})
+		return nil, err
+	},
+	"channel.invite": func(s *Server, r *openAPIRequest) (interface{}, error) {
+		var opt openapi.ChannelInviteOptions
+		_, err := s.updateChannel(r, &opt, &opt.ChannelID, func(c *Channel) {
+			c.Members = addMember(c.Members, opt.InviteUserID)
+		})
+		return nil, err
+	},
+	"channel.kick": func(s *Server, r *openAPIRequest) (interface{}, error) {
+		var opt openapi.ChannelKickOptions
+		_, err := s.updateChannel(r, &opt, &opt.ChannelID, func(c *Channel) {
+			c.Members = removeMember(c.Members, opt.KickUserID)
+		})
+		return nil, err
+	},
+
+	"p2p.info": func(s *Server, r *openAPIRequest) (interface{}, error) {
+		for _, p := range s.p2ps {
+			if *p.ID == r.query("p2p_channel_id") {
+				return p, nil
+			}
+		}
+		return nil, errNotFound("p2p not found")
+	},
+	"p2p.list": func(s *Server, r *openAPIRequest) (interface{}, error) {
+		return append([]*openapi.P2P{}, s.p2ps...), nil
+	},
+	"p2p.create": func(s *Server, r *openAPIRequest) (interface{}, error) {
+		var opt openapi.P2PCreateOptions
+		if err := r.decode(&opt); err != nil {
+			return nil, err
+		}
+		u := s.user(opt.UserID)
+		if u == nil {
+			return nil, errNotFound("user not found")
+		}
+		for _, p := range s.p2ps {
+			if *p.VChannelID == u.VChannelID {
+				return p, nil
+			}
+		}
+		p2pType := openapi.VChannelTypeP2P
+		p := &openapi.P2P{
+			ID:            strp(s.nextID("=p")),
+			TeamID:        strp(s.team.Id),
+			VChannelID:    strp(u.VChannelID),
+			Type:          &p2pType,
+			IsActive:      boolp(true),
+			IsMember:      boolp(true),
+			MemberUserIDs: []string{DEFAULT_USER_ID, u.ID},
+		}
+		s.p2ps = append(s.p2ps, p)
+		return p, nil
+	},
+
+	"message.info": func(s *Server, r *openAPIRequest) (interface{}, error) {
+		m, _ := s.message(r.query("vchannel_id"), openapi.MessageKey(r.query("message_key")))
+		if m == nil {
+			return nil, errNotFound("message not found")
+		}
+		return m, nil
+	},
+	"message.create": func(s *Server, r *openAPIRequest) (interface{}, error) {
+		var opt openapi.MessageCreateOptions
+		if err := r.decode(&opt); err != nil {
+			return nil, err
+		}
+		if opt.VChannelID == "" {
+			return nil, fmt.Errorf("vchannel_id is required")
+		}
+		return s.addMessage(opt.VChannelID, DEFAULT_USER_ID, opt.Text, opt.Attachments), nil
+	},
+	"message.delete": func(s *Server, r *openAPIRequest) (interface{}, error) {
+		var opt openapi.MessageDeleteOptions
+		if err := r.decode(&opt); err != nil {
+			return nil, err
+		}
+		_, i := s.message(opt.VChannelID, opt.Key)
+		if i < 0 {
+			return nil, errNotFound("message not found")
+		}
+		messages := s.messages[opt.VChannelID]
+		s.messages[opt.VChannelID] = append(messages[:i:i], messages[i+1:]...)
+		return nil, nil
+	},
+	"message.query": func(s *Server, r *openAPIRequest) (interface{}, error) {
+		var opt openapi.MessageQueryOptions
+		if err := r.decode(&opt); err != nil {
+			return nil, err
+		}
+		if opt.Query == nil {
+			return nil, fmt.Errorf("query is required")
+		}
+		return &openapi.MessageQueryResult{
+			Messages: queryMessages(s.messages[opt.VChannelID], opt.Query),
+		}, nil
+	},
+
+	"message_pin.list": func(s *Server, r *openAPIRequest) (interface{}, error) {
+		return append([]*openapi.MessagePin{}, s.pins[r.query("vchannel_id")]...), nil
+	},
+	"message_pin.create": func(s *Server, r *openAPIRequest) (interface{}, error) {
+		var opt openapi.MessagePinCreateOptions
+		if err := r.decode(&opt); err != nil {
+			return nil, err
+		}
+		m, _ := s.message(opt.VChannelID, opt.MessageKey)
+		if m == nil {
+			return nil, errNotFound("message not found")
+		}
+		key := opt.MessageKey
+		pin := &openapi.MessagePin{
+			ID:         strp(s.nextID("=pin")),
+			TeamID:     strp(s.team.Id),
+			UID:        strp(DEFAULT_USER_ID),
+			VchannelID: strp(opt.VChannelID),
+			MessageID:  m.ID,
+			MessageKey: &key,
+		}
+		s.pins[opt.VChannelID] = append(s.pins[opt.VChannelID], pin)
+		return pin, nil
+	},
+	"message_pin.delete": func(s *Server, r *openAPIRequest) (interface{}, error) {
+		var opt openapi.MessagePinDeleteOptions
+		if err := r.decode(&opt); err != nil {
+			return nil, err
+		}
+		pins := s.pins[opt.VChannelID]
+		for i, pin := range pins {
+			if *pin.ID == opt.PinID {
+				s.pins[opt.VChannelID] = append(pins[:i:i], pins[i+1:]...)
+				return nil, nil
+			}
+		}
+		return nil, errNotFound("pin not found")
+	},
+
+	"rtm.start": func(s *Server, r *openAPIRequest) (interface{}, error) {
+		return &openapi.RTMStart{
+			WebSocketHost: strp(s.WSHost()),
+			User:          s.user(DEFAULT_USER_ID).openapiUser(s.team.Id),
+		}, nil
+	},
+}
+
+// updateChannel decodes opt with channel id, and updates the channel.
+func (s *Server) updateChannel(r *openAPIRequest, opt interface{}, channelID *string, update func(c *Channel)) (interface{}, error) {
+	if err := r.decode(opt); err != nil {
+		return nil, err
+	}
+	c := s.channel(*channelID)
+	if c == nil {
+		return nil, errNotFound("channel not found")
+	}
+	update(c)
+	return c.openapiChannel(s.team.Id), nil
+}
+
+// message finds message by key, index is -1 if not found.
+func (s *Server) message(vchannelID string, key openapi.MessageKey) (*openapi.Message, int) {
+	for i, m := range s.messages[vchannelID] {
+		if *m.Key == key {
+			return m, i
+		}
+	}
+	return nil, -1
+}
+
+// queryMessages implements latest, since and window queries.
+// The message of since key is included, as the API does.
+func queryMessages(messages []*openapi.Message, query *openapi.MessageQuery) []*openapi.Message {
+	sorted := append([]*openapi.Message(nil), messages...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return *sorted[i].CreatedTS < *sorted[j].CreatedTS
+	})
+
+	switch {
+	case query.Latest != nil:
+		limit := len(sorted)
+		if query.Latest.Limit != nil && int(*query.Latest.Limit) < limit {
+			limit = int(*query.Latest.Limit)
+		}
+		return sorted[len(sorted)-limit:]
+
+	case query.Since != nil:
+		since := query.Since
+		pivot := len(sorted)
+		for i, m := range sorted {
+			if (since.SinceKey != nil && *m.Key == *since.SinceKey) ||
+				(since.SinceKey == nil && since.SinceTS != nil && *m.CreatedTS >= *since.SinceTS) {
+				pivot = i
+				break
+			}
+		}
+		from, to := pivot, pivot
+		if since.Forward != nil {
+			to = minInt(len(sorted), pivot+int(*since.Forward))
+		}
+		if since.Backward != nil {
+			from = maxInt(0, pivot-int(*since.Backward))
+		}
+		return sorted[from:to]
+
+	case query.Window != nil:
+		window := query.Window
+		var result []*openapi.Message
+		for _, m := range sorted {
+			if window.FromKey != nil && *m.Key < *window.FromKey ||
+				window.ToKey != nil && *m.Key > *window.ToKey ||
+				window.FromTS != nil && *m.CreatedTS < *window.FromTS ||
+				window.ToTS != nil && *m.CreatedTS > *window.ToTS {
+				continue
+			}
+			result = append(result, m)
+		}
+		if window.Forward != nil && int(*window.Forward) < len(result) {
+			result = result[:*window.Forward]
+		}
+		return result
+	}
+
+	return nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
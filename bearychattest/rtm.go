@@ -0,0 +1,272 @@
+package bearychattest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	bearychat "github.com/nanmu42/bearychat-go"
+)
+
+// serveRTMAPI serves `/rtm/<resource>` with RTM api response envelope.
+func (s *Server) serveRTMAPI(w http.ResponseWriter, r *http.Request) {
+	resource := strings.TrimPrefix(r.URL.Path, "/rtm/")
+	body, _ := io.ReadAll(r.Body)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.requests = append(s.requests, Request{
+		Method: r.Method,
+		Path:   resource,
+		Query:  r.URL.Query(),
+		Body:   body,
+	})
+
+	if r.URL.Query().Get("token") != s.Token {
+		writeRTMAPIResponse(w, http.StatusUnauthorized, nil, "invalid token")
+		return
+	}
+
+	switch resource {
+	case "start":
+		writeRTMAPIResponse(w, http.StatusOK, map[string]interface{}{
+			"user":    s.user(DEFAULT_USER_ID).rtmUser(s.team.Id),
+			"ws_host": s.WSHost(),
+		}, "")
+	case "message":
+		var m bearychat.RTMIncoming
+		if err := json.Unmarshal(body, &m); err != nil {
+			writeRTMAPIResponse(w, http.StatusBadRequest, nil, err.Error())
+			return
+		}
+		if err := m.Validate(); err != nil {
+			writeRTMAPIResponse(w, http.StatusBadRequest, nil, err.Error())
+			return
+		}
+		s.addMessage(m.VChannelId, DEFAULT_USER_ID, m.Text, bearychat.ToMessageAttachments(m.Attachments))
+		writeRTMAPIResponse(w, http.StatusOK, nil, "")
+	case "v1/current_team.info":
+		writeRTMAPIResponse(w, http.StatusOK, s.team, "")
+	case "v1/current_team.members":
+		members := make([]*bearychat.User, 0, len(s.users))
+		for _, u := range s.users {
+			members = append(members, u.rtmUser(s.team.Id))
+		}
+		writeRTMAPIResponse(w, http.StatusOK, members, "")
+	case "v1/current_team.channels":
+		channels := make([]*bearychat.Channel, 0, len(s.channels))
+		for _, c := range s.channels {
+			channels = append(channels, c.rtmChannel(s.team.Id))
+		}
+		writeRTMAPIResponse(w, http.StatusOK, channels, "")
+	case "v1/user.info":
+		u := s.user(r.URL.Query().Get("user_id"))
+		if u == nil {
+			writeRTMAPIResponse(w, http.StatusNotFound, nil, "user not found")
+			return
+		}
+		writeRTMAPIResponse(w, http.StatusOK, u.rtmUser(s.team.Id), "")
+	case "v1/channel.info":
+		c := s.channel(r.URL.Query().Get("channel_id"))
+		if c == nil {
+			writeRTMAPIResponse(w, http.StatusNotFound, nil, "channel not found")
+			return
+		}
+		writeRTMAPIResponse(w, http.StatusOK, c.rtmChannel(s.team.Id), "")
+	default:
+		writeRTMAPIResponse(w, http.StatusNotFound, nil, "unknown resource "+resource)
+	}
+}
+
+func writeRTMAPIResponse(w http.ResponseWriter, status int, result interface{}, reason string) {
+	response := map[string]interface{}{"code": 0}
+	if reason != "" {
+		response["code"] = status
+		response["error"] = reason
+	}
+	if result != nil {
+		response["result"] = result
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// rtmHub manages websocket connections.
+type rtmHub struct {
+	server   *Server
+	upgrader websocket.Upgrader
+
+	lock     sync.Mutex // lock for properties below
+	conns    map[*rtmConn]bool
+	scripted []bearychat.RTMMessage
+	received []bearychat.RTMMessage
+	connects int
+}
+
+type rtmConn struct {
+	conn      *websocket.Conn
+	writeLock sync.Mutex
+}
+
+func (c *rtmConn) write(m bearychat.RTMMessage) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	return c.conn.WriteJSON(withoutRaw(m))
+}
+
+func newRTMHub(s *Server) *rtmHub {
+	return &rtmHub{
+		server: s,
+		conns:  map[*rtmConn]bool{},
+	}
+}
+
+func (h *rtmHub) serveWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	c := &rtmConn{conn: conn}
+
+	h.lock.Lock()
+	h.conns[c] = true
+	h.connects = h.connects + 1
+	scripted := h.scripted
+	h.scripted = nil
+	h.lock.Unlock()
+
+	defer func() {
+		h.lock.Lock()
+		delete(h.conns, c)
+		h.lock.Unlock()
+		conn.Close()
+	}()
+
+	for _, m := range scripted {
+		if err := c.write(m); err != nil {
+			return
+		}
+	}
+
+	for {
+		_, raw, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		m := bearychat.RTMMessage{}
+		if err := json.Unmarshal(raw, &m); err != nil {
+			continue
+		}
+		h.handle(c, m)
+	}
+}
+
+// handle replies ping with pong, other messages with `reply` of the same call_id.
+// Chat messages are stored as sent by the token owner.
+func (h *rtmHub) handle(c *rtmConn, m bearychat.RTMMessage) {
+	if m.Type() == bearychat.RTMMessageTypePing {
+		c.write(bearychat.RTMMessage{
+			"type":    bearychat.RTMMessageTypePong,
+			"call_id": m["call_id"],
+		})
+		return
+	}
+
+	h.lock.Lock()
+	h.received = append(h.received, m)
+	h.lock.Unlock()
+
+	if m.IsChatMessage() {
+		vchannelID, _ := m["vchannel_id"].(string)
+		text, _ := m["text"].(string)
+		h.server.lock.Lock()
+		h.server.addMessage(vchannelID, DEFAULT_USER_ID, text, nil)
+		h.server.lock.Unlock()
+	}
+
+	if callID, present := m["call_id"]; present {
+		c.write(bearychat.RTMMessage{
+			"type":    bearychat.RTMMessageTypeReply,
+			"call_id": callID,
+			"code":    0,
+			"status":  bearychat.RTMMessageTypeOk,
+		})
+	}
+}
+
+// Push sends messages to connected clients.
+func (s *Server) Push(messages ...bearychat.RTMMessage) {
+	s.rtm.lock.Lock()
+	conns := make([]*rtmConn, 0, len(s.rtm.conns))
+	for c := range s.rtm.conns {
+		conns = append(conns, c)
+	}
+	s.rtm.lock.Unlock()
+
+	for _, c := range conns {
+		for _, m := range messages {
+			c.write(m)
+		}
+	}
+}
+
+// Script queues messages to send to the next connected client,
+// right after it connects.
+func (s *Server) Script(messages ...bearychat.RTMMessage) {
+	s.rtm.lock.Lock()
+	defer s.rtm.lock.Unlock()
+
+	s.rtm.scripted = append(s.rtm.scripted, messages...)
+}
+
+// Disconnect closes websocket connections, e.g. to test reconnecting.
+func (s *Server) Disconnect() {
+	s.rtm.disconnect()
+}
+
+// Connections returns count of websocket connections since started.
+func (s *Server) Connections() int {
+	s.rtm.lock.Lock()
+	defer s.rtm.lock.Unlock()
+
+	return s.rtm.connects
+}
+
+// Received returns websocket messages received from clients, pings excluded.
+func (s *Server) Received() []bearychat.RTMMessage {
+	s.rtm.lock.Lock()
+	defer s.rtm.lock.Unlock()
+
+	return append([]bearychat.RTMMessage(nil), s.rtm.received...)
+}
+
+func (h *rtmHub) disconnect() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for c := range h.conns {
+		c.conn.Close()
+	}
+}
+
+// withoutRaw drops JSONRawTag before encoding.
+func withoutRaw(m bearychat.RTMMessage) bearychat.RTMMessage {
+	if _, present := m[bearychat.JSONRawTag]; !present {
+		return m
+	}
+
+	copied := bearychat.RTMMessage{}
+	for k, v := range m {
+		if k != bearychat.JSONRawTag {
+			copied[k] = v
+		}
+	}
+	return copied
+}
@@ -0,0 +1,387 @@
+// Package bearychattest implements an in-process fake BearyChat server for tests.
+//
+// The fake keeps team, users, channels, p2ps, messages and pins in memory,
+// and serves RTM api, RTM websocket and OpenAPI endpoints on top of them:
+//
+//      server := bearychattest.NewServer()
+//      defer server.Close()
+//
+//      alice := server.AddUser(bearychattest.User{Name: "alice"})
+//      ops := server.AddChannel(bearychattest.Channel{Name: "ops", Members: []string{alice.ID}})
+//
+//      rtmClient, _ := server.RTMClient()
+//      client := server.OpenAPIClient()
+//
+// State is changed by API calls, and can be inspected with assertion helpers.
+//
+// Cassette records http exchanges with real servers, and replays them offline.
+package bearychattest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	bearychat "github.com/nanmu42/bearychat-go"
+	"github.com/nanmu42/bearychat-go/openapi"
+)
+
+const (
+	// Token accepted by the server.
+	DEFAULT_TOKEN = "bearychattest-token"
+
+	// ID of the user owning DEFAULT_TOKEN, e.g. the robot under test.
+	DEFAULT_USER_ID = "=bot"
+)
+
+// User in fake team.
+type User struct {
+	ID         string
+	Name       string
+	FullName   string
+	Email      string
+	Role       string
+	Type       string
+	VChannelID string
+	Online     bool
+}
+
+// Channel in fake team.
+type Channel struct {
+	ID         string
+	VChannelID string
+	Name       string
+	Topic      string
+	Private    bool
+	Archived   bool
+	// Member user ids
+	Members []string
+}
+
+// Request is a recorded http request.
+type Request struct {
+	Method string
+	// Path without api prefix, e.g. `channel.create`, `start`
+	Path  string
+	Query url.Values
+	Body  []byte
+}
+
+// Server is a fake BearyChat server.
+type Server struct {
+	// Token accepted by the server, DEFAULT_TOKEN by default.
+	// Requests with other tokens are rejected.
+	Token string
+
+	httpServer *httptest.Server
+
+	lock     sync.Mutex // lock for properties below
+	seq      int
+	team     *bearychat.Team
+	users    []*User
+	channels []*Channel
+	p2ps     []*openapi.P2P
+	messages map[string][]*openapi.Message
+	pins     map[string][]*openapi.MessagePin
+	requests []Request
+
+	rtm *rtmHub
+}
+
+// NewServer starts a fake server with a team and the token owner user.
+func NewServer() *Server {
+	s := &Server{
+		Token: DEFAULT_TOKEN,
+		team: &bearychat.Team{
+			Id:        "=team",
+			Subdomain: "bearychattest",
+			Name:      "bearychattest",
+		},
+		messages: map[string][]*openapi.Message{},
+		pins:     map[string][]*openapi.MessagePin{},
+	}
+	s.rtm = newRTMHub(s)
+	s.users = append(s.users, &User{
+		ID:         DEFAULT_USER_ID,
+		Name:       "bot",
+		Role:       bearychat.UserRoleNormal,
+		Type:       bearychat.UserTypeAssistant,
+		VChannelID: "=vc" + DEFAULT_USER_ID[1:],
+		Online:     true,
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rtm/", s.serveRTMAPI)
+	mux.HandleFunc("/openapi/v1/", s.serveOpenAPI)
+	mux.HandleFunc("/ws", s.rtm.serveWS)
+	s.httpServer = httptest.NewServer(mux)
+
+	return s
+}
+
+// Close closes websocket connections and shuts down the server.
+func (s *Server) Close() {
+	s.rtm.disconnect()
+	s.httpServer.Close()
+}
+
+// URL of the server.
+func (s *Server) URL() string {
+	return s.httpServer.URL
+}
+
+// RTMAPIBase returns rtm api base for bearychat.WithRTMAPIBase.
+func (s *Server) RTMAPIBase() string {
+	return s.httpServer.URL + "/rtm"
+}
+
+// OpenAPIBaseURL returns base url for openapi.NewClientWithBaseURL.
+func (s *Server) OpenAPIBaseURL() *url.URL {
+	u, _ := url.Parse(s.httpServer.URL + "/openapi/v1/")
+	return u
+}
+
+// WSHost returns websocket host returned by `rtm.start`.
+func (s *Server) WSHost() string {
+	return "ws" + strings.TrimPrefix(s.httpServer.URL, "http") + "/ws"
+}
+
+// RTMClient creates a rtm client talking to the server.
+func (s *Server) RTMClient() (*bearychat.RTMClient, error) {
+	return bearychat.NewRTMClient(s.Token, bearychat.WithRTMAPIBase(s.RTMAPIBase()))
+}
+
+// OpenAPIClient creates an openapi client talking to the server.
+func (s *Server) OpenAPIClient() *openapi.Client {
+	return openapi.NewClient(s.Token, openapi.NewClientWithBaseURL(s.OpenAPIBaseURL()))
+}
+
+// AddUser adds a user to team, missing ids are generated.
+func (s *Server) AddUser(u User) User {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if u.ID == "" {
+		u.ID = s.nextID("=u")
+	}
+	if u.VChannelID == "" {
+		u.VChannelID = s.nextID("=vc")
+	}
+	if u.Name == "" {
+		u.Name = u.ID[1:]
+	}
+	if u.Role == "" {
+		u.Role = bearychat.UserRoleNormal
+	}
+	if u.Type == "" {
+		u.Type = bearychat.UserTypeNormal
+	}
+	user := u
+	s.users = append(s.users, &user)
+	return u
+}
+
+// AddChannel adds a channel to team, missing ids are generated.
+// The token owner is always a member.
+func (s *Server) AddChannel(c Channel) Channel {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if c.ID == "" {
+		c.ID = s.nextID("=c")
+	}
+	if c.VChannelID == "" {
+		c.VChannelID = s.nextID("=vc")
+	}
+	c.Members = addMember(append([]string(nil), c.Members...), DEFAULT_USER_ID)
+	channel := c
+	s.channels = append(s.channels, &channel)
+	return c
+}
+
+// Users returns team users.
+func (s *Server) Users() []User {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	users := make([]User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, *u)
+	}
+	return users
+}
+
+// Channels returns team channels.
+func (s *Server) Channels() []Channel {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	channels := make([]Channel, 0, len(s.channels))
+	for _, c := range s.channels {
+		channel := *c
+		channel.Members = append([]string(nil), c.Members...)
+		channels = append(channels, channel)
+	}
+	return channels
+}
+
+// Messages returns messages of vchannel, oldest first.
+// Messages sent via RTM api, RTM websocket and OpenAPI are all recorded.
+func (s *Server) Messages(vchannelID string) []*openapi.Message {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return append([]*openapi.Message(nil), s.messages[vchannelID]...)
+}
+
+// Requests returns recorded http requests.
+func (s *Server) Requests() []Request {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+// addMessage stores a message sent by uid, should be called with lock held.
+func (s *Server) addMessage(vchannelID, uid, text string, attachments []openapi.MessageAttachment) *openapi.Message {
+	now := time.Now()
+	s.seq = s.seq + 1
+
+	ts := openapi.NewVChannelTS(now)
+	key := openapi.MessageKey(fmt.Sprintf("%d.%04d", ts, s.seq))
+	id := fmt.Sprintf("=m%d", s.seq)
+	subtype := openapi.MessageSubtypeNormal
+	message := &openapi.Message{
+		ID:          &id,
+		Key:         &key,
+		TeamID:      strp(s.team.Id),
+		UID:         &uid,
+		VchannelID:  &vchannelID,
+		Text:        &text,
+		Subtype:     &subtype,
+		CreatedTS:   &ts,
+		Created:     &openapi.Time{Time: now},
+		Updated:     &openapi.Time{Time: now},
+		Attachments: attachments,
+	}
+	s.messages[vchannelID] = append(s.messages[vchannelID], message)
+	return message
+}
+
+// nextID should be called with lock held.
+func (s *Server) nextID(prefix string) string {
+	s.seq = s.seq + 1
+	return fmt.Sprintf("%s%d", prefix, s.seq)
+}
+
+func (s *Server) user(id string) *User {
+	for _, u := range s.users {
+		if u.ID == id {
+			return u
+		}
+	}
+	return nil
+}
+
+func (s *Server) channel(id string) *Channel {
+	for _, c := range s.channels {
+		if c.ID == id {
+			return c
+		}
+	}
+	return nil
+}
+
+func (u *User) rtmUser(teamID string) *bearychat.User {
+	conn := "offline"
+	if u.Online {
+		conn = "connected"
+	}
+	return &bearychat.User{
+		Id:         u.ID,
+		TeamId:     teamID,
+		VChannelId: u.VChannelID,
+		Name:       u.Name,
+		FullName:   u.FullName,
+		Email:      u.Email,
+		Role:       u.Role,
+		Type:       u.Type,
+		Conn:       conn,
+	}
+}
+
+func (u *User) openapiUser(teamID string) *openapi.User {
+	role := openapi.UserRole(u.Role)
+	userType := openapi.UserType(u.Type)
+	return &openapi.User{
+		ID:       strp(u.ID),
+		TeamID:   strp(teamID),
+		Email:    strp(u.Email),
+		Name:     strp(u.Name),
+		FullName: strp(u.FullName),
+		Type:     &userType,
+		Role:     &role,
+	}
+}
+
+func (c *Channel) rtmChannel(teamID string) *bearychat.Channel {
+	return &bearychat.Channel{
+		Id:         c.ID,
+		TeamId:     teamID,
+		VChannelId: c.VChannelID,
+		Name:       c.Name,
+		IsPrivate:  c.Private,
+		Topic:      c.Topic,
+	}
+}
+
+func (c *Channel) openapiChannel(teamID string) *openapi.Channel {
+	channelType := openapi.VChannelTypeChannel
+	return &openapi.Channel{
+		ID:            strp(c.ID),
+		TeamID:        strp(teamID),
+		VChannelID:    strp(c.VChannelID),
+		Name:          strp(c.Name),
+		Type:          &channelType,
+		Private:       boolp(c.Private),
+		General:       boolp(false),
+		Topic:         strp(c.Topic),
+		IsMember:      boolp(hasMember(c.Members, DEFAULT_USER_ID)),
+		IsActive:      boolp(!c.Archived),
+		MemberUserIDs: append([]string{}, c.Members...),
+	}
+}
+
+func hasMember(members []string, uid string) bool {
+	for _, m := range members {
+		if m == uid {
+			return true
+		}
+	}
+	return false
+}
+
+func addMember(members []string, uid string) []string {
+	if hasMember(members, uid) {
+		return members
+	}
+	return append(members, uid)
+}
+
+func removeMember(members []string, uid string) []string {
+	kept := members[:0]
+	for _, m := range members {
+		if m != uid {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+func strp(s string) *string { return &s }
+
+func boolp(b bool) *bool { return &b }
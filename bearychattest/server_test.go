@@ -0,0 +1,162 @@
+package bearychattest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	bearychat "github.com/nanmu42/bearychat-go"
+	"github.com/nanmu42/bearychat-go/openapi"
+)
+
+func TestServer_RTMAPI(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	alice := server.AddUser(User{Name: "alice", Email: "alice@example.com"})
+	ops := server.AddChannel(Channel{Name: "ops", Members: []string{alice.ID}})
+
+	client, _ := server.RTMClient()
+	user, wsHost, err := client.Start()
+	if err != nil || user.Id != DEFAULT_USER_ID || wsHost != server.WSHost() {
+		t.Fatalf("unexpected start: %+v %s %+v", user, wsHost, err)
+	}
+
+	members, err := client.CurrentTeam.Members()
+	if err != nil || len(members) != 2 || members[1].Name != "alice" {
+		t.Errorf("unexpected members: %+v %+v", members, err)
+	}
+	if c, err := client.Channel.Info(ops.ID); err != nil || c.Name != "ops" {
+		t.Errorf("unexpected channel: %+v %+v", c, err)
+	}
+	if _, err := client.User.Info("=missing"); err == nil {
+		t.Errorf("expected error for missing user")
+	}
+
+	if err := client.Incoming(bearychat.RTMIncoming{Text: "hello", VChannelId: ops.VChannelID}); err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+	server.AssertMessage(t, ops.VChannelID, "hello")
+
+	bad, _ := bearychat.NewRTMClient("bad", bearychat.WithRTMAPIBase(server.RTMAPIBase()))
+	if _, _, err := bad.Start(); err == nil {
+		t.Errorf("expected error for invalid token")
+	}
+}
+
+func TestServer_RTMLoop(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	alice := server.AddUser(User{Name: "alice"})
+	server.Script(bearychat.RTMMessage{
+		"type":        bearychat.RTMMessageTypeP2PMessage,
+		"uid":         alice.ID,
+		"vchannel_id": alice.VChannelID,
+		"text":        "hi",
+	})
+
+	loop, _ := bearychat.NewRTMLoop(server.WSHost())
+	if err := loop.Start(); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	defer loop.Stop()
+	messageC, _ := loop.ReadC()
+
+	expect := func(mtype bearychat.RTMMessageType) bearychat.RTMMessage {
+		select {
+		case m := <-messageC:
+			if m.Type() != mtype {
+				t.Fatalf("expected %s, got %+v", mtype, m)
+			}
+			return m
+		case <-time.After(time.Second):
+			t.Fatalf("expected %s, got nothing", mtype)
+		}
+		return nil
+	}
+
+	incoming := expect(bearychat.RTMMessageTypeP2PMessage)
+	if incoming["text"] != "hi" {
+		t.Errorf("unexpected scripted message: %+v", incoming)
+	}
+
+	loop.Ping()
+	expect(bearychat.RTMMessageTypePong)
+
+	loop.Send(incoming.Reply("hello"))
+	reply := expect(bearychat.RTMMessageTypeReply)
+	if reply["call_id"] != float64(2) {
+		t.Errorf("unexpected reply: %+v", reply)
+	}
+	server.AssertMessage(t, alice.VChannelID, "hello")
+	server.AssertReceived(t, func(m bearychat.RTMMessage) bool {
+		return m["text"] == "hello"
+	})
+
+	server.Push(bearychat.RTMMessage{"type": bearychat.RTMMessageTypeUpdateUserConnection})
+	expect(bearychat.RTMMessageTypeUpdateUserConnection)
+}
+
+func TestServer_OpenAPI(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	alice := server.AddUser(User{Name: "alice", Email: "alice@example.com"})
+	client := server.OpenAPIClient()
+	ctx := context.Background()
+
+	channel, _, err := client.Channel.Create(ctx, &openapi.ChannelCreateOptions{Name: "ops"})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if _, _, err := client.Channel.Invite(ctx, &openapi.ChannelInviteOptions{
+		ChannelID:    *channel.ID,
+		InviteUserID: alice.ID,
+	}); err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+	server.AssertMembers(t, *channel.ID, DEFAULT_USER_ID, alice.ID)
+	server.AssertRequested(t, "channel.invite")
+
+	for _, text := range []string{"a", "b", "c"} {
+		if _, _, err := client.Message.Create(ctx, &openapi.MessageCreateOptions{
+			VChannelID: *channel.VChannelID,
+			Text:       text,
+		}); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+	}
+	b := server.AssertMessage(t, *channel.VChannelID, "b")
+
+	result, _, err := client.Message.Query(ctx, &openapi.MessageQueryOptions{
+		VChannelID: *channel.VChannelID,
+		Query: &openapi.MessageQuery{Since: &openapi.MessageQueryBySince{
+			SinceKey: b.Key,
+			Forward:  openapi.MessageQueryWithForward(10),
+		}},
+	})
+	if err != nil || len(result.Messages) != 2 || *result.Messages[1].Text != "c" {
+		t.Errorf("unexpected query result: %+v %+v", result, err)
+	}
+
+	if _, _, err := client.MessagePin.Create(ctx, &openapi.MessagePinCreateOptions{
+		VChannelID: *channel.VChannelID,
+		MessageKey: *b.Key,
+	}); err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+	pins, _, _ := client.MessagePin.List(ctx, &openapi.MessagePinListOptions{VChannelID: *channel.VChannelID})
+	if len(pins) != 1 || *pins[0].MessageKey != *b.Key {
+		t.Errorf("unexpected pins: %+v", pins)
+	}
+
+	p2p, _, err := client.P2P.Create(ctx, &openapi.P2PCreateOptions{UserID: alice.ID})
+	if err != nil || *p2p.VChannelID != alice.VChannelID {
+		t.Errorf("unexpected p2p: %+v %+v", p2p, err)
+	}
+
+	if _, _, err := client.Channel.Info(ctx, &openapi.ChannelInfoOptions{ChannelID: "=missing"}); err == nil {
+		t.Errorf("expected error for missing channel")
+	}
+}
@@ -62,6 +62,11 @@ func main() {
 	user, wsHost, err := rtmClient.Start()
 	checkErr(err)
 
+	directory, err := bearychat.NewDirectory(rtmClient)
+	checkErr(err)
+	directory.Start()
+	defer directory.Stop()
+
 	rtmLoop, err := bearychat.NewRTMLoop(wsHost)
 	checkErr(err)
 
@@ -82,7 +87,12 @@ func main() {
 		case err := <-errC:
 			checkErr(err)
 			return
-		case message := <-messageC:
+		case message, ok := <-messageC:
+			if !ok {
+				checkErr(bearychat.ErrRTMLoopClosed)
+				return
+			}
+			directory.HandleMessage(message)
 			if !message.IsChatMessage() {
 				continue
 			}
@@ -101,8 +111,12 @@ func main() {
 
 			checkErr(rtmLoop.Send(message.Refer("🙊")))
 		case <-tickTock.C:
-			user, err := rtmClient.User.Info(config.randomVictim())
-			checkErr(err)
+			victimUID := config.randomVictim()
+			user, ok := directory.User(victimUID)
+			if !ok {
+				log.Printf("user %s not found", victimUID)
+				continue
+			}
 
 			log.Printf("insulting user %s", user.Name)
 			checkErr(rtmLoop.Send(config.insultMessage(user)))
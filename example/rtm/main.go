@@ -4,8 +4,12 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/nanmu42/bearychat-go"
 )
@@ -19,48 +23,42 @@ func init() {
 func main() {
 	flag.Parse()
 
-	context, err := bearychat.NewRTMContext(rtmToken)
+	rtm, err := bearychat.NewRTMContext(rtmToken)
 	if err != nil {
 		log.Fatal(err)
 		return
 	}
 
-	err, messageC, errC := context.Run()
-	if err != nil {
-		log.Fatal(err)
-		return
-	}
-
-	for {
-		select {
-		case err := <-errC:
-			log.Printf("rtm loop error: %+v", err)
-			if err := context.Loop.Stop(); err != nil {
-				log.Fatal(err)
-			}
-			return
-		case message := <-messageC:
-			if !message.IsChatMessage() {
-				continue
-			}
-
-			// from self
-			if message.IsFromUID(context.UID()) {
-				continue
-			}
+	rtm.OnConnect(func(c *bearychat.RTMContext) {
+		log.Printf("connected as %s", c.User().Name)
+	})
+	rtm.OnDisconnect(func(c *bearychat.RTMContext, err error) {
+		log.Printf("disconnected: %+v", err)
+	})
+	go func() {
+		for err := range rtm.ErrC() {
+			log.Printf("rtm error: %+v", err)
+		}
+	}()
 
-			log.Printf(
-				"received: %s from %s",
-				message["text"],
-				message["uid"],
-			)
+	rtm.HandleChat(func(ctx context.Context, c *bearychat.RTMContext, message bearychat.RTMMessage) {
+		log.Printf(
+			"received: %s from %s",
+			message["text"],
+			message["uid"],
+		)
 
-			// only reply mentioned myself
-			if mentioned, content := message.ParseMentionUID(context.UID()); mentioned {
-				if err := context.Loop.Send(message.Refer(content)); err != nil {
-					log.Fatal(err)
-				}
+		// only reply mentioned myself
+		if mentioned, content := message.ParseMentionUID(c.UID()); mentioned {
+			if err := c.Send(message.Refer(content)); err != nil {
+				log.Printf("reply failed: %+v", err)
 			}
 		}
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := rtm.Run(ctx); err != nil {
+		log.Fatal(err)
 	}
 }
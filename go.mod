@@ -1,5 +1,7 @@
 module github.com/nanmu42/bearychat-go
 
+go 1.21
+
 require (
 	github.com/gorilla/websocket v1.4.0
 	github.com/pkg/errors v0.8.0
@@ -3,10 +3,7 @@ package bearychat
 import (
 	"bytes"
 	"encoding/json"
-	"fmt"
 	"io"
-
-	"github.com/pkg/errors"
 )
 
 // Incoming message builder.
@@ -43,22 +40,10 @@ func (m Incoming) Build() (io.Reader, error) {
 }
 
 // Validate fields.
+//
+// Returns ValidationErrors listing every violation found.
 func (m Incoming) Validate() error {
-	if m.Text == "" {
-		return fmt.Errorf("`text` is required for incoming message")
-	}
-
-	for i, a := range m.Attachments {
-		if err := a.Validate(); err != nil {
-			return errors.Wrapf(
-				err,
-				"#%d incoming attachment validate failed",
-				i,
-			)
-		}
-	}
-
-	return nil
+	return m.validate().err()
 }
 
 // IncomingAttachment contains incoming attachment fields.
@@ -71,21 +56,7 @@ type IncomingAttachment struct {
 
 // Validate fields.
 func (a IncomingAttachment) Validate() error {
-	if a.Title == "" && a.Text == "" {
-		return fmt.Errorf("`title`/`text` is required for incoming attachment")
-	}
-
-	for i, im := range a.Images {
-		if err := im.Validate(); err != nil {
-			return errors.Wrapf(
-				err,
-				"#%d incoming image validate failed",
-				i,
-			)
-		}
-	}
-
-	return nil
+	return a.validate("").err()
 }
 
 // IncomingAttachmentImage contains attachment image fields.
@@ -95,9 +66,5 @@ type IncomingAttachmentImage struct {
 
 // Validate fields.
 func (i IncomingAttachmentImage) Validate() error {
-	if i.URL == "" {
-		return fmt.Errorf("`url` is required for incoming image")
-	}
-
-	return nil
+	return i.validate("").err()
 }
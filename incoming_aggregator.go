@@ -0,0 +1,307 @@
+package bearychat
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DEFAULT_AGGREGATOR_WINDOW          = time.Minute
+	DEFAULT_AGGREGATOR_MAX_PER_CHANNEL = 10
+)
+
+// IncomingAggregator coalesces incoming messages before sending them
+// with a WebhookClient.
+//
+// Messages sharing the same key (channel + title by default) within a
+// window are collapsed into one message with the first text, a count and
+// all of their attachments. Every channel receives at most `maxPerChannel` messages
+// per window, the rest are folded into a single summary message.
+//
+//      a, _ := NewIncomingAggregator(
+//              NewIncomingWebhookClient("YOUR WEBHOOK URL"),
+//              WithAggregatorWindow(30 * time.Second),
+//      )
+//      a.Start()
+//      defer a.Stop()
+//
+//      a.Add(Incoming{Text: "disk usage is high", Channel: "ops"})
+type IncomingAggregator struct {
+	client        WebhookClient
+	window        time.Duration
+	maxPerChannel int
+	keyFunc       func(Incoming) string
+
+	lock   sync.Mutex // lock for properties below
+	groups map[string]*incomingGroup
+	order  []string
+	stopC  chan struct{}
+	doneC  chan struct{}
+
+	errC chan error
+}
+
+type incomingGroup struct {
+	message     Incoming
+	count       int
+	attachments []IncomingAttachment
+}
+
+type incomingAggregatorSetter func(*IncomingAggregator) error
+
+// WithAggregatorWindow sets the coalescing window.
+func WithAggregatorWindow(window time.Duration) incomingAggregatorSetter {
+	return func(a *IncomingAggregator) error {
+		if window <= 0 {
+			return errors.New("aggregator window should be positive")
+		}
+		a.window = window
+		return nil
+	}
+}
+
+// WithAggregatorMaxPerChannel sets max messages sent to one channel per window.
+func WithAggregatorMaxPerChannel(max int) incomingAggregatorSetter {
+	return func(a *IncomingAggregator) error {
+		if max <= 0 {
+			return errors.New("aggregator max messages per channel should be positive")
+		}
+		a.maxPerChannel = max
+		return nil
+	}
+}
+
+// WithAggregatorKey sets the function used to group messages.
+func WithAggregatorKey(keyFunc func(Incoming) string) incomingAggregatorSetter {
+	return func(a *IncomingAggregator) error {
+		if keyFunc == nil {
+			return errors.New("aggregator key func is required")
+		}
+		a.keyFunc = keyFunc
+		return nil
+	}
+}
+
+// IncomingAggregateKey groups messages by channel and title, which is
+// the first attachment's title, or text if there is none.
+func IncomingAggregateKey(m Incoming) string {
+	title := m.Text
+	if len(m.Attachments) > 0 && m.Attachments[0].Title != "" {
+		title = m.Attachments[0].Title
+	}
+	return m.Channel + "\x00" + title
+}
+
+// NewIncomingAggregator creates an aggregator sending via client.
+func NewIncomingAggregator(client WebhookClient, setters ...incomingAggregatorSetter) (*IncomingAggregator, error) {
+	if client == nil {
+		return nil, errors.New("webhook client is required")
+	}
+
+	a := &IncomingAggregator{
+		client:        client,
+		window:        DEFAULT_AGGREGATOR_WINDOW,
+		maxPerChannel: DEFAULT_AGGREGATOR_MAX_PER_CHANNEL,
+		keyFunc:       IncomingAggregateKey,
+
+		groups: map[string]*incomingGroup{},
+
+		errC: make(chan error, 1024),
+	}
+	for _, setter := range setters {
+		if err := setter(a); err != nil {
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
+
+// Add queues a message, it will be sent on next flush.
+func (a *IncomingAggregator) Add(m Incoming) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	key := a.keyFunc(m)
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	g, present := a.groups[key]
+	if !present {
+		g = &incomingGroup{message: m}
+		a.groups[key] = g
+		a.order = append(a.order, key)
+	}
+	g.count = g.count + 1
+	g.attachments = append(g.attachments, m.Attachments...)
+
+	return nil
+}
+
+// Pending returns count of queued messages.
+func (a *IncomingAggregator) Pending() int {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	pending := 0
+	for _, g := range a.groups {
+		pending = pending + g.count
+	}
+	return pending
+}
+
+// Flush sends all queued messages. Sending continues on failure,
+// the first error is returned.
+func (a *IncomingAggregator) Flush() error {
+	a.lock.Lock()
+	groups, order := a.groups, a.order
+	a.groups = map[string]*incomingGroup{}
+	a.order = nil
+	a.lock.Unlock()
+
+	var (
+		firstErr    error
+		sent        = map[string]int{}
+		suppressed  = map[string][]*incomingGroup{}
+		channelSeen []string
+	)
+	for _, key := range order {
+		g := groups[key]
+		channel := g.message.Channel
+		if sent[channel] >= a.maxPerChannel {
+			if len(suppressed[channel]) == 0 {
+				channelSeen = append(channelSeen, channel)
+			}
+			suppressed[channel] = append(suppressed[channel], g)
+			continue
+		}
+
+		sent[channel] = sent[channel] + 1
+		if err := a.send(g.build()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	for _, channel := range channelSeen {
+		if err := a.send(a.summary(channel, suppressed[channel])); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// Start flushes queued messages every window in background.
+// Flush errors are sent to ErrC.
+func (a *IncomingAggregator) Start() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.stopC != nil {
+		return
+	}
+	a.stopC = make(chan struct{})
+	a.doneC = make(chan struct{})
+
+	go a.loop(a.stopC, a.doneC)
+}
+
+// Stop stops the background flushing and flushes remaining messages.
+func (a *IncomingAggregator) Stop() error {
+	a.lock.Lock()
+	stopC, doneC := a.stopC, a.doneC
+	a.stopC, a.doneC = nil, nil
+	a.lock.Unlock()
+
+	if stopC != nil {
+		close(stopC)
+		<-doneC
+	}
+
+	return a.Flush()
+}
+
+// ErrC returns error channel for background flushing.
+func (a *IncomingAggregator) ErrC() chan error {
+	return a.errC
+}
+
+func (a *IncomingAggregator) loop(stopC, doneC chan struct{}) {
+	defer close(doneC)
+
+	ticker := time.NewTicker(a.window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopC:
+			return
+		case <-ticker.C:
+			if err := a.Flush(); err != nil {
+				select {
+				case a.errC <- err:
+				default:
+				}
+			}
+		}
+	}
+}
+
+func (a *IncomingAggregator) send(m Incoming) error {
+	payload, err := m.Build()
+	if err != nil {
+		return errors.Wrap(err, "build aggregated message failed")
+	}
+
+	resp, err := a.client.Send(payload)
+	if err != nil {
+		return errors.Wrap(err, "send aggregated message failed")
+	}
+	if !resp.IsOk() {
+		return errors.Errorf("send aggregated message failed: %d %s", resp.Code, resp.Error)
+	}
+
+	return nil
+}
+
+func (a *IncomingAggregator) summary(channel string, groups []*incomingGroup) Incoming {
+	total := 0
+	lines := make([]string, 0, len(groups))
+	for _, g := range groups {
+		total = total + g.count
+		lines = append(lines, fmt.Sprintf("%s (x%d)", g.message.Text, g.count))
+	}
+
+	return Incoming{
+		Text: fmt.Sprintf(
+			"%d messages suppressed in the last %s",
+			total,
+			a.window,
+		),
+		Channel: channel,
+		Attachments: []IncomingAttachment{
+			{Text: strings.Join(lines, "\n")},
+		},
+	}
+}
+
+// build collapses group into one message, keeping the first attachments
+// allowed by MAX_INCOMING_ATTACHMENTS.
+func (g *incomingGroup) build() Incoming {
+	m := g.message
+	m.Attachments = g.attachments
+	if len(m.Attachments) > MAX_INCOMING_ATTACHMENTS {
+		m.Attachments = m.Attachments[:MAX_INCOMING_ATTACHMENTS]
+	}
+	if g.count > 1 {
+		m.Text = fmt.Sprintf("%s (x%d)", m.Text, g.count)
+	}
+
+	return m
+}
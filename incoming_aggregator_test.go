@@ -0,0 +1,179 @@
+package bearychat
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testWebhookClient records sent incoming messages.
+type testWebhookClient struct {
+	lock     sync.Mutex
+	messages []Incoming
+}
+
+func (c *testWebhookClient) SetWebhook(webhook string) WebhookClient { return c }
+
+func (c *testWebhookClient) SetHTTPClient(client *http.Client) WebhookClient { return c }
+
+func (c *testWebhookClient) Send(payload io.Reader) (*WebhookResponse, error) {
+	var m Incoming
+	if err := json.NewDecoder(payload).Decode(&m); err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.messages = append(c.messages, m)
+
+	return &WebhookResponse{StatusCode: http.StatusOK}, nil
+}
+
+func (c *testWebhookClient) sent() []Incoming {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return append([]Incoming{}, c.messages...)
+}
+
+func TestNewIncomingAggregator(t *testing.T) {
+	if _, err := NewIncomingAggregator(nil); err == nil {
+		t.Errorf("should require webhook client")
+	}
+
+	a, err := NewIncomingAggregator(&testWebhookClient{})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if a.window != DEFAULT_AGGREGATOR_WINDOW {
+		t.Errorf("unexpected window: %s", a.window)
+	}
+	if a.maxPerChannel != DEFAULT_AGGREGATOR_MAX_PER_CHANNEL {
+		t.Errorf("unexpected max per channel: %d", a.maxPerChannel)
+	}
+
+	if _, err := NewIncomingAggregator(&testWebhookClient{}, WithAggregatorWindow(0)); err == nil {
+		t.Errorf("should reject non-positive window")
+	}
+}
+
+func TestIncomingAggregator_Flush_Coalesce(t *testing.T) {
+	client := &testWebhookClient{}
+	a, _ := NewIncomingAggregator(client)
+
+	for i := 0; i < 3; i = i + 1 {
+		a.Add(Incoming{
+			Text:        "check failed",
+			Channel:     "ops",
+			Attachments: []IncomingAttachment{{Text: "host"}},
+		})
+	}
+	a.Add(Incoming{Text: "check recovered", Channel: "ops"})
+	if err := a.Add(Incoming{}); err == nil {
+		t.Errorf("should validate message")
+	}
+
+	if a.Pending() != 4 {
+		t.Errorf("unexpected pending: %d", a.Pending())
+	}
+	if err := a.Flush(); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if a.Pending() != 0 {
+		t.Errorf("unexpected pending after flush: %d", a.Pending())
+	}
+
+	sent := client.sent()
+	if len(sent) != 2 {
+		t.Fatalf("unexpected sent messages: %+v", sent)
+	}
+	if sent[0].Text != "check failed (x3)" {
+		t.Errorf("unexpected text: %s", sent[0].Text)
+	}
+	if len(sent[0].Attachments) != 3 {
+		t.Errorf("unexpected attachments: %+v", sent[0].Attachments)
+	}
+	if sent[1].Text != "check recovered" {
+		t.Errorf("unexpected text: %s", sent[1].Text)
+	}
+}
+
+func TestIncomingAggregator_Flush_CoalesceTitle(t *testing.T) {
+	client := &testWebhookClient{}
+	a, _ := NewIncomingAggregator(client)
+
+	for i := 0; i < 3; i = i + 1 {
+		a.Add(Incoming{
+			Text:        fmt.Sprintf("%d errors in last minute", i+10),
+			Channel:     "ops",
+			Attachments: []IncomingAttachment{{Title: "error rate is high", Text: "api"}},
+		})
+	}
+	a.Add(Incoming{
+		Text:        "10 errors in last minute",
+		Channel:     "dev",
+		Attachments: []IncomingAttachment{{Title: "error rate is high", Text: "api"}},
+	})
+	if err := a.Flush(); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	sent := client.sent()
+	if len(sent) != 2 {
+		t.Fatalf("unexpected sent messages: %+v", sent)
+	}
+	if sent[0].Text != "10 errors in last minute (x3)" || len(sent[0].Attachments) != 3 {
+		t.Errorf("unexpected message: %+v", sent[0])
+	}
+	if sent[1].Channel != "dev" {
+		t.Errorf("channels should not be coalesced: %+v", sent[1])
+	}
+}
+
+func TestIncomingAggregator_Flush_MaxPerChannel(t *testing.T) {
+	client := &testWebhookClient{}
+	a, _ := NewIncomingAggregator(client, WithAggregatorMaxPerChannel(1))
+
+	a.Add(Incoming{Text: "a", Channel: "ops"})
+	a.Add(Incoming{Text: "b", Channel: "ops"})
+	a.Add(Incoming{Text: "b", Channel: "ops"})
+	a.Add(Incoming{Text: "c", Channel: "dev"})
+
+	if err := a.Flush(); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	sent := client.sent()
+	if len(sent) != 3 {
+		t.Fatalf("unexpected sent messages: %+v", sent)
+	}
+	summary := sent[2]
+	if summary.Channel != "ops" {
+		t.Errorf("unexpected summary channel: %s", summary.Channel)
+	}
+	if !strings.HasPrefix(summary.Text, "2 messages suppressed") {
+		t.Errorf("unexpected summary text: %s", summary.Text)
+	}
+	if summary.Attachments[0].Text != "b (x2)" {
+		t.Errorf("unexpected summary attachment: %+v", summary.Attachments)
+	}
+}
+
+func TestIncomingAggregator_Stop(t *testing.T) {
+	client := &testWebhookClient{}
+	a, _ := NewIncomingAggregator(client)
+
+	a.Start()
+	a.Add(Incoming{Text: "a"})
+	if err := a.Stop(); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if len(client.sent()) != 1 {
+		t.Errorf("should flush on stop: %+v", client.sent())
+	}
+}
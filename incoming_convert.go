@@ -0,0 +1,237 @@
+package bearychat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nanmu42/bearychat-go/openapi"
+	"github.com/pkg/errors"
+)
+
+// Conversions between the three message shapes:
+//
+//      Incoming            (incoming webhook)
+//      RTMIncoming         (`rtm.message`)
+//      MessageCreateOptions (openapi `message.create`)
+//
+// Text and attachments survive every conversion. Conversions are lossless:
+// converting a message with fields the target shape doesn't have
+// (`notification`, `channel` and `user` of Incoming, `markdown` of
+// Incoming/RTMIncoming) fails with IncomingConversionError, clear them
+// first to drop them. The vchannel id has to be supplied when converting
+// into a shape having it.
+
+// IncomingConversionError reports set fields the target shape doesn't have.
+type IncomingConversionError struct {
+	// Target shape, e.g. `RTMIncoming`
+	Target string
+	// Fields by json name
+	Fields []string
+}
+
+func (e *IncomingConversionError) Error() string {
+	return fmt.Sprintf("converting to %s drops fields: %s", e.Target, strings.Join(e.Fields, ", "))
+}
+
+// droppedFields returns fields set but not in RTMIncoming, or also markdown
+// for MessageCreateOptions.
+func (m Incoming) droppedFields(markdown bool) []string {
+	var fields []string
+	if m.Notification != "" {
+		fields = append(fields, "notification")
+	}
+	if markdown && m.Markdown {
+		fields = append(fields, "markdown")
+	}
+	if m.Channel != "" {
+		fields = append(fields, "channel")
+	}
+	if m.User != "" {
+		fields = append(fields, "user")
+	}
+	return fields
+}
+
+// Validate fields.
+func (m RTMIncoming) Validate() error {
+	es := validateIncomingContent(m.Text, m.Markdown, m.Attachments)
+	if m.VChannelId == "" {
+		es.add("vchannel", "`vchannel` is required for rtm incoming message")
+	}
+
+	return es.err()
+}
+
+// ToIncoming converts to an incoming webhook message.
+func (m RTMIncoming) ToIncoming() Incoming {
+	return Incoming{
+		Text:        m.Text,
+		Markdown:    m.Markdown,
+		Attachments: copyIncomingAttachments(m.Attachments),
+	}
+}
+
+// ToMessageCreateOptions converts to openapi `message.create` options.
+func (m RTMIncoming) ToMessageCreateOptions() (*openapi.MessageCreateOptions, error) {
+	if m.Markdown {
+		return nil, &IncomingConversionError{Target: "MessageCreateOptions", Fields: []string{"markdown"}}
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &openapi.MessageCreateOptions{
+		VChannelID:  m.VChannelId,
+		Text:        m.Text,
+		Attachments: ToMessageAttachments(m.Attachments),
+	}, nil
+}
+
+// ToRTMIncoming converts to a `rtm.message` message sent to vchannel.
+func (m Incoming) ToRTMIncoming(vchannelID string) (RTMIncoming, error) {
+	if fields := m.droppedFields(false); len(fields) > 0 {
+		return RTMIncoming{}, &IncomingConversionError{Target: "RTMIncoming", Fields: fields}
+	}
+
+	rm := RTMIncoming{
+		Text:        m.Text,
+		VChannelId:  vchannelID,
+		Markdown:    m.Markdown,
+		Attachments: copyIncomingAttachments(m.Attachments),
+	}
+
+	return rm, rm.Validate()
+}
+
+// ToMessageCreateOptions converts to openapi `message.create` options
+// sent to vchannel.
+func (m Incoming) ToMessageCreateOptions(vchannelID string) (*openapi.MessageCreateOptions, error) {
+	if fields := m.droppedFields(true); len(fields) > 0 {
+		return nil, &IncomingConversionError{Target: "MessageCreateOptions", Fields: fields}
+	}
+
+	rm, err := m.ToRTMIncoming(vchannelID)
+	if err != nil {
+		return nil, err
+	}
+
+	return rm.ToMessageCreateOptions()
+}
+
+// IncomingFromMessageCreateOptions converts openapi `message.create` options
+// to an incoming webhook message.
+func IncomingFromMessageCreateOptions(opt *openapi.MessageCreateOptions) (Incoming, error) {
+	rm, err := RTMIncomingFromMessageCreateOptions(opt)
+	if err != nil {
+		return Incoming{}, err
+	}
+
+	return rm.ToIncoming(), nil
+}
+
+// RTMIncomingFromMessageCreateOptions converts openapi `message.create` options
+// to a `rtm.message` message.
+func RTMIncomingFromMessageCreateOptions(opt *openapi.MessageCreateOptions) (RTMIncoming, error) {
+	if opt == nil {
+		return RTMIncoming{}, errors.New("message create options is required")
+	}
+
+	rm := RTMIncoming{
+		Text:        opt.Text,
+		VChannelId:  opt.VChannelID,
+		Attachments: FromMessageAttachments(opt.Attachments),
+	}
+
+	return rm, rm.Validate()
+}
+
+// ToMessageAttachment converts to an openapi message attachment.
+func (a IncomingAttachment) ToMessageAttachment() openapi.MessageAttachment {
+	attachment := openapi.MessageAttachment{
+		Title: stringOrNil(a.Title),
+		Text:  stringOrNil(a.Text),
+		Color: stringOrNil(a.Color),
+	}
+	if a.Images != nil {
+		attachment.Images = make([]openapi.MessageAttachmentImage, 0, len(a.Images))
+		for _, im := range a.Images {
+			attachment.Images = append(
+				attachment.Images,
+				openapi.MessageAttachmentImage{Url: stringOrNil(im.URL)},
+			)
+		}
+	}
+
+	return attachment
+}
+
+// IncomingAttachmentFromMessageAttachment converts an openapi message attachment.
+func IncomingAttachmentFromMessageAttachment(a openapi.MessageAttachment) IncomingAttachment {
+	attachment := IncomingAttachment{
+		Title: stringValue(a.Title),
+		Text:  stringValue(a.Text),
+		Color: stringValue(a.Color),
+	}
+	if a.Images != nil {
+		attachment.Images = make([]IncomingAttachmentImage, 0, len(a.Images))
+		for _, im := range a.Images {
+			attachment.Images = append(
+				attachment.Images,
+				IncomingAttachmentImage{URL: stringValue(im.Url)},
+			)
+		}
+	}
+
+	return attachment
+}
+
+// ToMessageAttachments converts incoming attachments to openapi ones.
+func ToMessageAttachments(as []IncomingAttachment) []openapi.MessageAttachment {
+	attachments := make([]openapi.MessageAttachment, 0, len(as))
+	for _, a := range as {
+		attachments = append(attachments, a.ToMessageAttachment())
+	}
+	return attachments
+}
+
+// FromMessageAttachments converts openapi attachments to incoming ones.
+func FromMessageAttachments(as []openapi.MessageAttachment) []IncomingAttachment {
+	if len(as) == 0 {
+		return nil
+	}
+
+	attachments := make([]IncomingAttachment, 0, len(as))
+	for _, a := range as {
+		attachments = append(attachments, IncomingAttachmentFromMessageAttachment(a))
+	}
+	return attachments
+}
+
+func copyIncomingAttachments(as []IncomingAttachment) []IncomingAttachment {
+	if as == nil {
+		return nil
+	}
+
+	attachments := make([]IncomingAttachment, len(as))
+	for i, a := range as {
+		if a.Images != nil {
+			a.Images = append([]IncomingAttachmentImage{}, a.Images...)
+		}
+		attachments[i] = a
+	}
+	return attachments
+}
+
+func stringOrNil(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
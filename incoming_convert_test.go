@@ -0,0 +1,118 @@
+package bearychat
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nanmu42/bearychat-go/openapi"
+)
+
+func TestIncoming_Convert_RoundTrip(t *testing.T) {
+	m := Incoming{
+		Text:     "Hello, **BearyChat**",
+		Markdown: true,
+		Attachments: []IncomingAttachment{
+			{Text: "attachment 1", Color: "#cb3f20"},
+			{Title: "attachment 2"},
+			{
+				Text: "attachment 3",
+				Images: []IncomingAttachmentImage{
+					{URL: "http://img3.douban.com/icon/ul15067564-30.jpg"},
+				},
+			},
+		},
+	}
+
+	rm, err := m.ToRTMIncoming("=bw52O")
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if rm.VChannelId != "=bw52O" {
+		t.Errorf("unexpected vchannel: %s", rm.VChannelId)
+	}
+	if got := rm.ToIncoming(); !reflect.DeepEqual(got, m) {
+		t.Errorf("rtm incoming round trip: %+v", got)
+	}
+
+	m.Markdown = false
+	opt, err := m.ToMessageCreateOptions("=bw52O")
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if opt.Attachments[1].Text != nil || *opt.Attachments[1].Title != "attachment 2" {
+		t.Errorf("unexpected attachment: %+v", opt.Attachments[1])
+	}
+
+	back, err := RTMIncomingFromMessageCreateOptions(opt)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if !reflect.DeepEqual(back.Attachments, m.Attachments) {
+		t.Errorf("message create options round trip: %+v", back.Attachments)
+	}
+	if back.Text != m.Text || back.VChannelId != "=bw52O" {
+		t.Errorf("message create options round trip: %+v", back)
+	}
+}
+
+func TestIncoming_Convert_Dropped(t *testing.T) {
+	m := Incoming{
+		Text:         "test",
+		Notification: "notified",
+		Markdown:     true,
+		Channel:      "ops",
+		User:         "bob",
+	}
+
+	_, err := m.ToRTMIncoming("=bw52O")
+	if e, ok := err.(*IncomingConversionError); !ok || !reflect.DeepEqual(e.Fields, []string{"notification", "channel", "user"}) {
+		t.Errorf("unexpected error: %+v", err)
+	}
+	_, err = m.ToMessageCreateOptions("=bw52O")
+	if e, ok := err.(*IncomingConversionError); !ok || !reflect.DeepEqual(e.Fields, []string{"notification", "markdown", "channel", "user"}) {
+		t.Errorf("unexpected error: %+v", err)
+	}
+	_, err = (RTMIncoming{Text: "test", VChannelId: "=bw52O", Markdown: true}).ToMessageCreateOptions()
+	if e, ok := err.(*IncomingConversionError); !ok || e.Target != "MessageCreateOptions" || !reflect.DeepEqual(e.Fields, []string{"markdown"}) {
+		t.Errorf("unexpected error: %+v", err)
+	}
+
+	// lossless once cleared
+	m.Notification, m.Channel, m.User = "", "", ""
+	rm, err := m.ToRTMIncoming("=bw52O")
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if got := rm.ToIncoming(); !reflect.DeepEqual(got, m) {
+		t.Errorf("rtm incoming round trip: %+v", got)
+	}
+	m.Markdown = false
+	opt, err := m.ToMessageCreateOptions("=bw52O")
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if got, _ := IncomingFromMessageCreateOptions(opt); !reflect.DeepEqual(got, m) {
+		t.Errorf("message create options round trip: %+v", got)
+	}
+}
+
+func TestIncoming_Convert_Validate(t *testing.T) {
+	if _, err := (Incoming{Text: "test"}).ToRTMIncoming(""); err == nil {
+		t.Errorf("vchannel should not be empty")
+	}
+	if _, err := (Incoming{}).ToMessageCreateOptions("=bw52O"); err == nil {
+		t.Errorf("text should not be empty")
+	}
+	if _, err := IncomingFromMessageCreateOptions(nil); err == nil {
+		t.Errorf("options should not be nil")
+	}
+
+	opt := &openapi.MessageCreateOptions{
+		VChannelID:  "=bw52O",
+		Text:        "test",
+		Attachments: []openapi.MessageAttachment{{}},
+	}
+	if _, err := IncomingFromMessageCreateOptions(opt); err == nil {
+		t.Errorf("title or text should not be empty")
+	}
+}
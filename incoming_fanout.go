@@ -0,0 +1,196 @@
+package bearychat
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/nanmu42/bearychat-go/openapi"
+	"github.com/pkg/errors"
+)
+
+// FanoutDestination is somewhere an incoming message can be delivered to.
+type FanoutDestination interface {
+	// Destination name used in results
+	Name() string
+	// Deliver the message
+	Deliver(ctx context.Context, m Incoming) error
+}
+
+type webhookDestination struct {
+	name   string
+	client WebhookClient
+}
+
+// NewWebhookDestination delivers messages to an incoming webhook url.
+// Webhook url is a secret, destination is named `webhook:<host>`.
+func NewWebhookDestination(webhook string) FanoutDestination {
+	host := ""
+	if u, err := url.Parse(webhook); err == nil {
+		host = u.Host
+	}
+
+	return &webhookDestination{
+		name:   "webhook:" + host,
+		client: NewIncomingWebhookClient(webhook),
+	}
+}
+
+// NewWebhookClientDestination delivers messages via a configured webhook client.
+func NewWebhookClientDestination(name string, client WebhookClient) FanoutDestination {
+	return &webhookDestination{
+		name:   name,
+		client: client,
+	}
+}
+
+func (d *webhookDestination) Name() string {
+	return d.name
+}
+
+func (d *webhookDestination) Deliver(ctx context.Context, m Incoming) error {
+	payload, err := m.Build()
+	if err != nil {
+		return err
+	}
+
+	var resp *WebhookResponse
+	if sender, ok := d.client.(WebhookContextSender); ok {
+		resp, err = sender.SendContext(ctx, payload)
+	} else {
+		resp, err = d.client.Send(payload)
+	}
+	if err != nil {
+		return err
+	}
+	if !resp.IsOk() {
+		return errors.Errorf("webhook responded: %d %s", resp.Code, resp.Error)
+	}
+
+	return nil
+}
+
+type rtmDestination struct {
+	client     *RTMClient
+	vchannelID string
+}
+
+// NewRTMDestination delivers messages to a vchannel via `rtm.message`,
+// `notification`, `channel` and `user` of messages are ignored.
+func NewRTMDestination(client *RTMClient, vchannelID string) FanoutDestination {
+	return &rtmDestination{
+		client:     client,
+		vchannelID: vchannelID,
+	}
+}
+
+func (d *rtmDestination) Name() string {
+	return "rtm:" + d.vchannelID
+}
+
+func (d *rtmDestination) Deliver(ctx context.Context, m Incoming) error {
+	m.Notification, m.Channel, m.User = "", "", ""
+	rm, err := m.ToRTMIncoming(d.vchannelID)
+	if err != nil {
+		return err
+	}
+
+	return d.client.IncomingContext(ctx, rm)
+}
+
+type openapiDestination struct {
+	client     *openapi.Client
+	vchannelID string
+}
+
+// NewOpenAPIDestination delivers messages to a vchannel via `message.create`,
+// `notification`, `markdown`, `channel` and `user` of messages are ignored.
+func NewOpenAPIDestination(client *openapi.Client, vchannelID string) FanoutDestination {
+	return &openapiDestination{
+		client:     client,
+		vchannelID: vchannelID,
+	}
+}
+
+func (d *openapiDestination) Name() string {
+	return "openapi:" + d.vchannelID
+}
+
+func (d *openapiDestination) Deliver(ctx context.Context, m Incoming) error {
+	m.Notification, m.Markdown, m.Channel, m.User = "", false, "", ""
+	opt, err := m.ToMessageCreateOptions(d.vchannelID)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = d.client.Message.Create(ctx, opt)
+	return err
+}
+
+// FanoutResult is delivery result of one destination.
+type FanoutResult struct {
+	Destination string
+	Err         error
+}
+
+// FanoutResults are delivery results in destination order.
+type FanoutResults []FanoutResult
+
+// Err returns an error describing all failed deliveries, or nil.
+func (rs FanoutResults) Err() error {
+	var failures []string
+	for _, r := range rs {
+		if r.Err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", r.Destination, r.Err))
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+
+	return errors.Errorf(
+		"%d/%d deliveries failed: %s",
+		len(failures),
+		len(rs),
+		strings.Join(failures, "; "),
+	)
+}
+
+// Fanout delivers the same incoming message to many destinations.
+//
+//      f := NewFanout(
+//              NewWebhookDestination("TEAM A WEBHOOK URL"),
+//              NewRTMDestination(rtmClient, "=bw52O"),
+//      )
+//      if err := f.Send(ctx, m).Err(); err != nil {
+//              // some deliveries failed
+//      }
+type Fanout struct {
+	Destinations []FanoutDestination
+}
+
+// NewFanout creates a fanout sender.
+func NewFanout(destinations ...FanoutDestination) *Fanout {
+	return &Fanout{Destinations: destinations}
+}
+
+// Send delivers message to all destinations concurrently.
+func (f *Fanout) Send(ctx context.Context, m Incoming) FanoutResults {
+	results := make(FanoutResults, len(f.Destinations))
+
+	var wg sync.WaitGroup
+	for i, d := range f.Destinations {
+		results[i].Destination = d.Name()
+
+		wg.Add(1)
+		go func(i int, d FanoutDestination) {
+			defer wg.Done()
+			results[i].Err = d.Deliver(ctx, m)
+		}(i, d)
+	}
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,124 @@
+package bearychat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/nanmu42/bearychat-go/openapi"
+)
+
+func TestFanout_Send(t *testing.T) {
+	var (
+		rtmReceived     RTMIncoming
+		openapiReceived openapi.MessageCreateOptions
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/message":
+			json.NewDecoder(r.Body).Decode(&rtmReceived)
+			w.Write([]byte(`{"code":0,"result":null}`))
+		case "/v1/message.create":
+			json.NewDecoder(r.Body).Decode(&openapiReceived)
+			w.Write([]byte(`{"key":"1"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code":1,"error":"not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	rtmClient, _ := NewRTMClient(testRTMToken, WithRTMAPIBase(server.URL))
+	badRTMClient, _ := NewRTMClient(testRTMToken, WithRTMAPIBase(server.URL+"/bad"))
+	baseURL, _ := url.Parse(server.URL + "/v1/")
+	openapiClient := openapi.NewClient("foobar", openapi.NewClientWithBaseURL(baseURL))
+	webhookClient := &testWebhookClient{}
+
+	f := NewFanout(
+		NewWebhookClientDestination("webhook", webhookClient),
+		NewRTMDestination(rtmClient, "=rtm"),
+		NewOpenAPIDestination(openapiClient, "=openapi"),
+		NewRTMDestination(badRTMClient, "=bad"),
+	)
+
+	m := Incoming{
+		Text:        "deploy finished",
+		Markdown:    true,
+		Attachments: []IncomingAttachment{{Title: "v1.0.0", Color: "#00ff00"}},
+	}
+	results := f.Send(context.Background(), m)
+	if len(results) != 4 {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	for _, r := range results[:3] {
+		if r.Err != nil {
+			t.Errorf("unexpected error for %s: %+v", r.Destination, r.Err)
+		}
+	}
+	if results[3].Destination != "rtm:=bad" || results[3].Err == nil {
+		t.Errorf("expected failure for bad destination: %+v", results[3])
+	}
+	if results.Err() == nil {
+		t.Errorf("expected aggregated error")
+	}
+
+	if sent := webhookClient.sent(); len(sent) != 1 || sent[0].Text != m.Text {
+		t.Errorf("unexpected webhook messages: %+v", sent)
+	}
+	if rtmReceived.VChannelId != "=rtm" || rtmReceived.Text != m.Text || !rtmReceived.Markdown {
+		t.Errorf("unexpected rtm message: %+v", rtmReceived)
+	}
+	if openapiReceived.VChannelID != "=openapi" || *openapiReceived.Attachments[0].Title != "v1.0.0" {
+		t.Errorf("unexpected openapi message: %+v", openapiReceived)
+	}
+}
+
+func TestRTMDestination_Canceled(t *testing.T) {
+	requested := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+		w.Write([]byte(`{"code":0,"result":null}`))
+	}))
+	defer server.Close()
+
+	client, _ := NewRTMClient(testRTMToken, WithRTMAPIBase(server.URL))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := NewRTMDestination(client, "=rtm").Deliver(ctx, Incoming{Text: "hi"}); err == nil {
+		t.Errorf("expected error for canceled ctx")
+	}
+	if requested {
+		t.Errorf("canceled delivery should not be requested")
+	}
+}
+
+func TestWebhookDestination(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":0}`))
+	}))
+	defer server.Close()
+
+	d := NewWebhookDestination(server.URL + "/hook/secret")
+	if u, _ := url.Parse(server.URL); d.Name() != "webhook:"+u.Host {
+		t.Errorf("unexpected name: %s", d.Name())
+	}
+	if err := d.Deliver(context.Background(), Incoming{Text: "hi"}); err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := d.Deliver(ctx, Incoming{Text: "hi"}); err == nil {
+		t.Errorf("expected error for canceled ctx")
+	}
+}
+
+func TestFanoutResults_Err(t *testing.T) {
+	results := FanoutResults{{Destination: "a"}, {Destination: "b"}}
+	if err := results.Err(); err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+}
@@ -0,0 +1,333 @@
+package bearychat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// Colors used by `color` template func.
+const (
+	SEVERITY_COLOR_CRITICAL = "#cb3f20"
+	SEVERITY_COLOR_WARNING  = "#ffa500"
+	SEVERITY_COLOR_OK       = "#2ea44f"
+	SEVERITY_COLOR_INFO     = "#3a87ad"
+)
+
+// IncomingTemplate describes an incoming message, every string is
+// a `text/template` rendered with the data object. It's usually stored
+// in a JSON file:
+//
+//      {
+//              "text": "{{ .Service }} is {{ .Status }}",
+//              "notification": "{{ .Service }} alert",
+//              "markdown": true,
+//              "attachments": [
+//                      {
+//                              "text": "{{ escape .Detail }}",
+//                              "color": "{{ color .Severity }}",
+//                              "images": ["{{ .GraphURL }}"]
+//                      }
+//              ]
+//      }
+type IncomingTemplate struct {
+	Text         string                       `json:"text"`
+	Notification string                       `json:"notification,omitempty"`
+	Markdown     bool                         `json:"markdown,omitempty"`
+	Channel      string                       `json:"channel,omitempty"`
+	User         string                       `json:"user,omitempty"`
+	Attachments  []IncomingAttachmentTemplate `json:"attachments,omitempty"`
+}
+
+// IncomingAttachmentTemplate describes an incoming attachment.
+//
+// Attachments rendering empty title and text, and images rendering
+// empty url are left out, so they can be made conditional.
+type IncomingAttachmentTemplate struct {
+	Title  string   `json:"title,omitempty"`
+	Text   string   `json:"text,omitempty"`
+	Color  string   `json:"color,omitempty"`
+	Images []string `json:"images,omitempty"`
+}
+
+// IncomingRenderer renders named templates into incoming messages.
+//
+//      r, _ := NewIncomingRenderer()
+//      r.ParseFS(os.DirFS("templates"), "*.json")
+//      m, err := r.Render("alert", alert)
+type IncomingRenderer struct {
+	funcs template.FuncMap
+
+	lock      sync.RWMutex // lock for properties below
+	templates map[string]*incomingTemplate
+}
+
+type incomingTemplate struct {
+	spec IncomingTemplate
+	tmpl *template.Template
+}
+
+type incomingRendererSetter func(*IncomingRenderer) error
+
+// WithRendererFuncs adds template funcs, overriding builtin ones.
+func WithRendererFuncs(funcs template.FuncMap) incomingRendererSetter {
+	return func(r *IncomingRenderer) error {
+		for name, f := range funcs {
+			r.funcs[name] = f
+		}
+		return nil
+	}
+}
+
+// NewIncomingRenderer creates a renderer with builtin funcs:
+//
+//      mention  uid       -> "@<=uid=>"
+//      escape   text      -> markdown escaped text
+//      color    severity  -> hex color for critical/warning/ok/info
+func NewIncomingRenderer(setters ...incomingRendererSetter) (*IncomingRenderer, error) {
+	r := &IncomingRenderer{
+		funcs: template.FuncMap{
+			"mention": TemplateMention,
+			"escape":  TemplateEscape,
+			"color":   TemplateSeverityColor,
+		},
+
+		templates: map[string]*incomingTemplate{},
+	}
+	for _, setter := range setters {
+		if err := setter(r); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// Add parses and registers a template by name.
+func (r *IncomingRenderer) Add(name string, t IncomingTemplate) error {
+	tmpl := template.New(name).Funcs(r.funcs).Option("missingkey=error")
+
+	define := func(field, text string) error {
+		_, err := tmpl.New(field).Parse(text)
+		return errors.Wrapf(err, "parse template %s.%s failed", name, field)
+	}
+
+	if err := define("text", t.Text); err != nil {
+		return err
+	}
+	if err := define("notification", t.Notification); err != nil {
+		return err
+	}
+	if err := define("channel", t.Channel); err != nil {
+		return err
+	}
+	if err := define("user", t.User); err != nil {
+		return err
+	}
+	for i, a := range t.Attachments {
+		prefix := attachmentTemplatePrefix(i)
+		if err := define(prefix+"title", a.Title); err != nil {
+			return err
+		}
+		if err := define(prefix+"text", a.Text); err != nil {
+			return err
+		}
+		if err := define(prefix+"color", a.Color); err != nil {
+			return err
+		}
+		for j, im := range a.Images {
+			if err := define(prefix+imageTemplateName(j), im); err != nil {
+				return err
+			}
+		}
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.templates[name] = &incomingTemplate{spec: t, tmpl: tmpl}
+	return nil
+}
+
+// ParseFS loads JSON templates matching patterns from fsys,
+// templates are named after file names without extension.
+func (r *IncomingRenderer) ParseFS(fsys fs.FS, patterns ...string) error {
+	for _, pattern := range patterns {
+		filenames, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return err
+		}
+		if len(filenames) == 0 {
+			return errors.Errorf("pattern matches no files: %s", pattern)
+		}
+
+		for _, filename := range filenames {
+			b, err := fs.ReadFile(fsys, filename)
+			if err != nil {
+				return err
+			}
+			if err := r.parse(templateName(path.Base(filename)), b); err != nil {
+				return errors.Wrapf(err, "load %s failed", filename)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ParseFiles loads JSON template files, templates are named after
+// file names without extension.
+func (r *IncomingRenderer) ParseFiles(filenames ...string) error {
+	for _, filename := range filenames {
+		b, err := os.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		if err := r.parse(templateName(filepath.Base(filename)), b); err != nil {
+			return errors.Wrapf(err, "load %s failed", filename)
+		}
+	}
+
+	return nil
+}
+
+// Names returns sorted names of registered templates.
+func (r *IncomingRenderer) Names() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	names := make([]string, 0, len(r.templates))
+	for name := range r.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render renders named template with data and validates the result.
+// Referring missing map keys in data is an error.
+func (r *IncomingRenderer) Render(name string, data interface{}) (Incoming, error) {
+	r.lock.RLock()
+	t, present := r.templates[name]
+	r.lock.RUnlock()
+
+	if !present {
+		return Incoming{}, errors.Errorf("template not found: %s", name)
+	}
+
+	var renderErr error
+	render := func(field string) string {
+		if renderErr != nil {
+			return ""
+		}
+		buf := new(bytes.Buffer)
+		if err := t.tmpl.ExecuteTemplate(buf, field, data); err != nil {
+			renderErr = errors.Wrapf(err, "render template %s.%s failed", name, field)
+			return ""
+		}
+		return strings.TrimSpace(buf.String())
+	}
+
+	m := Incoming{
+		Text:         render("text"),
+		Notification: render("notification"),
+		Markdown:     t.spec.Markdown,
+		Channel:      render("channel"),
+		User:         render("user"),
+	}
+	for i, at := range t.spec.Attachments {
+		prefix := attachmentTemplatePrefix(i)
+		a := IncomingAttachment{
+			Title: render(prefix + "title"),
+			Text:  render(prefix + "text"),
+			Color: render(prefix + "color"),
+		}
+		for j := range at.Images {
+			if url := render(prefix + imageTemplateName(j)); url != "" {
+				a.Images = append(a.Images, IncomingAttachmentImage{URL: url})
+			}
+		}
+		if a.Title == "" && a.Text == "" {
+			continue
+		}
+		m.Attachments = append(m.Attachments, a)
+	}
+	if renderErr != nil {
+		return Incoming{}, renderErr
+	}
+
+	if err := m.Validate(); err != nil {
+		return Incoming{}, errors.Wrapf(err, "rendered template %s is invalid", name)
+	}
+	return m, nil
+}
+
+func (r *IncomingRenderer) parse(name string, b []byte) error {
+	var t IncomingTemplate
+	if err := json.Unmarshal(b, &t); err != nil {
+		return err
+	}
+
+	return r.Add(name, t)
+}
+
+func templateName(filename string) string {
+	return strings.TrimSuffix(filename, path.Ext(filename))
+}
+
+func attachmentTemplatePrefix(i int) string {
+	return fmt.Sprintf("attachments[%d].", i)
+}
+
+func imageTemplateName(j int) string {
+	return fmt.Sprintf("images[%d]", j)
+}
+
+// TemplateMention formats a user mention.
+func TemplateMention(uid string) string {
+	return "@<=" + uid + "=>"
+}
+
+var markdownEscaper = strings.NewReplacer(
+	"\\", "\\\\",
+	"`", "\\`",
+	"*", "\\*",
+	"_", "\\_",
+	"[", "\\[",
+	"]", "\\]",
+	"(", "\\(",
+	")", "\\)",
+	"#", "\\#",
+	">", "\\>",
+	"~", "\\~",
+	"|", "\\|",
+)
+
+// TemplateEscape escapes markdown control characters in text.
+func TemplateEscape(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+// TemplateSeverityColor maps severity to attachment color.
+func TemplateSeverityColor(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical", "error", "fatal", "down":
+		return SEVERITY_COLOR_CRITICAL
+	case "warning", "warn":
+		return SEVERITY_COLOR_WARNING
+	case "ok", "success", "resolved", "up":
+		return SEVERITY_COLOR_OK
+	default:
+		return SEVERITY_COLOR_INFO
+	}
+}
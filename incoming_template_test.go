@@ -0,0 +1,112 @@
+package bearychat
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+const testAlertTemplate = `{
+	"text": "{{ mention .Owner }} {{ .Service }} is {{ .Status }}",
+	"notification": "{{ .Service }} alert",
+	"markdown": true,
+	"attachments": [
+		{
+			"text": "{{ escape .Detail }}",
+			"color": "{{ color .Severity }}",
+			"images": ["{{ .GraphURL }}"]
+		},
+		{
+			"title": "{{ if .Runbook }}runbook{{ end }}",
+			"text": "{{ .Runbook }}"
+		}
+	]
+}`
+
+type testAlert struct {
+	Owner, Service, Status, Severity, Detail, GraphURL, Runbook string
+}
+
+func TestIncomingRenderer_Render(t *testing.T) {
+	r, err := NewIncomingRenderer()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	fsys := fstest.MapFS{
+		"templates/alert.json": {Data: []byte(testAlertTemplate)},
+	}
+	if err := r.ParseFS(fsys, "templates/*.json"); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if names := r.Names(); len(names) != 1 || names[0] != "alert" {
+		t.Errorf("unexpected names: %+v", names)
+	}
+
+	m, err := r.Render("alert", testAlert{
+		Owner:    "=bw52O",
+		Service:  "api",
+		Status:   "down",
+		Severity: "critical",
+		Detail:   "5xx_rate > 10%",
+		GraphURL: "https://example.com/graph.png",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if m.Text != "@<==bw52O=> api is down" {
+		t.Errorf("unexpected text: %s", m.Text)
+	}
+	if m.Notification != "api alert" || !m.Markdown {
+		t.Errorf("unexpected message: %+v", m)
+	}
+	if len(m.Attachments) != 1 {
+		t.Fatalf("empty attachment should be left out: %+v", m.Attachments)
+	}
+	a := m.Attachments[0]
+	if a.Text != `5xx\_rate \> 10%` {
+		t.Errorf("unexpected attachment text: %s", a.Text)
+	}
+	if a.Color != SEVERITY_COLOR_CRITICAL {
+		t.Errorf("unexpected attachment color: %s", a.Color)
+	}
+	if len(a.Images) != 1 || a.Images[0].URL != "https://example.com/graph.png" {
+		t.Errorf("unexpected attachment images: %+v", a.Images)
+	}
+}
+
+func TestIncomingRenderer_Render_Invalid(t *testing.T) {
+	r, _ := NewIncomingRenderer()
+
+	if _, err := r.Render("missing", nil); err == nil {
+		t.Errorf("should fail on unknown template")
+	}
+
+	if err := r.Add("broken", IncomingTemplate{Text: "{{ .Foo "}); err == nil {
+		t.Errorf("should fail on broken template")
+	}
+
+	r.Add("missing_key", IncomingTemplate{Text: "{{ .Foo }}"})
+	if _, err := r.Render("missing_key", map[string]string{}); err == nil {
+		t.Errorf("should fail on missing key")
+	}
+
+	r.Add("empty", IncomingTemplate{Text: "{{ .Foo }}"})
+	if _, err := r.Render("empty", map[string]string{"Foo": ""}); err == nil {
+		t.Errorf("should validate rendered message")
+	}
+}
+
+func TestTemplateSeverityColor(t *testing.T) {
+	cases := map[string]string{
+		"CRITICAL": SEVERITY_COLOR_CRITICAL,
+		"warning":  SEVERITY_COLOR_WARNING,
+		"resolved": SEVERITY_COLOR_OK,
+		"whatever": SEVERITY_COLOR_INFO,
+	}
+	for severity, color := range cases {
+		if c := TemplateSeverityColor(severity); c != color {
+			t.Errorf("unexpected color for %s: %s", severity, c)
+		}
+	}
+}
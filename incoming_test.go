@@ -32,6 +32,65 @@ func TestValidateIncoming(t *testing.T) {
 	}
 }
 
+func TestValidateIncoming_Strict(t *testing.T) {
+	m := Incoming{
+		Text:     "```\nunclosed",
+		Markdown: true,
+		Channel:  "#ops team",
+		User:     "@",
+		Attachments: []IncomingAttachment{
+			{Text: "ok", Color: "#cb3f20"},
+			{Text: "ok", Color: "red"},
+			{
+				Text: "ok",
+				Images: []IncomingAttachmentImage{
+					{URL: "ftp://example.com/a.png"},
+					{URL: "http://example.com/b.png"},
+				},
+			},
+		},
+	}
+
+	err := m.Validate()
+	es, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("expected validation errors: %+v", err)
+	}
+
+	paths := map[string]bool{}
+	for _, e := range es {
+		paths[e.Path] = true
+	}
+	expected := []string{
+		"text",
+		"channel",
+		"user",
+		"attachments[1].color",
+		"attachments[2].images[0].url",
+	}
+	for _, p := range expected {
+		if !paths[p] {
+			t.Errorf("expected violation at %s: %+v", p, es)
+		}
+	}
+	if len(es) != len(expected) {
+		t.Errorf("unexpected violations: %+v", es)
+	}
+
+	m = Incoming{Text: "test", Channel: "#所有人", User: "@bearybot"}
+	if err := m.Validate(); err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+
+	m = Incoming{Text: "test"}
+	for i := 0; i <= MAX_INCOMING_ATTACHMENTS; i = i + 1 {
+		m.Attachments = append(m.Attachments, IncomingAttachment{Text: "test"})
+	}
+	if err := m.Validate(); err == nil {
+		t.Errorf("attachments should be limited")
+	}
+}
+
 func ExampleIncoming() {
 	m := Incoming{
 		Text:         "Hello, **BearyChat",
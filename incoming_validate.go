@@ -0,0 +1,167 @@
+package bearychat
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits enforced by incoming message validation.
+const (
+	MAX_INCOMING_TEXT_LENGTH       = 5000
+	MAX_INCOMING_ATTACHMENTS       = 10
+	MAX_INCOMING_ATTACHMENT_IMAGES = 10
+	MAX_INCOMING_NAME_LENGTH       = 64
+)
+
+// ValidationError is a violation of the field at JSON path.
+type ValidationError struct {
+	// JSON path of the field, e.g. `attachments[2].images[0].url`
+	Path   string
+	Reason string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Reason)
+}
+
+// ValidationErrors lists every violation found in a message.
+type ValidationErrors []ValidationError
+
+func (es ValidationErrors) Error() string {
+	reasons := make([]string, 0, len(es))
+	for _, e := range es {
+		reasons = append(reasons, e.Error())
+	}
+
+	return fmt.Sprintf(
+		"%d validation error(s): %s",
+		len(es),
+		strings.Join(reasons, "; "),
+	)
+}
+
+// err returns nil when there is no violation.
+func (es ValidationErrors) err() error {
+	if len(es) == 0 {
+		return nil
+	}
+	return es
+}
+
+func (es *ValidationErrors) add(path, reason string, args ...interface{}) {
+	*es = append(*es, ValidationError{
+		Path:   path,
+		Reason: fmt.Sprintf(reason, args...),
+	})
+}
+
+func joinPath(prefix, field string) string {
+	if prefix == "" {
+		return field
+	}
+	return prefix + "." + field
+}
+
+var colorRegex = regexp.MustCompile("^#([0-9A-Fa-f]{3}|[0-9A-Fa-f]{6})$")
+
+// validateIncomingContent validates fields shared by all message shapes.
+func validateIncomingContent(text string, markdown bool, attachments []IncomingAttachment) ValidationErrors {
+	var es ValidationErrors
+
+	if text == "" {
+		es.add("text", "`text` is required for incoming message")
+	}
+	if l := utf8.RuneCountInString(text); l > MAX_INCOMING_TEXT_LENGTH {
+		es.add("text", "`text` is too long: %d > %d", l, MAX_INCOMING_TEXT_LENGTH)
+	}
+	if markdown && strings.Count(text, "```")%2 != 0 {
+		es.add("text", "unclosed code fence in markdown `text`")
+	}
+
+	if len(attachments) > MAX_INCOMING_ATTACHMENTS {
+		es.add("attachments", "too many attachments: %d > %d", len(attachments), MAX_INCOMING_ATTACHMENTS)
+	}
+	for i, a := range attachments {
+		es = append(es, a.validate(fmt.Sprintf("attachments[%d]", i))...)
+	}
+
+	return es
+}
+
+func (m Incoming) validate() ValidationErrors {
+	es := validateIncomingContent(m.Text, m.Markdown, m.Attachments)
+
+	if m.Channel != "" {
+		validateIncomingName(&es, "channel", strings.TrimPrefix(m.Channel, "#"))
+	}
+	if m.User != "" {
+		validateIncomingName(&es, "user", strings.TrimPrefix(m.User, "@"))
+	}
+
+	return es
+}
+
+// validateIncomingName checks channel/user names: optionally prefixed
+// with `#`/`@`, non-empty, limited in length and without spaces.
+func validateIncomingName(es *ValidationErrors, path, name string) {
+	if name == "" {
+		es.add(path, "`%s` name is empty", path)
+		return
+	}
+	if l := utf8.RuneCountInString(name); l > MAX_INCOMING_NAME_LENGTH {
+		es.add(path, "`%s` name is too long: %d > %d", path, l, MAX_INCOMING_NAME_LENGTH)
+	}
+	if strings.IndexFunc(name, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsControl(r)
+	}) >= 0 {
+		es.add(path, "`%s` name contains whitespace or control characters", path)
+	}
+}
+
+func (a IncomingAttachment) validate(prefix string) ValidationErrors {
+	var es ValidationErrors
+
+	if a.Title == "" && a.Text == "" {
+		es.add(joinPath(prefix, "title"), "`title`/`text` is required for incoming attachment")
+	}
+	if a.Color != "" && !colorRegex.MatchString(a.Color) {
+		es.add(joinPath(prefix, "color"), "`color` should be hex format like `#ffa500`: %q", a.Color)
+	}
+
+	if len(a.Images) > MAX_INCOMING_ATTACHMENT_IMAGES {
+		es.add(joinPath(prefix, "images"), "too many images: %d > %d", len(a.Images), MAX_INCOMING_ATTACHMENT_IMAGES)
+	}
+	for i, im := range a.Images {
+		es = append(es, im.validate(joinPath(prefix, fmt.Sprintf("images[%d]", i)))...)
+	}
+
+	return es
+}
+
+func (i IncomingAttachmentImage) validate(prefix string) ValidationErrors {
+	var es ValidationErrors
+	path := joinPath(prefix, "url")
+
+	if i.URL == "" {
+		es.add(path, "`url` is required for incoming image")
+		return es
+	}
+
+	u, err := url.Parse(i.URL)
+	if err != nil {
+		es.add(path, "invalid `url`: %s", err)
+		return es
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		es.add(path, "`url` should use http or https scheme: %q", i.URL)
+	}
+	if u.Host == "" {
+		es.add(path, "`url` host is required: %q", i.URL)
+	}
+
+	return es
+}
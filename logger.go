@@ -0,0 +1,27 @@
+package bearychat
+
+import (
+	"net/url"
+
+	"github.com/nanmu42/bearychat-go/openapi"
+	"github.com/pkg/errors"
+)
+
+// Logger logs structured events, *slog.Logger satisfies it:
+//
+//      client, _ := NewRTMClient("rtm-token", WithRTMLogger(slog.Default()))
+//
+// Args are alternating keys and values. Tokens and webhook urls are not logged.
+// It's the same interface as openapi.Logger, so one logger serves both.
+type Logger = openapi.Logger
+
+// nopLogger is the default Logger logging nothing.
+var nopLogger = openapi.NopLogger
+
+// redactURLError strips url with token from http client errors.
+func redactURLError(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		return errors.Wrap(urlErr.Err, urlErr.Op)
+	}
+	return err
+}
@@ -0,0 +1,54 @@
+package bearychat
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestLogger() (*slog.Logger, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	return slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})), buf
+}
+
+func TestLogger_Redacted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/start" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code":1,"error":"invalid token"}`))
+			return
+		}
+		w.Write([]byte(`{"code":0,"result":null}`))
+	}))
+	defer server.Close()
+
+	logger, buf := newTestLogger()
+
+	client, _ := NewRTMClient(testRTMToken, WithRTMAPIBase(server.URL), WithRTMLogger(logger))
+	client.Start()
+	webhook := NewIncomingWebhookClient(server.URL+"/webhook-secret", WithWebhookLogger(logger))
+	webhook.Send(strings.NewReader(`{"text":"hello"}`))
+	closed, _ := NewRTMClient(testRTMToken, WithRTMAPIBase("http://127.0.0.1:1"), WithRTMLogger(logger))
+	closed.Start()
+
+	logged := buf.String()
+	for _, expected := range []string{
+		`msg="rtm api request" method=POST resource=start`,
+		`msg="rtm api error response"`,
+		`reason="invalid token"`,
+		`msg="webhook response"`,
+		`msg="rtm api request failed"`,
+	} {
+		if !strings.Contains(logged, expected) {
+			t.Errorf("expected %s logged, got:\n%s", expected, logged)
+		}
+	}
+	for _, secret := range []string{testRTMToken, "webhook-secret"} {
+		if strings.Contains(logged, secret) {
+			t.Errorf("%s should not be logged, got:\n%s", secret, logged)
+		}
+	}
+}
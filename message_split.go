@@ -0,0 +1,316 @@
+package bearychat
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// separators used to split text, from the most preferred one.
+var splitSeparators = []string{"\n\n", "\n", " "}
+
+// tokens which should never be broken: mentions, markdown links and inline code.
+var splitProtectedRegex = regexp.MustCompile("[@#]<=[^\\s>]*=>|\\[[^\\]\\n]*\\]\\([^)\\s]*\\)|`[^`\\n]+`")
+
+const codeFence = "```"
+
+// SplitText breaks text into parts no longer than limit (in characters).
+//
+// Text is broken at paragraph, then line, then word boundaries, mentions,
+// links and inline code are kept intact, and code fences broken between
+// parts are closed and reopened. Parts are numbered like "(1/3)" on their
+// first line when text is split.
+func SplitText(text string, limit int) ([]string, error) {
+	if utf8.RuneCountInString(text) <= limit {
+		return []string{text}, nil
+	}
+
+	fenceReserve := 0
+	for _, line := range strings.Split(text, "\n") {
+		if l := utf8.RuneCountInString(line); strings.HasPrefix(strings.TrimSpace(line), codeFence) && l > fenceReserve {
+			fenceReserve = l
+		}
+	}
+	if fenceReserve > 0 {
+		// reopening line + newline + closing fence
+		fenceReserve = fenceReserve + 2 + len(codeFence)
+	}
+
+	// grow numbering reserve until parts count fits in it
+	for digits := 1; ; digits = digits + 1 {
+		budget := limit - fenceReserve - (2*digits + len("(/)\n"))
+		if budget < 1 {
+			return nil, errors.Errorf("split limit %d is too small", limit)
+		}
+
+		parts := fixCodeFences(splitPieces(text, budget, 0))
+		if len(fmt.Sprint(len(parts))) > digits {
+			continue
+		}
+
+		for i, part := range parts {
+			parts[i] = fmt.Sprintf("(%d/%d)\n%s", i+1, len(parts), part)
+		}
+		return parts, nil
+	}
+}
+
+func splitPieces(text string, budget, level int) []string {
+	if utf8.RuneCountInString(text) <= budget {
+		return []string{text}
+	}
+	if level >= len(splitSeparators) {
+		return splitHard(text, budget)
+	}
+
+	var (
+		chunks []string
+		cur    string
+	)
+	flush := func() {
+		if c := strings.TrimRight(cur, " \n"); c != "" {
+			chunks = append(chunks, c)
+		}
+		cur = ""
+	}
+
+	for _, p := range splitKeepSeparator(text, splitSeparators[level]) {
+		if utf8.RuneCountInString(p) > budget {
+			flush()
+			chunks = append(chunks, splitPieces(p, budget, level+1)...)
+			continue
+		}
+		if utf8.RuneCountInString(cur)+utf8.RuneCountInString(p) > budget {
+			flush()
+		}
+		cur = cur + p
+	}
+	flush()
+
+	return chunks
+}
+
+// splitKeepSeparator splits text after each separator, keeping it
+// and protected tokens in place.
+func splitKeepSeparator(text, sep string) []string {
+	protected := splitProtectedRegex.FindAllStringIndex(text, -1)
+
+	var parts []string
+	start := 0
+	for offset := 0; offset < len(text); {
+		i := strings.Index(text[offset:], sep)
+		if i < 0 {
+			break
+		}
+		end := offset + i + len(sep)
+		offset = end
+		if insideProtected(protected, end-len(sep)) {
+			continue
+		}
+		parts = append(parts, text[start:end])
+		start = end
+	}
+	if start < len(text) {
+		parts = append(parts, text[start:])
+	}
+
+	return parts
+}
+
+// splitHard splits text at character boundaries, moving cuts out of protected tokens.
+func splitHard(text string, budget int) []string {
+	var chunks []string
+	for utf8.RuneCountInString(text) > budget {
+		cut := 0
+		for n := 0; n < budget; n = n + 1 {
+			_, size := utf8.DecodeRuneInString(text[cut:])
+			cut = cut + size
+		}
+
+		for _, loc := range splitProtectedRegex.FindAllStringIndex(text, -1) {
+			if loc[0] < cut && cut < loc[1] {
+				// tokens longer than budget are broken, parts can't be over it
+				if loc[0] > 0 {
+					cut = loc[0]
+				}
+				break
+			}
+		}
+
+		chunks = append(chunks, text[:cut])
+		text = text[cut:]
+	}
+	if text != "" {
+		chunks = append(chunks, text)
+	}
+
+	return chunks
+}
+
+func insideProtected(protected [][]int, i int) bool {
+	for _, loc := range protected {
+		if loc[0] <= i && i < loc[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// fixCodeFences closes code fences left open at the end of a part
+// and reopens them in the next one.
+func fixCodeFences(parts []string) []string {
+	open := ""
+	for i, part := range parts {
+		if open != "" {
+			part = open + "\n" + part
+		}
+
+		open = ""
+		for _, line := range strings.Split(part, "\n") {
+			line = strings.TrimSpace(line)
+			if !strings.HasPrefix(line, codeFence) {
+				continue
+			}
+			if open == "" {
+				open = line
+			} else {
+				open = ""
+			}
+		}
+		if open != "" {
+			part = part + "\n" + codeFence
+		}
+
+		parts[i] = part
+	}
+
+	return parts
+}
+
+// Split breaks message into parts with text no longer than limit.
+// Notification is kept in the first part and attachments in the last one.
+func (m Incoming) Split(limit int) ([]Incoming, error) {
+	texts, err := SplitText(m.Text, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]Incoming, len(texts))
+	for i, text := range texts {
+		part := m
+		part.Text = text
+		if i > 0 {
+			part.Notification = ""
+		}
+		if i < len(texts)-1 {
+			part.Attachments = nil
+		}
+		parts[i] = part
+	}
+	return parts, nil
+}
+
+// Split breaks message into parts with text no longer than limit.
+// Attachments are kept in the last part.
+func (m RTMIncoming) Split(limit int) ([]RTMIncoming, error) {
+	texts, err := SplitText(m.Text, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]RTMIncoming, len(texts))
+	for i, text := range texts {
+		part := m
+		part.Text = text
+		if i < len(texts)-1 {
+			part.Attachments = nil
+		}
+		parts[i] = part
+	}
+	return parts, nil
+}
+
+// Split breaks message into parts with text no longer than limit.
+// Only the first part refers the original message, parts get
+// their own `call_id` when sending.
+func (m RTMMessage) Split(limit int) ([]RTMMessage, error) {
+	texts, err := SplitText(m.Text(), limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(texts) == 1 {
+		return []RTMMessage{m}, nil
+	}
+
+	parts := make([]RTMMessage, len(texts))
+	for i, text := range texts {
+		part := RTMMessage{}
+		for k, v := range m {
+			part[k] = v
+		}
+		part["text"] = text
+		delete(part, "call_id")
+		delete(part, JSONRawTag)
+		if i > 0 {
+			delete(part, "refer_key")
+		}
+		parts[i] = part
+	}
+	return parts, nil
+}
+
+// SendIncomingSplit splits message and sends parts in order via client.
+func SendIncomingSplit(client WebhookClient, m Incoming, limit int) error {
+	parts, err := m.Split(limit)
+	if err != nil {
+		return err
+	}
+
+	for i, part := range parts {
+		payload, err := part.Build()
+		if err != nil {
+			return errors.Wrapf(err, "build part #%d failed", i+1)
+		}
+		resp, err := client.Send(payload)
+		if err != nil {
+			return errors.Wrapf(err, "send part #%d failed", i+1)
+		}
+		if !resp.IsOk() {
+			return errors.Errorf("send part #%d failed: %d %s", i+1, resp.Code, resp.Error)
+		}
+	}
+	return nil
+}
+
+// SendRTMIncomingSplit splits message and sends parts in order via `rtm.message`.
+func SendRTMIncomingSplit(client *RTMClient, m RTMIncoming, limit int) error {
+	parts, err := m.Split(limit)
+	if err != nil {
+		return err
+	}
+
+	for i, part := range parts {
+		if err := client.Incoming(part); err != nil {
+			return errors.Wrapf(err, "send part #%d failed", i+1)
+		}
+	}
+	return nil
+}
+
+// SendRTMMessageSplit splits message and sends parts in order via rtm loop.
+func SendRTMMessageSplit(loop RTMLoop, m RTMMessage, limit int) error {
+	parts, err := m.Split(limit)
+	if err != nil {
+		return err
+	}
+
+	for i, part := range parts {
+		if err := loop.Send(part); err != nil {
+			return errors.Wrapf(err, "send part #%d failed", i+1)
+		}
+	}
+	return nil
+}
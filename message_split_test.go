@@ -0,0 +1,159 @@
+package bearychat
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitText_Short(t *testing.T) {
+	parts, err := SplitText("hello", 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if len(parts) != 1 || parts[0] != "hello" {
+		t.Errorf("unexpected parts: %q", parts)
+	}
+
+	if _, err := SplitText(strings.Repeat("a", 20), 3); err == nil {
+		t.Errorf("should reject too small limit")
+	}
+}
+
+func TestSplitText_Boundaries(t *testing.T) {
+	text := strings.Repeat("line of the first paragraph\n", 3) +
+		"\n" +
+		strings.Repeat("line of the second paragraph\n", 3)
+
+	parts, err := SplitText(text, 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if len(parts) != 2 {
+		t.Fatalf("unexpected parts: %q", parts)
+	}
+	if !strings.HasPrefix(parts[0], "(1/2)\n") || !strings.HasPrefix(parts[1], "(2/2)\n") {
+		t.Errorf("parts should be numbered: %q", parts)
+	}
+	if strings.Contains(parts[0], "second") || strings.Contains(parts[1], "first") {
+		t.Errorf("should split at paragraph boundary: %q", parts)
+	}
+	for _, part := range parts {
+		if utf8.RuneCountInString(part) > 100 {
+			t.Errorf("part is too long: %q", part)
+		}
+	}
+}
+
+func TestSplitText_Mention(t *testing.T) {
+	text := strings.Repeat("@<==bwOwr=> ", 10)
+
+	parts, err := SplitText(text, 30)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	for _, part := range parts {
+		body := part[strings.Index(part, "\n")+1:]
+		if strings.Count(body, "@<=") != strings.Count(body, "=>") {
+			t.Errorf("mention is broken: %q", part)
+		}
+		if utf8.RuneCountInString(part) > 30 {
+			t.Errorf("part is too long: %q", part)
+		}
+	}
+}
+
+func TestSplitText_LongToken(t *testing.T) {
+	for _, token := range []string{
+		"[docs](https://example.com/" + strings.Repeat("a", 80) + ")",
+		"`" + strings.Repeat("x", 80) + "`",
+	} {
+		parts, err := SplitText(token+" tail", 30)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		if len(parts) < 3 {
+			t.Errorf("token longer than limit should be broken: %q", parts)
+		}
+		for _, part := range parts {
+			if utf8.RuneCountInString(part) > 30 {
+				t.Errorf("part is too long: %q", part)
+			}
+		}
+	}
+}
+
+func TestSplitText_CodeFence(t *testing.T) {
+	text := "build log:\n```text\n" + strings.Repeat("step ok\n", 20) + "```\ndone"
+
+	parts, err := SplitText(text, 80)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if len(parts) < 2 {
+		t.Fatalf("expected split: %q", parts)
+	}
+	for _, part := range parts {
+		if strings.Count(part, codeFence)%2 != 0 {
+			t.Errorf("code fence is not closed: %q", part)
+		}
+		if utf8.RuneCountInString(part) > 80 {
+			t.Errorf("part is too long: %q", part)
+		}
+	}
+	if !strings.Contains(parts[1], "```text\n") {
+		t.Errorf("code fence should be reopened: %q", parts[1])
+	}
+}
+
+func TestIncoming_Split(t *testing.T) {
+	m := Incoming{
+		Text:         strings.Repeat("word ", 40),
+		Notification: "build failed",
+		Attachments:  []IncomingAttachment{{Text: "log"}},
+	}
+
+	parts, err := m.Split(60)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	for i, part := range parts {
+		if err := part.Validate(); err != nil {
+			t.Errorf("unexpected error: %+v", err)
+		}
+		if (i == 0) != (part.Notification != "") {
+			t.Errorf("notification should be kept in first part: %+v", part)
+		}
+		if (i == len(parts)-1) != (len(part.Attachments) > 0) {
+			t.Errorf("attachments should be kept in last part: %+v", part)
+		}
+	}
+}
+
+func TestRTMMessage_Split(t *testing.T) {
+	m := RTMMessage{
+		"type":      RTMMessageTypeChannelMessage,
+		"text":      strings.Repeat("word ", 40),
+		"call_id":   1,
+		"refer_key": "foobar",
+	}
+
+	parts, err := m.Split(60)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if len(parts) < 2 {
+		t.Fatalf("expected split: %+v", parts)
+	}
+	for i, part := range parts {
+		if _, present := part["call_id"]; present {
+			t.Errorf("call_id should be removed: %+v", part)
+		}
+		if _, present := part["refer_key"]; present != (i == 0) {
+			t.Errorf("only first part should refer: %+v", part)
+		}
+		if part.Type() != RTMMessageTypeChannelMessage {
+			t.Errorf("unexpected type: %s", part.Type())
+		}
+	}
+}
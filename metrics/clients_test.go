@@ -0,0 +1,65 @@
+package metrics_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	bearychat "github.com/nanmu42/bearychat-go"
+	"github.com/nanmu42/bearychat-go/bearychattest"
+	"github.com/nanmu42/bearychat-go/metrics"
+	"github.com/nanmu42/bearychat-go/openapi"
+)
+
+func TestClients(t *testing.T) {
+	server := bearychattest.NewServer()
+	defer server.Close()
+	m := metrics.NewExpvar("")
+
+	client := openapi.NewClient(
+		server.Token,
+		openapi.NewClientWithBaseURL(server.OpenAPIClient().BaseURL),
+		openapi.NewClientWithMetrics(m),
+	)
+	client.Team.Info(context.Background())
+	client.Channel.Info(context.Background(), &openapi.ChannelInfoOptions{ChannelID: "=missing"})
+	if v := m.Value("bearychat_http_request_duration_seconds", "client", "openapi", "endpoint", "team.info", "status", "200"); v != 1 {
+		t.Errorf("unexpected team.info requests: %f", v)
+	}
+	if v := m.Value("bearychat_http_request_duration_seconds", "client", "openapi", "endpoint", "channel.info", "status", "404"); v != 1 {
+		t.Errorf("unexpected channel.info requests: %f", v)
+	}
+
+	rtmClient, _ := bearychat.NewRTMClient(
+		server.Token,
+		bearychat.WithRTMAPIBase(server.RTMAPIBase()),
+		bearychat.WithRTMMetrics(m),
+	)
+	rtmClient.CurrentTeam.Members()
+	if v := m.Value("bearychat_http_request_duration_seconds", "client", "rtm", "endpoint", "v1/current_team.members", "status", "200"); v != 1 {
+		t.Errorf("unexpected current_team.members requests: %f", v)
+	}
+
+	loop, _ := bearychat.NewRTMLoop(server.WSHost(), bearychat.WithRTMLoopBacklog(10), bearychat.WithRTMLoopMetrics(m))
+	if err := loop.Start(); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	defer loop.Stop()
+	messageC, _ := loop.ReadC()
+
+	loop.Ping()
+	select {
+	case <-messageC:
+	case <-time.After(time.Second):
+		t.Fatalf("expected pong")
+	}
+	if v := m.Value("bearychat_rtm_messages_sent_total", "type", "ping"); v != 1 {
+		t.Errorf("unexpected sent pings: %f", v)
+	}
+	if v := m.Value("bearychat_rtm_messages_received_total", "type", "pong"); v != 1 {
+		t.Errorf("unexpected received pongs: %f", v)
+	}
+	if v := m.Value("bearychat_rtm_ping_rtt_seconds"); v != 1 {
+		t.Errorf("unexpected ping rtt observations: %f", v)
+	}
+}
@@ -0,0 +1,269 @@
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DEFAULT_BUCKETS are upper bounds of histogram buckets, in seconds for durations.
+var DEFAULT_BUCKETS = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type kind string
+
+const (
+	kindCounter   kind = "counter"
+	kindGauge     kind = "gauge"
+	kindHistogram kind = "histogram"
+)
+
+type series struct {
+	name   string
+	labels [][2]string
+	kind   kind
+
+	// counter or gauge value
+	value float64
+
+	// histogram
+	buckets []float64
+	counts  []uint64 // per bucket, not cumulative
+	sum     float64
+	count   uint64
+}
+
+// Expvar is a Metrics kept in memory, published with expvar, and exposed
+// in Prometheus text format by Handler. A name is of the kind it's first
+// recorded as, recording it as another kind is ignored.
+type Expvar struct {
+	// Histogram buckets, DEFAULT_BUCKETS by default.
+	// Changes only apply to histograms created later.
+	Buckets []float64
+
+	lock   sync.Mutex // lock for properties below
+	series map[string]*series
+	kinds  map[string]kind // by name
+}
+
+var (
+	defaultExpvar     *Expvar
+	defaultExpvarOnce sync.Once
+)
+
+// Default returns Expvar published as `bearychat`.
+func Default() *Expvar {
+	defaultExpvarOnce.Do(func() {
+		defaultExpvar = NewExpvar("bearychat")
+	})
+	return defaultExpvar
+}
+
+// NewExpvar creates Expvar published as name, it panics if name is used
+// like expvar.Publish. Empty name skips publishing.
+func NewExpvar(name string) *Expvar {
+	e := &Expvar{
+		Buckets: DEFAULT_BUCKETS,
+		series:  map[string]*series{},
+		kinds:   map[string]kind{},
+	}
+	if name != "" {
+		expvar.Publish(name, e)
+	}
+
+	return e
+}
+
+// Add implements Metrics.
+func (e *Expvar) Add(name string, delta float64, labels ...string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if s := e.get(name, kindCounter, labels); s != nil {
+		s.value = s.value + delta
+	}
+}
+
+// Set implements Metrics.
+func (e *Expvar) Set(name string, value float64, labels ...string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if s := e.get(name, kindGauge, labels); s != nil {
+		s.value = value
+	}
+}
+
+// Observe implements Metrics.
+func (e *Expvar) Observe(name string, value float64, labels ...string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	s := e.get(name, kindHistogram, labels)
+	if s == nil {
+		return
+	}
+	s.counts[sort.SearchFloat64s(s.buckets, value)]++
+	s.sum = s.sum + value
+	s.count++
+}
+
+// Value returns value of a counter or gauge, or count of a histogram.
+func (e *Expvar) Value(name string, labels ...string) float64 {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	s, present := e.series[seriesKey(name, normalizeLabels(labels))]
+	if !present {
+		return 0
+	}
+	if s.kind == kindHistogram {
+		return float64(s.count)
+	}
+	return s.value
+}
+
+// String implements expvar.Var, series are encoded as a JSON object.
+func (e *Expvar) String() string {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	vars := map[string]interface{}{}
+	for key, s := range e.series {
+		if s.kind == kindHistogram {
+			vars[key] = map[string]interface{}{"count": s.count, "sum": s.sum}
+		} else {
+			vars[key] = s.value
+		}
+	}
+	b, _ := json.Marshal(vars)
+	return string(b)
+}
+
+// Handler serves series in Prometheus text format.
+func (e *Expvar) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		e.WritePrometheus(w)
+	})
+}
+
+// WritePrometheus writes series in Prometheus text format.
+func (e *Expvar) WritePrometheus(w io.Writer) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	keys := make([]string, 0, len(e.series))
+	for key := range e.series {
+		keys = append(keys, key)
+	}
+	// series of a name are grouped under its TYPE line
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := e.series[keys[i]], e.series[keys[j]]
+		if a.name != b.name {
+			return a.name < b.name
+		}
+		return keys[i] < keys[j]
+	})
+
+	var (
+		b        strings.Builder
+		lastName string
+	)
+	for _, key := range keys {
+		s := e.series[key]
+		if s.name != lastName {
+			fmt.Fprintf(&b, "# TYPE %s %s\n", s.name, s.kind)
+			lastName = s.name
+		}
+
+		if s.kind != kindHistogram {
+			fmt.Fprintf(&b, "%s%s %s\n", s.name, formatLabels(s.labels), formatValue(s.value))
+			continue
+		}
+
+		var cumulative uint64
+		for i, upper := range append(append([]float64{}, s.buckets...), math.Inf(1)) {
+			cumulative = cumulative + s.counts[i]
+			labels := append(append([][2]string{}, s.labels...), [2]string{"le", formatValue(upper)})
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", s.name, formatLabels(labels), cumulative)
+		}
+		fmt.Fprintf(&b, "%s_sum%s %s\n", s.name, formatLabels(s.labels), formatValue(s.sum))
+		fmt.Fprintf(&b, "%s_count%s %d\n", s.name, formatLabels(s.labels), s.count)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// get returns series of name and labels, creates it if not present.
+// It returns nil if name is used by series of another kind.
+func (e *Expvar) get(name string, k kind, labels []string) *series {
+	if used, present := e.kinds[name]; present && used != k {
+		return nil
+	}
+	e.kinds[name] = k
+
+	normalized := normalizeLabels(labels)
+	key := seriesKey(name, normalized)
+	if s, present := e.series[key]; present {
+		return s
+	}
+
+	s := &series{name: name, labels: normalized, kind: k}
+	if k == kindHistogram {
+		s.buckets = append([]float64{}, e.Buckets...)
+		s.counts = make([]uint64, len(s.buckets)+1)
+	}
+	e.series[key] = s
+	return s
+}
+
+// normalizeLabels pairs labels and sorts them by key.
+func normalizeLabels(labels []string) [][2]string {
+	pairs := make([][2]string, 0, (len(labels)+1)/2)
+	for i := 0; i < len(labels); i = i + 2 {
+		pair := [2]string{labels[i], ""}
+		if i+1 < len(labels) {
+			pair[1] = labels[i+1]
+		}
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i][0] < pairs[j][0] })
+	return pairs
+}
+
+func seriesKey(name string, labels [][2]string) string {
+	return name + formatLabels(labels)
+}
+
+// labelValueEscaper escapes label values as Prometheus text format.
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(labels [][2]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	parts := make([]string, 0, len(labels))
+	for _, pair := range labels {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, pair[0], labelValueEscaper.Replace(pair[1])))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExpvar(t *testing.T) {
+	m := NewExpvar("bearychat_test")
+	m.Buckets = []float64{0.1, 1}
+
+	m.Add("sent_total", 1, "type", "message")
+	m.Add("sent_total", 2, "type", "message")
+	m.Add("sent_total", 1, "type", "ping")
+	m.Set("queue_depth", 3)
+	m.Observe("duration_seconds", 0.05, "status", "200", "endpoint", "team.info")
+	m.Observe("duration_seconds", 0.5, "endpoint", "team.info", "status", "200")
+	m.Observe("duration_seconds", 5, "endpoint", "team.info", "status", "200")
+
+	if v := m.Value("sent_total", "type", "message"); v != 3 {
+		t.Errorf("unexpected counter: %f", v)
+	}
+	if v := m.Value("duration_seconds", "status", "200", "endpoint", "team.info"); v != 3 {
+		t.Errorf("unexpected histogram count: %f", v)
+	}
+
+	recorder := httptest.NewRecorder()
+	m.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	expected := `# TYPE duration_seconds histogram
+duration_seconds_bucket{endpoint="team.info",status="200",le="0.1"} 1
+duration_seconds_bucket{endpoint="team.info",status="200",le="1"} 2
+duration_seconds_bucket{endpoint="team.info",status="200",le="+Inf"} 3
+duration_seconds_sum{endpoint="team.info",status="200"} 5.55
+duration_seconds_count{endpoint="team.info",status="200"} 3
+# TYPE queue_depth gauge
+queue_depth 3
+# TYPE sent_total counter
+sent_total{type="message"} 3
+sent_total{type="ping"} 1
+`
+	if got := recorder.Body.String(); got != expected {
+		t.Errorf("unexpected exposition:\n%s", got)
+	}
+	if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("unexpected content type: %s", recorder.Header().Get("Content-Type"))
+	}
+
+	vars := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(expvar.Get("bearychat_test").String()), &vars); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if vars[`queue_depth`] != float64(3) {
+		t.Errorf("unexpected vars: %+v", vars)
+	}
+}
+
+func TestExpvar_KindMismatch(t *testing.T) {
+	m := NewExpvar("")
+
+	m.Add("requests", 1)
+	m.Observe("requests", 0.5)
+	m.Set("requests", 5, "type", "other")
+	if v := m.Value("requests"); v != 1 {
+		t.Errorf("unexpected counter: %f", v)
+	}
+	if v := m.Value("requests", "type", "other"); v != 0 {
+		t.Errorf("unexpected gauge of counter name: %f", v)
+	}
+}
+
+func TestExpvar_LabelEscaping(t *testing.T) {
+	m := NewExpvar("")
+	m.Add("errors_total", 1, "reason", "bad \"token\"\\\nretry 中文")
+
+	var b strings.Builder
+	m.WritePrometheus(&b)
+	expected := "# TYPE errors_total counter\nerrors_total{reason=\"bad \\\"token\\\"\\\\\\nretry 中文\"} 1\n"
+	if b.String() != expected {
+		t.Errorf("unexpected exposition:\n%s", b.String())
+	}
+}
@@ -0,0 +1,39 @@
+// Package metrics defines the metrics interface called by bearychat clients,
+// with an expvar based implementation exposed in Prometheus text format.
+//
+//      m := metrics.Default()
+//      client := openapi.NewClient(token, openapi.NewClientWithMetrics(m))
+//      loop, _ := bearychat.NewRTMLoop(wsHost, bearychat.WithRTMLoopMetrics(m))
+//
+//      http.Handle("/metrics", m.Handler())
+//
+// Metrics reported by clients:
+//
+//      bearychat_rtm_messages_received_total{type}       counter
+//      bearychat_rtm_messages_sent_total{type}           counter
+//      bearychat_rtm_send_failures_total{type}           counter
+//      bearychat_rtm_queue_depth                         gauge, messages waiting in ReadC
+//      bearychat_rtm_ping_rtt_seconds                    histogram
+//      bearychat_rtm_reconnects_total                    counter
+//      bearychat_http_request_duration_seconds{client, endpoint, status}  histogram
+package metrics
+
+// Metrics records counters, histograms and gauges. Labels are alternating
+// keys and values.
+type Metrics interface {
+	// Add adds delta to a counter.
+	Add(name string, delta float64, labels ...string)
+	// Observe records value in a histogram.
+	Observe(name string, value float64, labels ...string)
+	// Set sets a gauge.
+	Set(name string, value float64, labels ...string)
+}
+
+// Nop is a Metrics recording nothing, used by clients by default.
+var Nop Metrics = nop{}
+
+type nop struct{}
+
+func (nop) Add(name string, delta float64, labels ...string)     {}
+func (nop) Observe(name string, value float64, labels ...string) {}
+func (nop) Set(name string, value float64, labels ...string)     {}
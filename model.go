@@ -1,16 +1,18 @@
 package bearychat
 
+import "github.com/nanmu42/bearychat-go/openapi"
+
 // Team information
 type Team struct {
-	Id          string `json:"id"`
-	Subdomain   string `json:"subdomain"`
-	Name        string `json:"name"`
-	UserId      string `json:"uid"`
-	Description string `json:"description"`
-	EmailDomain string `json:"email_domain"`
-	Inactive    bool   `json:"inactive"`
-	CreatedAt   string `json:"created"` // TODO parse date
-	UpdatedAt   string `json:"updated"` // TODO parse date
+	Id          string       `json:"id"`
+	Subdomain   string       `json:"subdomain"`
+	Name        string       `json:"name"`
+	UserId      string       `json:"uid"`
+	Description string       `json:"description"`
+	EmailDomain string       `json:"email_domain"`
+	Inactive    bool         `json:"inactive"`
+	CreatedAt   openapi.Time `json:"created"`
+	UpdatedAt   openapi.Time `json:"updated"`
 }
 
 const (
@@ -28,18 +30,18 @@ const (
 
 // User information
 type User struct {
-	Id         string `json:"id"`
-	TeamId     string `json:"team_id"`
-	VChannelId string `json:"vchannel_id"`
-	Name       string `json:"name"`
-	FullName   string `json:"full_name"`
-	Email      string `json:"email"`
-	AvatarUrl  string `json:"avatar_url"`
-	Role       string `json:"role"`
-	Type       string `json:"type"`
-	Conn       string `json:"conn"`
-	CreatedAt  string `json:"created"` // TODO parse date
-	UpdatedAt  string `json:"updated"` // TODO parse date
+	Id         string       `json:"id"`
+	TeamId     string       `json:"team_id"`
+	VChannelId string       `json:"vchannel_id"`
+	Name       string       `json:"name"`
+	FullName   string       `json:"full_name"`
+	Email      string       `json:"email"`
+	AvatarUrl  string       `json:"avatar_url"`
+	Role       string       `json:"role"`
+	Type       string       `json:"type"`
+	Conn       string       `json:"conn"`
+	CreatedAt  openapi.Time `json:"created"`
+	UpdatedAt  openapi.Time `json:"updated"`
 }
 
 // IsOnline tells user connection status.
@@ -54,26 +56,26 @@ func (u User) IsNormal() bool {
 
 // Channel information.
 type Channel struct {
-	Id         string `json:"id"`
-	TeamId     string `json:"team_id"`
-	UserId     string `json:"uid"`
-	VChannelId string `json:"vchannel_id"`
-	Name       string `json:"name"`
-	IsPrivate  bool   `json:"private"`
-	IsGeneral  bool   `json:"general"`
-	Topic      string `json:"topic"`
-	CreatedAt  string `json:"created"` // TODO parse date
-	UpdatedAt  string `json:"updated"` // TODO parse date
+	Id         string       `json:"id"`
+	TeamId     string       `json:"team_id"`
+	UserId     string       `json:"uid"`
+	VChannelId string       `json:"vchannel_id"`
+	Name       string       `json:"name"`
+	IsPrivate  bool         `json:"private"`
+	IsGeneral  bool         `json:"general"`
+	Topic      string       `json:"topic"`
+	CreatedAt  openapi.Time `json:"created"`
+	UpdatedAt  openapi.Time `json:"updated"`
 }
 
 // AttachedFile RTM struct in Attachments
 type AttachedFile struct {
-	Category    string `json:"category"`
-	Created     string `json:"created"`
-	Deleted     bool   `json:"deleted"`
-	Description string `json:"description"`
-	Height      int    `json:"height"`
-	ID          string `json:"id"`
+	Category    string       `json:"category"`
+	Created     openapi.Time `json:"created"`
+	Deleted     bool         `json:"deleted"`
+	Description string       `json:"description"`
+	Height      int          `json:"height"`
+	ID          string       `json:"id"`
 	// image URL
 	ImageURL string `json:"image_url"`
 	Inactive bool   `json:"inactive"`
@@ -87,16 +89,16 @@ type AttachedFile struct {
 	// preview URL
 	PreviewURL string `json:"preview_url"`
 	// File Size in byte
-	Size       int    `json:"size"`
-	Source     string `json:"source"`
-	TeamID     string `json:"team_id"`
-	Title      string `json:"title"`
-	Type       string `json:"type"`
-	UID        string `json:"uid"`
-	Updated    string `json:"updated"`
-	UploadZone string `json:"upload_zone"`
-	URL        string `json:"url"`
-	Width      int    `json:"width"`
+	Size       int          `json:"size"`
+	Source     string       `json:"source"`
+	TeamID     string       `json:"team_id"`
+	Title      string       `json:"title"`
+	Type       string       `json:"type"`
+	UID        string       `json:"uid"`
+	Updated    openapi.Time `json:"updated"`
+	UploadZone string       `json:"upload_zone"`
+	URL        string       `json:"url"`
+	Width      int          `json:"width"`
 }
 
 // UpdateAttachments RTM msg UpdateAttachments
@@ -115,23 +117,23 @@ type UpdateAttachments struct {
 			Type    string `json:"type"`
 			UID     string `json:"uid"`
 		} `json:"attachments"`
-		Created         string `json:"created"`
-		CreatedTs       int64  `json:"created_ts"`
-		DisableMarkdown bool   `json:"disable_markdown"`
-		Edited          bool   `json:"edited"`
-		ID              string `json:"id"`
-		IsChannel       bool   `json:"is_channel"`
-		Key             string `json:"key"`
-		ReferKey        string `json:"refer_key"`
-		Subtype         string `json:"subtype"`
-		TeamID          string `json:"team_id"`
-		Text            string `json:"text"`
-		UID             string `json:"uid"`
-		Updated         string `json:"updated"`
-		VChannelID      string `json:"vchannel_id"`
+		Created         openapi.Time       `json:"created"`
+		CreatedTs       openapi.VChannelTS `json:"created_ts"`
+		DisableMarkdown bool               `json:"disable_markdown"`
+		Edited          bool               `json:"edited"`
+		ID              string             `json:"id"`
+		IsChannel       bool               `json:"is_channel"`
+		Key             string             `json:"key"`
+		ReferKey        string             `json:"refer_key"`
+		Subtype         string             `json:"subtype"`
+		TeamID          string             `json:"team_id"`
+		Text            string             `json:"text"`
+		UID             string             `json:"uid"`
+		Updated         openapi.Time       `json:"updated"`
+		VChannelID      string             `json:"vchannel_id"`
 	} `json:"data"`
-	Ts   int64  `json:"ts"`
-	Type string `json:"type"`
+	Ts   openapi.VChannelTS `json:"ts"`
+	Type string             `json:"type"`
 }
 
 type MessageFile struct {
@@ -0,0 +1,30 @@
+package bearychat
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestUser_JSON(t *testing.T) {
+	raw := `{"id":"=bw52O","team_id":"","vchannel_id":"","name":"alice","full_name":"","email":"","avatar_url":"","role":"","type":"","conn":"","created":"2018-10-28T14:16:52+0000","updated":null}`
+
+	var u User
+	if err := json.Unmarshal([]byte(raw), &u); err != nil {
+		t.Fatalf("unmarshal failed: %+v", err)
+	}
+	if created := time.Date(2018, 10, 28, 14, 16, 52, 0, time.UTC); !u.CreatedAt.Equal(created) {
+		t.Errorf("expected created %s, got %s", created, u.CreatedAt)
+	}
+	if !u.UpdatedAt.IsZero() {
+		t.Errorf("expected zero updated, got %s", u.UpdatedAt)
+	}
+
+	b, err := json.Marshal(u)
+	if err != nil {
+		t.Fatalf("marshal failed: %+v", err)
+	}
+	if string(b) != raw {
+		t.Errorf("expected %s, got %s", raw, b)
+	}
+}
@@ -0,0 +1,189 @@
+package openapi
+
+import (
+	"context"
+	"net/http"
+)
+
+// Interfaces of services, satisfied by services of Client.
+//
+// Depend on them instead of concrete services, so code can be tested with
+// mocks from package openapimock:
+//
+//      type Notifier struct {
+//              Message openapi.MessageAPI
+//      }
+//
+//      notifier := &Notifier{Message: client.Message}
+//
+// Regenerate mocks after changing interfaces below.
+//go:generate go run ./openapimock/gen -o openapimock/mock.go api.go
+
+// MetaAPI is implemented by MetaService.
+type MetaAPI interface {
+	Get(ctx context.Context) (*Meta, *http.Response, error)
+}
+
+// TeamAPI is implemented by TeamService.
+type TeamAPI interface {
+	Info(ctx context.Context) (*Team, *http.Response, error)
+}
+
+// UserAPI is implemented by UserService.
+type UserAPI interface {
+	Info(ctx context.Context, opt *UserInfoOptions) (*User, *http.Response, error)
+	List(ctx context.Context) ([]*User, *http.Response, error)
+	Me(ctx context.Context) (*User, *http.Response, error)
+}
+
+// ChannelAPI is implemented by ChannelService.
+type ChannelAPI interface {
+	Info(ctx context.Context, opt *ChannelInfoOptions) (*Channel, *http.Response, error)
+	List(ctx context.Context) ([]*Channel, *http.Response, error)
+	Create(ctx context.Context, opt *ChannelCreateOptions) (*Channel, *http.Response, error)
+	Archive(ctx context.Context, opt *ChannelArchiveOptions) (*Channel, *http.Response, error)
+	Unarchive(ctx context.Context, opt *ChannelUnarchiveOptions) (*Channel, *http.Response, error)
+	Leave(ctx context.Context, opt *ChannelLeaveOptions) (*ResponseNoContent, *http.Response, error)
+	Join(ctx context.Context, opt *ChannelJoinOptions) (*Channel, *http.Response, error)
+	Invite(ctx context.Context, opt *ChannelInviteOptions) (*ResponseNoContent, *http.Response, error)
+	Kick(ctx context.Context, opt *ChannelKickOptions) (*ResponseNoContent, *http.Response, error)
+	Kickout(ctx context.Context, opt *ChannelKickOptions) (*ResponseNoContent, *http.Response, error)
+}
+
+// SessionChannelAPI is implemented by SessionChannelService.
+type SessionChannelAPI interface {
+	Info(ctx context.Context, opt *SessionChannelInfoOptions) (*SessionChannel, *http.Response, error)
+	List(ctx context.Context) ([]*SessionChannel, *http.Response, error)
+	Create(ctx context.Context, opt *SessionChannelCreateOptions) (*SessionChannel, *http.Response, error)
+	Archive(ctx context.Context, opt *SessionChannelArchiveOptions) (*SessionChannel, *http.Response, error)
+	ConvertToChannel(ctx context.Context, opt *SessionChannelConvertOptions) (*Channel, *http.Response, error)
+	Leave(ctx context.Context, opt *SessionChannelLeaveOptions) (*ResponseNoContent, *http.Response, error)
+	Invite(ctx context.Context, opt *SessionChannelInviteOptions) (*ResponseNoContent, *http.Response, error)
+	Kick(ctx context.Context, opt *SessionChannelKickOptions) (*ResponseNoContent, *http.Response, error)
+}
+
+// MessageAPI is implemented by MessageService.
+type MessageAPI interface {
+	Query(ctx context.Context, opt *MessageQueryOptions) (*MessageQueryResult, *http.Response, error)
+	Info(ctx context.Context, opt *MessageInfoOptions) (*Message, *http.Response, error)
+	Create(ctx context.Context, opt *MessageCreateOptions) (*Message, *http.Response, error)
+	Delete(ctx context.Context, opt *MessageDeleteOptions) (*ResponseNoContent, *http.Response, error)
+	UpdateText(ctx context.Context, opt *MessageUpdateTextOptions) (*Message, *http.Response, error)
+	Forward(ctx context.Context, opt *MessageForwardOptions) (*Message, *http.Response, error)
+}
+
+// P2PAPI is implemented by P2PService.
+type P2PAPI interface {
+	Info(ctx context.Context, opt *P2PInfoOptions) (*P2P, *http.Response, error)
+	List(ctx context.Context) ([]*P2P, *http.Response, error)
+	Create(ctx context.Context, opt *P2PCreateOptions) (*P2P, *http.Response, error)
+}
+
+// EmojiAPI is implemented by EmojiService.
+type EmojiAPI interface {
+	List(ctx context.Context) ([]*Emoji, *http.Response, error)
+}
+
+// StickerAPI is implemented by StickerService.
+type StickerAPI interface {
+	List(ctx context.Context) ([]*StickerPack, *http.Response, error)
+}
+
+// RTMAPI is implemented by RTMService.
+type RTMAPI interface {
+	Start(ctx context.Context) (*RTMStart, *http.Response, error)
+}
+
+// MessagePinAPI is implemented by MessagePinService.
+type MessagePinAPI interface {
+	List(ctx context.Context, opt *MessagePinListOptions) ([]*MessagePin, *http.Response, error)
+	Create(ctx context.Context, opt *MessagePinCreateOptions) (*MessagePin, *http.Response, error)
+	Delete(ctx context.Context, opt *MessagePinDeleteOptions) (*ResponseNoContent, *http.Response, error)
+}
+
+// VChannelAPI is implemented by VChannelService.
+type VChannelAPI interface {
+	List(ctx context.Context, opt *VChannelListOptions) ([]VChannel, *http.Response, error)
+	Info(ctx context.Context, opt *VChannelInfoOptions) (VChannel, *http.Response, error)
+	CreateMessage(ctx context.Context, vchannel VChannel, opt *VChannelMessageOptions) (*Message, *http.Response, error)
+	QueryMessages(ctx context.Context, vchannel VChannel, query *MessageQuery) (*MessageQueryResult, *http.Response, error)
+}
+
+// MembershipAPI is implemented by MembershipService.
+type MembershipAPI interface {
+	SyncChannel(ctx context.Context, channelID string, opt *MembershipSyncOptions) (*MembershipSyncReport, *http.Response, error)
+	SyncSessionChannel(ctx context.Context, channelID string, opt *MembershipSyncOptions) (*MembershipSyncReport, *http.Response, error)
+}
+
+// API is what Client provides, as interfaces.
+type API interface {
+	MetaAPI() MetaAPI
+	TeamAPI() TeamAPI
+	UserAPI() UserAPI
+	ChannelAPI() ChannelAPI
+	SessionChannelAPI() SessionChannelAPI
+	MessageAPI() MessageAPI
+	P2PAPI() P2PAPI
+	EmojiAPI() EmojiAPI
+	StickerAPI() StickerAPI
+	RTMAPI() RTMAPI
+	MessagePinAPI() MessagePinAPI
+	VChannelAPI() VChannelAPI
+	MembershipAPI() MembershipAPI
+}
+
+var (
+	_ API               = (*Client)(nil)
+	_ MetaAPI           = (*MetaService)(nil)
+	_ TeamAPI           = (*TeamService)(nil)
+	_ UserAPI           = (*UserService)(nil)
+	_ ChannelAPI        = (*ChannelService)(nil)
+	_ SessionChannelAPI = (*SessionChannelService)(nil)
+	_ MessageAPI        = (*MessageService)(nil)
+	_ P2PAPI            = (*P2PService)(nil)
+	_ EmojiAPI          = (*EmojiService)(nil)
+	_ StickerAPI        = (*StickerService)(nil)
+	_ RTMAPI            = (*RTMService)(nil)
+	_ MessagePinAPI     = (*MessagePinService)(nil)
+	_ VChannelAPI       = (*VChannelService)(nil)
+	_ MembershipAPI     = (*MembershipService)(nil)
+)
+
+// MetaAPI returns Meta as interface.
+func (c *Client) MetaAPI() MetaAPI { return c.Meta }
+
+// TeamAPI returns Team as interface.
+func (c *Client) TeamAPI() TeamAPI { return c.Team }
+
+// UserAPI returns User as interface.
+func (c *Client) UserAPI() UserAPI { return c.User }
+
+// ChannelAPI returns Channel as interface.
+func (c *Client) ChannelAPI() ChannelAPI { return c.Channel }
+
+// SessionChannelAPI returns SessionChannel as interface.
+func (c *Client) SessionChannelAPI() SessionChannelAPI { return c.SessionChannel }
+
+// MessageAPI returns Message as interface.
+func (c *Client) MessageAPI() MessageAPI { return c.Message }
+
+// P2PAPI returns P2P as interface.
+func (c *Client) P2PAPI() P2PAPI { return c.P2P }
+
+// EmojiAPI returns Emoji as interface.
+func (c *Client) EmojiAPI() EmojiAPI { return c.Emoji }
+
+// StickerAPI returns Sticker as interface.
+func (c *Client) StickerAPI() StickerAPI { return c.Sticker }
+
+// RTMAPI returns RTM as interface.
+func (c *Client) RTMAPI() RTMAPI { return c.RTM }
+
+// MessagePinAPI returns MessagePin as interface.
+func (c *Client) MessagePinAPI() MessagePinAPI { return c.MessagePin }
+
+// VChannelAPI returns VChannel as interface.
+func (c *Client) VChannelAPI() VChannelAPI { return c.VChannel }
+
+// MembershipAPI returns Membership as interface.
+func (c *Client) MembershipAPI() MembershipAPI { return c.Membership }
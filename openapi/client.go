@@ -9,8 +9,12 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/nanmu42/bearychat-go/metrics"
+	"github.com/nanmu42/bearychat-go/trace"
 )
 
 var defaultBaseURL = "https://api.bearychat.com/v1/"
@@ -28,6 +32,15 @@ type Client struct {
 	// Access token for the client.
 	Token string
 
+	// Logger for requests, logs nothing by default.
+	logger Logger
+
+	// Metrics for requests, records nothing by default.
+	metrics metrics.Metrics
+
+	// Tracer for requests, traces nothing by default.
+	tracer trace.Tracer
+
 	// Shared services holder to reduce real service allocating.
 	base service
 
@@ -42,6 +55,8 @@ type Client struct {
 	Sticker        *StickerService
 	RTM            *RTMService
 	MessagePin     *MessagePinService
+	VChannel       *VChannelService
+	Membership     *MembershipService
 }
 
 type service struct {
@@ -64,6 +79,27 @@ func NewClientWithHTTPClient(httpClient *http.Client) clientOpt {
 	}
 }
 
+// NewClientWithLogger binds logger to client.
+func NewClientWithLogger(logger Logger) clientOpt {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// NewClientWithMetrics binds metrics to client.
+func NewClientWithMetrics(m metrics.Metrics) clientOpt {
+	return func(c *Client) {
+		c.metrics = m
+	}
+}
+
+// NewClientWithTracer binds tracer to client.
+func NewClientWithTracer(tracer trace.Tracer) clientOpt {
+	return func(c *Client) {
+		c.tracer = tracer
+	}
+}
+
 // NewClient constructs a client with given access token.
 // Other settings can set via clientOpt functions.
 func NewClient(token string, opts ...clientOpt) *Client {
@@ -79,6 +115,18 @@ func NewClient(token string, opts ...clientOpt) *Client {
 		c.httpClient = http.DefaultClient
 	}
 
+	if c.logger == nil {
+		c.logger = NopLogger
+	}
+
+	if c.metrics == nil {
+		c.metrics = metrics.Nop
+	}
+
+	if c.tracer == nil {
+		c.tracer = trace.Nop
+	}
+
 	if c.BaseURL == nil {
 		baseURL, _ := url.Parse(defaultBaseURL)
 		c.BaseURL = baseURL
@@ -96,6 +144,8 @@ func NewClient(token string, opts ...clientOpt) *Client {
 	c.Sticker = (*StickerService)(&c.base)
 	c.RTM = (*RTMService)(&c.base)
 	c.MessagePin = (*MessagePinService)(&c.base)
+	c.VChannel = (*VChannelService)(&c.base)
+	c.Membership = (*MembershipService)(&c.base)
 
 	return c
 }
@@ -139,10 +189,45 @@ func (c *Client) newRequest(requestMethod, apiMethod string, body interface{}) (
 // will be written to v, without attempting to first decode it.
 //
 // The provided ctx must be non-nil. If it is canceled or times out, ctx.Err() will be returned.
-func (c *Client) do(ctx context.Context, req *http.Request, v interface{}) (*http.Response, error) {
+func (c *Client) do(ctx context.Context, req *http.Request, v interface{}) (resp *http.Response, err error) {
+	endpoint := strings.TrimPrefix(req.URL.Path, c.BaseURL.Path)
+	if req.URL.Host != c.BaseURL.Host {
+		// e.g. attachment urls, which would be unbounded series
+		endpoint = "external"
+	}
+	ctx, span := c.tracer.Start(
+		ctx,
+		"bearychat.openapi.request",
+		trace.Attr("http.method", req.Method),
+		trace.Attr("endpoint", endpoint),
+	)
+	defer func() {
+		if resp != nil {
+			span.SetAttributes(trace.Attr("http.status_code", resp.StatusCode))
+		}
+		if err != nil {
+			span.RecordError(redactError(err))
+		}
+		span.End()
+	}()
 	req = req.WithContext(ctx)
 
-	resp, err := c.httpClient.Do(req)
+	// query is not logged, which has the token
+	c.logger.Debug("openapi request", "method", req.Method, "path", req.URL.Path)
+	start := time.Now()
+
+	resp, err = c.httpClient.Do(req)
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	c.metrics.Observe(
+		"bearychat_http_request_duration_seconds",
+		time.Since(start).Seconds(),
+		"client", "openapi",
+		"endpoint", endpoint,
+		"status", status,
+	)
 	if err != nil {
 		// try to use context's error
 		select {
@@ -151,13 +236,32 @@ func (c *Client) do(ctx context.Context, req *http.Request, v interface{}) (*htt
 		default:
 		}
 
+		c.logger.Warn("openapi request failed", "method", req.Method, "path", req.URL.Path, "error", redactError(err))
 		return nil, err
 	}
 
 	defer resp.Body.Close()
 
+	c.logger.Debug(
+		"openapi response",
+		"method", req.Method,
+		"path", req.URL.Path,
+		"status", resp.StatusCode,
+		"duration", time.Since(start),
+	)
+
 	err = CheckResponse(resp)
 	if err != nil {
+		// error message has the url with token
+		errResponse := err.(*ErrorResponse)
+		c.logger.Warn(
+			"openapi error response",
+			"method", req.Method,
+			"path", req.URL.Path,
+			"status", resp.StatusCode,
+			"code", errResponse.ErrorCode,
+			"reason", errResponse.ErrorReason,
+		)
 		return resp, err
 	}
 
@@ -208,21 +312,15 @@ func CheckResponse(r *http.Response) error {
 	return errResponse
 }
 
-const timeLayout = "2006-01-02T15:04:05-0700"
-
-// Time with custom JSON format.
-type Time struct {
-	time.Time
-}
-
-func (t Time) UnmarshalJSON(b []byte) (err error) {
-	s := strings.Trim(string(b), "\"")
-	t.Time, err = time.Parse(timeLayout, s)
-	return
-}
-
-func (t Time) MarshalJSON() ([]byte, error) {
-	return []byte(fmt.Sprintf("\"%s\"", t.Time.Format(timeLayout))), nil
+// redactError strips url with token from http client and API response errors.
+func redactError(err error) error {
+	if errResponse, ok := err.(*ErrorResponse); ok {
+		return fmt.Errorf("%d %s", errResponse.ErrorCode, errResponse.ErrorReason)
+	}
+	if urlErr, ok := err.(*url.Error); ok {
+		return fmt.Errorf("%s: %w", urlErr.Op, urlErr.Err)
+	}
+	return err
 }
 
 type ResponseOK struct {
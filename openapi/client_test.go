@@ -1,8 +1,13 @@
 package openapi
 
 import (
+	"bytes"
+	"context"
+	"log/slog"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 )
 
@@ -38,3 +43,26 @@ func TestNewClient_NewClientWithHTTPClient(t *testing.T) {
 		t.Errorf("unexpected httpClient: %+v", client.httpClient)
 	}
 }
+
+func TestNewClient_NewClientWithLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"code":2,"error":"forbidden"}`))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	u, _ := url.Parse(server.URL + "/")
+	client := NewClient("secret-token", NewClientWithBaseURL(u), NewClientWithLogger(logger))
+
+	client.Team.Info(context.Background())
+
+	logged := buf.String()
+	if !strings.Contains(logged, `msg="openapi error response" method=GET path=/team.info status=403 code=2 reason=forbidden`) {
+		t.Errorf("unexpected log: %s", logged)
+	}
+	if strings.Contains(logged, "secret-token") {
+		t.Errorf("token should not be logged: %s", logged)
+	}
+}
@@ -13,4 +13,7 @@
 // API methods are grouped by "namespace":
 //
 //      team, _, err := client.Team.Info()
+//
+// Each service satisfies an interface, e.g. MessageAPI, and Client satisfies API.
+// In-memory mocks of them are in package openapimock.
 package openapi
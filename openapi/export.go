@@ -0,0 +1,396 @@
+package openapi
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	DEFAULT_EXPORT_PAGE_SIZE = 100
+
+	// ExportManifestFile is the manifest file name in archive directory.
+	ExportManifestFile = "manifest.json"
+	// ExportManifestVersion is the current archive layout version.
+	ExportManifestVersion = 1
+)
+
+// Exporter writes vchannel histories to an archive directory:
+//
+//      manifest.json             ExportManifest
+//      <vchannel id>.jsonl       one ExportedMessage per line, oldest first
+//      files/<vchannel id>/...   downloaded attachment images
+//
+// Exporting to an existing archive resumes from the last exported message
+// of each vchannel recorded in manifest.
+//
+//      e := openapi.NewExporter(client, "archive")
+//      err := e.ExportChannels(ctx)
+type Exporter struct {
+	client *Client
+	dir    string
+
+	// Messages per `message.query` call, DEFAULT_EXPORT_PAGE_SIZE by default.
+	PageSize uint
+	// Download attachment images into archive, true by default.
+	DownloadAttachments bool
+
+	users map[string]*User
+}
+
+// ExportManifest describes an archive.
+type ExportManifest struct {
+	Version    int                       `json:"version"`
+	ExportedAt Time                      `json:"exported_at"`
+	VChannels  []*ExportManifestVChannel `json:"vchannels"`
+}
+
+// ExportManifestVChannel describes an exported vchannel.
+type ExportManifestVChannel struct {
+	VChannelID string       `json:"vchannel_id"`
+	ID         string       `json:"id"`
+	Type       VChannelType `json:"type"`
+	Name       string       `json:"name,omitempty"`
+	// JSONL file relative to archive directory
+	File     string `json:"file"`
+	Messages int    `json:"messages"`
+	// Size of File when last exported, trailing bytes are from an interrupted export
+	Size    int64      `json:"size"`
+	LastKey MessageKey `json:"last_key,omitempty"`
+	LastTS  VChannelTS `json:"last_ts,omitempty"`
+}
+
+// ExportedMessage is a line of vchannel JSONL file.
+type ExportedMessage struct {
+	Message *Message `json:"message"`
+	// Sender, nil for robots and unknown users
+	User  *ExportedUser  `json:"user,omitempty"`
+	Files []ExportedFile `json:"files,omitempty"`
+}
+
+// ExportedUser is the sender of an exported message.
+type ExportedUser struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	FullName string `json:"full_name,omitempty"`
+	Email    string `json:"email,omitempty"`
+}
+
+// ExportedFile is a downloaded attachment image.
+type ExportedFile struct {
+	URL string `json:"url"`
+	// Path relative to archive directory
+	Path string `json:"path"`
+}
+
+// NewExporter creates an exporter writing to dir.
+func NewExporter(client *Client, dir string) *Exporter {
+	return &Exporter{
+		client:              client,
+		dir:                 dir,
+		PageSize:            DEFAULT_EXPORT_PAGE_SIZE,
+		DownloadAttachments: true,
+	}
+}
+
+// ReadExportManifest reads manifest of archive in dir.
+// An empty manifest is returned if there is none.
+func ReadExportManifest(dir string) (*ExportManifest, error) {
+	b, err := os.ReadFile(filepath.Join(dir, ExportManifestFile))
+	if os.IsNotExist(err) {
+		return &ExportManifest{Version: ExportManifestVersion}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest ExportManifest
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return nil, fmt.Errorf("decode manifest failed: %s", err)
+	}
+	if manifest.Version != ExportManifestVersion {
+		return nil, fmt.Errorf("unsupported manifest version %d", manifest.Version)
+	}
+	return &manifest, nil
+}
+
+// ExportChannels exports all channels from `channel.list`.
+func (e *Exporter) ExportChannels(ctx context.Context) error {
+	channels, _, err := e.client.Channel.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range channels {
+		if _, err := e.Export(ctx, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Export exports messages of vchannel since last export.
+func (e *Exporter) Export(ctx context.Context, vchannel VChannel) (*ExportManifestVChannel, error) {
+	if vchannel.GetVChannelID() == "" {
+		return nil, fmt.Errorf("vchannel id is required")
+	}
+	if err := os.MkdirAll(e.dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := e.loadUsers(ctx); err != nil {
+		return nil, err
+	}
+
+	manifest, err := ReadExportManifest(e.dir)
+	if err != nil {
+		return nil, err
+	}
+	entry := manifest.vchannel(vchannel)
+
+	f, err := os.OpenFile(filepath.Join(e.dir, entry.File), os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	// drop lines written after last manifest update
+	if err := f.Truncate(entry.Size); err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(entry.Size, 0); err != nil {
+		return nil, err
+	}
+
+	for {
+		messages, err := e.query(ctx, entry)
+		if err != nil {
+			return nil, err
+		}
+		if len(messages) == 0 {
+			return entry, nil
+		}
+
+		for _, m := range messages {
+			exported, err := e.exportMessage(ctx, entry, m)
+			if err != nil {
+				return nil, err
+			}
+			line, err := json.Marshal(exported)
+			if err != nil {
+				return nil, err
+			}
+			n, err := f.Write(append(line, '\n'))
+			if err != nil {
+				return nil, err
+			}
+
+			entry.Size = entry.Size + int64(n)
+			entry.Messages = entry.Messages + 1
+			if m.Key != nil {
+				entry.LastKey = *m.Key
+			}
+			entry.LastTS = tsv(m.CreatedTS)
+		}
+
+		if err := f.Sync(); err != nil {
+			return nil, err
+		}
+		manifest.ExportedAt = Time{time.Now()}
+		if err := manifest.write(e.dir); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// query returns next page of messages after entry.LastKey, oldest first.
+func (e *Exporter) query(ctx context.Context, entry *ExportManifestVChannel) ([]*Message, error) {
+	pageSize := e.PageSize
+	if pageSize == 0 {
+		pageSize = DEFAULT_EXPORT_PAGE_SIZE
+	}
+
+	since := &MessageQueryBySince{Forward: MessageQueryWithForward(pageSize)}
+	if entry.LastKey != "" {
+		// message of since key is included, which is exported already
+		lastKey := entry.LastKey
+		since.SinceKey = &lastKey
+		since.Forward = MessageQueryWithForward(pageSize + 1)
+	} else {
+		since.SinceTS = new(VChannelTS)
+	}
+
+	result, _, err := e.client.Message.Query(ctx, &MessageQueryOptions{
+		VChannelID: entry.VChannelID,
+		Query:      &MessageQuery{Since: since},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []*Message
+	for _, m := range result.Messages {
+		if m.Key != nil && *m.Key == entry.LastKey {
+			continue
+		}
+		if entry.LastTS != 0 && tsv(m.CreatedTS) < entry.LastTS {
+			continue
+		}
+		messages = append(messages, m)
+	}
+	sort.SliceStable(messages, func(i, j int) bool {
+		return tsv(messages[i].CreatedTS) < tsv(messages[j].CreatedTS)
+	})
+	return messages, nil
+}
+
+func (e *Exporter) exportMessage(ctx context.Context, entry *ExportManifestVChannel, m *Message) (*ExportedMessage, error) {
+	exported := &ExportedMessage{Message: m}
+
+	if user, present := e.users[strv(m.UID)]; present {
+		exported.User = &ExportedUser{
+			ID:       strv(user.ID),
+			Name:     strv(user.Name),
+			FullName: strv(user.FullName),
+			Email:    strv(user.Email),
+		}
+	}
+
+	if !e.DownloadAttachments {
+		return exported, nil
+	}
+	for _, a := range m.Attachments {
+		for _, image := range a.Images {
+			if strv(image.Url) == "" {
+				continue
+			}
+			file, err := e.download(ctx, entry.VChannelID, strv(image.Url))
+			if err != nil {
+				return nil, err
+			}
+			exported.Files = append(exported.Files, *file)
+		}
+	}
+	return exported, nil
+}
+
+// download saves url into files directory, named after url hash.
+// Downloaded files are not downloaded again.
+func (e *Exporter) download(ctx context.Context, vchannelID, fileURL string) (*ExportedFile, error) {
+	u, err := url.Parse(fileURL)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha1.Sum([]byte(fileURL))
+	// extension without query, e.g. signature of signed urls
+	name := hex.EncodeToString(sum[:]) + path.Ext(u.Path)
+	rel := filepath.Join("files", vchannelID, name)
+	file := &ExportedFile{URL: fileURL, Path: filepath.ToSlash(rel)}
+
+	abs := filepath.Join(e.dir, rel)
+	if _, err := os.Stat(abs); err == nil {
+		return file, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fileURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(abs), name+".*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	// files are not API requests, which are logged and measured by endpoint
+	err = e.get(req, tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("download %s failed: %s", fileURL, err)
+	}
+	if err := os.Rename(tmp.Name(), abs); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+func (e *Exporter) get(req *http.Request, w io.Writer) error {
+	resp, err := e.client.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+func (e *Exporter) loadUsers(ctx context.Context) error {
+	if e.users != nil {
+		return nil
+	}
+
+	users, _, err := e.client.User.List(ctx)
+	if err != nil {
+		return err
+	}
+	e.users = make(map[string]*User, len(users))
+	for _, u := range users {
+		e.users[strv(u.ID)] = u
+	}
+	return nil
+}
+
+// vchannel returns manifest entry of vchannel, adding it when absent.
+func (m *ExportManifest) vchannel(vchannel VChannel) *ExportManifestVChannel {
+	for _, entry := range m.VChannels {
+		if entry.VChannelID == vchannel.GetVChannelID() {
+			return entry
+		}
+	}
+
+	entry := &ExportManifestVChannel{
+		VChannelID: vchannel.GetVChannelID(),
+		ID:         vchannel.GetID(),
+		Type:       vchannel.GetType(),
+		File:       vchannel.GetVChannelID() + ".jsonl",
+	}
+	switch c := vchannel.(type) {
+	case *Channel:
+		entry.Name = strv(c.Name)
+	case *SessionChannel:
+		entry.Name = strv(c.Name)
+	}
+	m.VChannels = append(m.VChannels, entry)
+	return entry
+}
+
+// write replaces manifest file atomically.
+func (m *ExportManifest) write(dir string) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(dir, ExportManifestFile+".tmp")
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, ExportManifestFile))
+}
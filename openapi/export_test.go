@@ -0,0 +1,179 @@
+package openapi
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nanmu42/bearychat-go/metrics"
+)
+
+// exportTestServer serves messages of vchannel =vc1, keys are `k<ts>`.
+type exportTestServer struct {
+	lock     sync.Mutex
+	messages []*Message
+	server   *httptest.Server
+}
+
+func (s *exportTestServer) add(ts VChannelTS, text string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	key := MessageKey(fmt.Sprintf("k%d", ts))
+	m := &Message{Key: &key, CreatedTS: &ts, Text: strp(text), UID: strp("=u1")}
+	if text == "image" {
+		m.Attachments = []MessageAttachment{{Images: []MessageAttachmentImage{{Url: strp(s.server.URL + "/files/a.png?sig=YWJj%3D&expires=1")}}}}
+	}
+	s.messages = append(s.messages, m)
+}
+
+func (s *exportTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch r.URL.Path {
+	case "/user.list":
+		json.NewEncoder(w).Encode([]*User{{ID: strp("=u1"), Name: strp("alice")}})
+	case "/channel.list":
+		json.NewEncoder(w).Encode([]*Channel{{ID: strp("=c1"), VChannelID: strp("=vc1"), Name: strp("ops")}})
+	case "/files/a.png":
+		w.Write([]byte("png"))
+	case "/message.query":
+		var opt MessageQueryOptions
+		json.NewDecoder(r.Body).Decode(&opt)
+		since := opt.Query.Since
+
+		// like the API, the message of since key is included
+		var result MessageQueryResult
+		for _, m := range s.messages {
+			if since.SinceKey != nil && *m.Key < *since.SinceKey {
+				continue
+			}
+			if uint(len(result.Messages)) < *since.Forward {
+				result.Messages = append(result.Messages, m)
+			}
+		}
+		json.NewEncoder(w).Encode(result)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func readExportedMessages(t *testing.T, path string) []ExportedMessage {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open failed: %+v", err)
+	}
+	defer f.Close()
+
+	var messages []ExportedMessage
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var m ExportedMessage
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			t.Fatalf("decode line failed: %+v", err)
+		}
+		messages = append(messages, m)
+	}
+	return messages
+}
+
+func TestExporter(t *testing.T) {
+	s := &exportTestServer{}
+	s.server = httptest.NewServer(s)
+	defer s.server.Close()
+	for ts := VChannelTS(1); ts <= 5; ts = ts + 1 {
+		text := "hello"
+		if ts == 3 {
+			text = "image"
+		}
+		s.add(ts, text)
+	}
+
+	baseURL, _ := url.Parse(s.server.URL + "/")
+	dir := t.TempDir()
+	m := metrics.NewExpvar("")
+	e := NewExporter(NewClient("foobar", NewClientWithBaseURL(baseURL), NewClientWithMetrics(m)), dir)
+	e.PageSize = 2
+	ctx := context.Background()
+
+	if err := e.ExportChannels(ctx); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	messages := readExportedMessages(t, filepath.Join(dir, "=vc1.jsonl"))
+	if len(messages) != 5 {
+		t.Fatalf("expected 5 messages, got %d", len(messages))
+	}
+	if messages[0].User == nil || messages[0].User.Name != "alice" {
+		t.Errorf("user should be resolved: %+v", messages[0].User)
+	}
+	files := messages[2].Files
+	if len(files) != 1 {
+		t.Fatalf("expected downloaded file: %+v", files)
+	}
+	// signed url query is not in file name
+	if !strings.HasSuffix(files[0].Path, ".png") || strings.ContainsAny(filepath.Base(files[0].Path), "?=&") {
+		t.Errorf("unexpected file path: %s", files[0].Path)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, files[0].Path)); string(b) != "png" {
+		t.Errorf("unexpected file content: %s", b)
+	}
+	if strings.Contains(m.String(), "a.png") {
+		t.Errorf("file urls should not be measured: %s", m.String())
+	}
+
+	// resume drops lines of interrupted export and appends new messages
+	f, _ := os.OpenFile(filepath.Join(dir, "=vc1.jsonl"), os.O_APPEND|os.O_WRONLY, 0644)
+	f.Write([]byte(`{"message":{"text":"partial`))
+	f.Close()
+	s.add(6, "new")
+
+	if err := e.ExportChannels(ctx); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	messages = readExportedMessages(t, filepath.Join(dir, "=vc1.jsonl"))
+	if len(messages) != 6 || *messages[5].Message.Text != "new" {
+		t.Errorf("unexpected messages after resume: %d", len(messages))
+	}
+
+	manifest, err := ReadExportManifest(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	entry := manifest.VChannels[0]
+	if entry.Name != "ops" || entry.Messages != 6 || entry.LastKey != "k6" || entry.LastTS != 6 {
+		t.Errorf("unexpected manifest: %+v", entry)
+	}
+}
+
+func TestExporter_PageSize(t *testing.T) {
+	s := &exportTestServer{}
+	s.server = httptest.NewServer(s)
+	defer s.server.Close()
+	for ts := VChannelTS(1); ts <= 5; ts = ts + 1 {
+		s.add(ts, "hello")
+	}
+	baseURL, _ := url.Parse(s.server.URL + "/")
+
+	for _, pageSize := range []uint{1, 2, 100} {
+		dir := t.TempDir()
+		e := NewExporter(NewClient("foobar", NewClientWithBaseURL(baseURL)), dir)
+		e.PageSize = pageSize
+		if err := e.ExportChannels(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		if messages := readExportedMessages(t, filepath.Join(dir, "=vc1.jsonl")); len(messages) != 5 {
+			t.Errorf("expected 5 messages with page size %d, got %d", pageSize, len(messages))
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package openapi
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const (
+	DEFAULT_IMPORT_INTERVAL    = 500 * time.Millisecond
+	DEFAULT_IMPORT_MAX_RETRIES = 5
+
+	// ImportStateFile records import progress in archive directory.
+	ImportStateFile = "import_state.json"
+)
+
+// ImportAttributionFunc formats text of an imported message,
+// sender is the mention of remapped user or the original sender name.
+type ImportAttributionFunc func(m *ExportedMessage, sender, text string) string
+
+// DefaultImportAttribution prefixes text with sender and original time.
+func DefaultImportAttribution(m *ExportedMessage, sender, text string) string {
+	created := tsv(m.Message.CreatedTS).Time().UTC()
+	return fmt.Sprintf("%s (%s)\n%s", sender, created.Format("2006-01-02 15:04:05 MST"), text)
+}
+
+// ImportFileURLFunc returns URL of an exported file to post as attachment image.
+type ImportFileURLFunc func(ctx context.Context, file ExportedFile) (string, error)
+
+// Importer replays an archive written by Exporter into vchannels via `message.create`.
+//
+// Messages are posted one by one every Interval, retried on rate limit,
+// and progress is recorded in archive so an interrupted import resumes
+// from the next message. A message may be posted twice if import is
+// interrupted right after posting it.
+//
+// Files downloaded by Exporter are re-uploaded with FileURL, which is
+// required once a message with files is met, as their original URLs stop
+// working with the source team. Set FileURL and rerun to resume from
+// that message. Attachments not downloaded are posted with original URLs.
+//
+//      i := openapi.NewImporter(client, "archive")
+//      i.UserIDs = map[string]string{"=bw52O": "=cx63P"}
+//      n, err := i.Import(ctx, "=oldvc", "=newvc")
+type Importer struct {
+	client *Client
+	dir    string
+
+	// Interval between messages, DEFAULT_IMPORT_INTERVAL by default.
+	Interval time.Duration
+	// Retries of a rate limited message, DEFAULT_IMPORT_MAX_RETRIES by default.
+	MaxRetries int
+	// Source user id to target user id, used for sender and mentions.
+	UserIDs map[string]string
+	// Attribution formats text, DefaultImportAttribution by default.
+	Attribution ImportAttributionFunc
+	// FileURL re-uploads downloaded files, e.g. to an object storage,
+	// and returns URL to post. Required for messages with files.
+	FileURL ImportFileURLFunc
+}
+
+// ImportState records imported message count by `<source>:<target>` vchannel pair.
+type ImportState map[string]int
+
+// NewImporter creates an importer reading archive in dir.
+func NewImporter(client *Client, dir string) *Importer {
+	return &Importer{
+		client:      client,
+		dir:         dir,
+		Interval:    DEFAULT_IMPORT_INTERVAL,
+		MaxRetries:  DEFAULT_IMPORT_MAX_RETRIES,
+		Attribution: DefaultImportAttribution,
+	}
+}
+
+var importMentionRegex = regexp.MustCompile(`@<=(=[0-9A-Za-z]+)=>`)
+
+// Import posts messages of source vchannel in archive to target vchannel,
+// returning count of messages posted by this call.
+func (i *Importer) Import(ctx context.Context, sourceVChannelID, targetVChannelID string) (int, error) {
+	manifest, err := ReadExportManifest(i.dir)
+	if err != nil {
+		return 0, err
+	}
+	var entry *ExportManifestVChannel
+	for _, e := range manifest.VChannels {
+		if e.VChannelID == sourceVChannelID {
+			entry = e
+		}
+	}
+	if entry == nil {
+		return 0, fmt.Errorf("vchannel %s not found in archive", sourceVChannelID)
+	}
+
+	state, err := i.readState()
+	if err != nil {
+		return 0, err
+	}
+	stateKey := sourceVChannelID + ":" + targetVChannelID
+
+	f, err := os.Open(filepath.Join(i.dir, entry.File))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line, posted := 0, 0
+	for scanner.Scan() {
+		line = line + 1
+		if line <= state[stateKey] {
+			continue
+		}
+		if line > entry.Messages {
+			// written by an interrupted export
+			break
+		}
+
+		var m ExportedMessage
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			return posted, fmt.Errorf("%s:%d: %s", entry.File, line, err)
+		}
+		if m.Message == nil {
+			m.Message = &Message{}
+		}
+
+		opt, err := i.messageCreateOptions(ctx, &m, targetVChannelID)
+		if err != nil {
+			return posted, fmt.Errorf("%s:%d: %s", entry.File, line, err)
+		}
+		if posted > 0 {
+			if err := importSleep(ctx, i.Interval); err != nil {
+				return posted, err
+			}
+		}
+		if err := i.create(ctx, opt); err != nil {
+			return posted, fmt.Errorf("%s:%d: %s", entry.File, line, err)
+		}
+		posted = posted + 1
+
+		state[stateKey] = line
+		if err := i.writeState(state); err != nil {
+			return posted, err
+		}
+	}
+
+	return posted, scanner.Err()
+}
+
+func (i *Importer) messageCreateOptions(ctx context.Context, m *ExportedMessage, targetVChannelID string) (*MessageCreateOptions, error) {
+	text := importMentionRegex.ReplaceAllStringFunc(strv(m.Message.Text), func(mention string) string {
+		uid := importMentionRegex.FindStringSubmatch(mention)[1]
+		if mapped, present := i.UserIDs[uid]; present {
+			return "@<=" + mapped + "=>"
+		}
+		return mention
+	})
+
+	sender := strv(m.Message.UID)
+	if m.User != nil && m.User.Name != "" {
+		sender = m.User.Name
+	}
+	if mapped, present := i.UserIDs[strv(m.Message.UID)]; present {
+		sender = "@<=" + mapped + "=>"
+	}
+	if sender == "" {
+		sender = "robot"
+	}
+
+	attribution := i.Attribution
+	if attribution == nil {
+		attribution = DefaultImportAttribution
+	}
+
+	if len(m.Files) > 0 && i.FileURL == nil {
+		return nil, fmt.Errorf("message has downloaded files, set Importer.FileURL to re-upload them")
+	}
+	// re-posted urls by original url
+	urls := map[string]string{}
+	for _, file := range m.Files {
+		u, err := i.FileURL(ctx, file)
+		if err != nil {
+			return nil, err
+		}
+		urls[file.URL] = u
+	}
+
+	attachments := make([]MessageAttachment, 0, len(m.Message.Attachments))
+	for _, a := range m.Message.Attachments {
+		images := make([]MessageAttachmentImage, 0, len(a.Images))
+		for _, image := range a.Images {
+			u := strv(image.Url)
+			if mapped, present := urls[u]; present {
+				u = mapped
+			}
+			images = append(images, MessageAttachmentImage{Url: &u})
+		}
+		a.Images = images
+		attachments = append(attachments, a)
+	}
+
+	return &MessageCreateOptions{
+		VChannelID:  targetVChannelID,
+		Text:        attribution(m, sender, text),
+		Attachments: attachments,
+	}, nil
+}
+
+// create posts message, retrying when rate limited.
+func (i *Importer) create(ctx context.Context, opt *MessageCreateOptions) error {
+	backoff := i.Interval
+	if backoff <= 0 {
+		backoff = DEFAULT_IMPORT_INTERVAL
+	}
+
+	for retry := 0; ; retry = retry + 1 {
+		_, resp, err := i.client.Message.Create(ctx, opt)
+		if err == nil {
+			return nil
+		}
+		if resp == nil || resp.StatusCode != http.StatusTooManyRequests || retry >= i.MaxRetries {
+			return err
+		}
+
+		wait := backoff << uint(retry)
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			wait = time.Duration(seconds) * time.Second
+		}
+		if err := importSleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+func (i *Importer) readState() (ImportState, error) {
+	b, err := os.ReadFile(filepath.Join(i.dir, ImportStateFile))
+	if os.IsNotExist(err) {
+		return ImportState{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state := ImportState{}
+	if err := json.Unmarshal(b, &state); err != nil {
+		return nil, fmt.Errorf("decode import state failed: %s", err)
+	}
+	return state, nil
+}
+
+// writeState replaces state file atomically.
+func (i *Importer) writeState(state ImportState) error {
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(i.dir, ImportStateFile+".tmp")
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(i.dir, ImportStateFile))
+}
+
+func importSleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
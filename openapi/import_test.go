@@ -0,0 +1,129 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestArchive(t *testing.T, dir string, messages []string) {
+	manifest := &ExportManifest{
+		Version: ExportManifestVersion,
+		VChannels: []*ExportManifestVChannel{
+			{VChannelID: "=vc1", File: "=vc1.jsonl", Messages: len(messages)},
+		},
+	}
+	if err := manifest.write(dir); err != nil {
+		t.Fatalf("write manifest failed: %+v", err)
+	}
+	// trailing partial line of an interrupted export
+	content := strings.Join(messages, "\n") + "\n" + `{"message":`
+	if err := os.WriteFile(filepath.Join(dir, "=vc1.jsonl"), []byte(content), 0644); err != nil {
+		t.Fatalf("write messages failed: %+v", err)
+	}
+}
+
+func TestImporter(t *testing.T) {
+	var (
+		created     []MessageCreateOptions
+		rateLimited bool
+		failOnce    = true
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var opt MessageCreateOptions
+		json.NewDecoder(r.Body).Decode(&opt)
+		if !rateLimited {
+			rateLimited = true
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		if strings.Contains(opt.Text, "third") && failOnce {
+			failOnce = false
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		created = append(created, opt)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	writeTestArchive(t, dir, []string{
+		`{"message":{"uid":"=u1","created_ts":1540736786063,"text":"hi @<==u2=>"},"user":{"id":"=u1","name":"alice"}}`,
+		`{"message":{"uid":"=u2","text":"image","attachments":[{"images":[{"url":"https://old/a.png"}]}]},"files":[{"url":"https://old/a.png","path":"files/=vc1/a.png"}]}`,
+		`{"message":{"uid":"=u3","text":"third"}}`,
+	})
+
+	baseURL, _ := url.Parse(server.URL + "/")
+	i := NewImporter(NewClient("foobar", NewClientWithBaseURL(baseURL)), dir)
+	i.Interval = 0
+	i.UserIDs = map[string]string{"=u2": "=n2"}
+	i.FileURL = func(ctx context.Context, file ExportedFile) (string, error) {
+		return "https://new/" + filepath.Base(file.Path), nil
+	}
+	ctx := context.Background()
+
+	posted, err := i.Import(ctx, "=vc1", "=target")
+	if err == nil || posted != 2 {
+		t.Fatalf("expected failure at third message: %d %+v", posted, err)
+	}
+	posted, err = i.Import(ctx, "=vc1", "=target")
+	if err != nil || posted != 1 {
+		t.Fatalf("unexpected resume: %d %+v", posted, err)
+	}
+	if posted, _ := i.Import(ctx, "=vc1", "=target"); posted != 0 {
+		t.Errorf("rerun should post nothing, posted %d", posted)
+	}
+
+	if len(created) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(created))
+	}
+	if created[0].VChannelID != "=target" || created[0].Text != "alice (2018-10-28 14:26:26 UTC)\nhi @<==n2=>" {
+		t.Errorf("unexpected message: %+v", created[0])
+	}
+	if !strings.HasPrefix(created[1].Text, "@<==n2=>") || *created[1].Attachments[0].Images[0].Url != "https://new/a.png" {
+		t.Errorf("unexpected message: %+v", created[1])
+	}
+	if !strings.HasPrefix(created[2].Text, "=u3") {
+		t.Errorf("unexpected message: %+v", created[2])
+	}
+}
+
+func TestImporter_FileURLRequired(t *testing.T) {
+	created := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		created = created + 1
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	writeTestArchive(t, dir, []string{
+		`{"message":{"uid":"=u1","text":"hi"}}`,
+		`{"message":{"uid":"=u2","text":"image","attachments":[{"images":[{"url":"https://old/a.png"}]}]},"files":[{"url":"https://old/a.png","path":"files/=vc1/a.png"}]}`,
+	})
+
+	baseURL, _ := url.Parse(server.URL + "/")
+	i := NewImporter(NewClient("foobar", NewClientWithBaseURL(baseURL)), dir)
+	i.Interval = 0
+	ctx := context.Background()
+
+	posted, err := i.Import(ctx, "=vc1", "=target")
+	if err == nil || !strings.Contains(err.Error(), "FileURL") || posted != 1 || created != 1 {
+		t.Fatalf("expected FileURL required at second message: %d %+v", posted, err)
+	}
+
+	i.FileURL = func(ctx context.Context, file ExportedFile) (string, error) {
+		return "https://new/" + filepath.Base(file.Path), nil
+	}
+	if posted, err := i.Import(ctx, "=vc1", "=target"); err != nil || posted != 1 {
+		t.Errorf("unexpected resume: %d %+v", posted, err)
+	}
+}
@@ -0,0 +1,23 @@
+package openapi
+
+// Logger logs structured events, *slog.Logger satisfies it:
+//
+//      client := openapi.NewClient(token, openapi.NewClientWithLogger(slog.Default()))
+//
+// Args are alternating keys and values. Tokens are not logged.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// NopLogger is the default Logger logging nothing.
+var NopLogger Logger = nopLogger{}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, args ...interface{}) {}
+func (nopLogger) Info(msg string, args ...interface{})  {}
+func (nopLogger) Warn(msg string, args ...interface{})  {}
+func (nopLogger) Error(msg string, args ...interface{}) {}
@@ -0,0 +1,266 @@
+package openapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	DEFAULT_MEMBERSHIP_SYNC_CONCURRENCY = 4
+)
+
+// MembershipService syncs channel members to a desired member set,
+// on top of Channel and SessionChannel services.
+//
+//      report, _, err := client.Membership.SyncChannel(ctx, "=bw52O", &openapi.MembershipSyncOptions{
+//              Members: []string{"alice@example.com", "@bob", "=bw52P"},
+//      })
+type MembershipService service
+
+type MembershipSyncOptions struct {
+	// Desired members as user ids, emails or names (with or without `@`)
+	Members []string
+	// Users never kicked, e.g. the robot itself, in the same forms as Members
+	Keep []string
+	// Compute changes only, without inviting or kicking
+	DryRun bool
+	// Allow Members to resolve to no user, which kicks everyone not kept.
+	// It's rejected by default, as it's likely an empty roster by mistake.
+	AllowEmpty bool
+	// Max concurrent invite/kick calls, DEFAULT_MEMBERSHIP_SYNC_CONCURRENCY if not positive
+	Concurrency int
+	// Resolver for Members and Keep, reuse it when syncing many channels.
+	// A new resolver is created if nil.
+	Resolver *Resolver
+}
+
+// MembershipChange is an invite or kick of one user.
+type MembershipChange struct {
+	UserID string
+	// Err is nil on success or in dry run
+	Err error
+}
+
+// MembershipSyncReport tells what is changed by a sync.
+type MembershipSyncReport struct {
+	VChannel  VChannel
+	DryRun    bool
+	Invited   []MembershipChange
+	Kicked    []MembershipChange
+	Unchanged []string
+}
+
+// Err aggregates failed changes, nil if all succeeded.
+func (r *MembershipSyncReport) Err() error {
+	var failed []string
+	for _, c := range r.Invited {
+		if c.Err != nil {
+			failed = append(failed, fmt.Sprintf("invite %s: %s", c.UserID, c.Err))
+		}
+	}
+	for _, c := range r.Kicked {
+		if c.Err != nil {
+			failed = append(failed, fmt.Sprintf("kick %s: %s", c.UserID, c.Err))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("membership sync of %s failed: %s", r.VChannel.GetID(), strings.Join(failed, "; "))
+}
+
+// SyncChannel invites and kicks channel members to match opt.Members.
+//
+// Nothing is changed if any member can't be resolved, or opt.Members is
+// empty without opt.AllowEmpty. Changes not made before ctx is done fail
+// with ctx.Err().
+func (m *MembershipService) SyncChannel(ctx context.Context, channelID string, opt *MembershipSyncOptions) (*MembershipSyncReport, *http.Response, error) {
+	channel, resp, err := m.client.Channel.Info(ctx, &ChannelInfoOptions{ChannelID: channelID})
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return m.sync(ctx, channel, opt, func(ctx context.Context, userID string) error {
+		_, _, err := m.client.Channel.Invite(ctx, &ChannelInviteOptions{
+			ChannelID:    channelID,
+			InviteUserID: userID,
+		})
+		return err
+	}, func(ctx context.Context, userID string) error {
+		_, _, err := m.client.Channel.Kick(ctx, &ChannelKickOptions{
+			ChannelID:  channelID,
+			KickUserID: userID,
+		})
+		return err
+	})
+}
+
+// SyncSessionChannel invites and kicks session channel members to match opt.Members.
+//
+// Nothing is changed if any member can't be resolved, or opt.Members is
+// empty without opt.AllowEmpty. Changes not made before ctx is done fail
+// with ctx.Err().
+func (m *MembershipService) SyncSessionChannel(ctx context.Context, channelID string, opt *MembershipSyncOptions) (*MembershipSyncReport, *http.Response, error) {
+	channel, resp, err := m.client.SessionChannel.Info(ctx, &SessionChannelInfoOptions{ChannelID: channelID})
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return m.sync(ctx, channel, opt, func(ctx context.Context, userID string) error {
+		_, _, err := m.client.SessionChannel.Invite(ctx, &SessionChannelInviteOptions{
+			ChannelID:    channelID,
+			InviteUserID: userID,
+		})
+		return err
+	}, func(ctx context.Context, userID string) error {
+		_, _, err := m.client.SessionChannel.Kick(ctx, &SessionChannelKickOptions{
+			ChannelID:  channelID,
+			KickUserID: userID,
+		})
+		return err
+	})
+}
+
+type membershipChangeFunc func(ctx context.Context, userID string) error
+
+func (m *MembershipService) sync(ctx context.Context, vchannel VChannel, opt *MembershipSyncOptions, invite, kick membershipChangeFunc) (*MembershipSyncReport, *http.Response, error) {
+	resolver := opt.Resolver
+	if resolver == nil {
+		resolver = NewResolver(m.client)
+	}
+
+	desired, err := resolveMembers(ctx, resolver, opt.Members)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(desired) == 0 && !opt.AllowEmpty {
+		return nil, nil, fmt.Errorf("no members to sync, set AllowEmpty to kick all members")
+	}
+	keep, err := resolveMembers(ctx, resolver, opt.Keep)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	invites, kicks, unchanged := diffMembers(vchannel.GetMemberUserIDs(), desired, keep)
+	report := &MembershipSyncReport{
+		VChannel:  vchannel,
+		DryRun:    opt.DryRun,
+		Unchanged: unchanged,
+	}
+	for _, id := range invites {
+		report.Invited = append(report.Invited, MembershipChange{UserID: id})
+	}
+	for _, id := range kicks {
+		report.Kicked = append(report.Kicked, MembershipChange{UserID: id})
+	}
+
+	if opt.DryRun {
+		return report, nil, nil
+	}
+
+	concurrency := opt.Concurrency
+	if concurrency <= 0 {
+		concurrency = DEFAULT_MEMBERSHIP_SYNC_CONCURRENCY
+	}
+	sem := make(chan struct{}, concurrency)
+	acquire := func() bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		select {
+		case sem <- struct{}{}:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	var wg sync.WaitGroup
+	apply := func(changes []MembershipChange, change membershipChangeFunc) {
+		for i := range changes {
+			if !acquire() {
+				for ; i < len(changes); i = i + 1 {
+					changes[i].Err = ctx.Err()
+				}
+				return
+			}
+			wg.Add(1)
+			go func(c *MembershipChange) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				c.Err = change(ctx, c.UserID)
+			}(&changes[i])
+		}
+	}
+	apply(report.Invited, invite)
+	apply(report.Kicked, kick)
+	wg.Wait()
+
+	return report, nil, nil
+}
+
+// diffMembers returns sorted user ids to invite, to kick and unchanged,
+// for current members to match desired ones. Users to keep are never kicked.
+func diffMembers(current []string, desired, keep map[string]bool) (invites, kicks, unchanged []string) {
+	currentSet := make(map[string]bool, len(current))
+	for _, id := range current {
+		currentSet[id] = true
+	}
+	for id := range desired {
+		if currentSet[id] {
+			unchanged = append(unchanged, id)
+		} else {
+			invites = append(invites, id)
+		}
+	}
+	for id := range currentSet {
+		if desired[id] {
+			continue
+		}
+		if keep[id] {
+			unchanged = append(unchanged, id)
+		} else {
+			kicks = append(kicks, id)
+		}
+	}
+	sort.Strings(invites)
+	sort.Strings(kicks)
+	sort.Strings(unchanged)
+	return
+}
+
+// resolveMembers resolves member references to a user id set,
+// plain names are tried as `@name` when they are not user ids.
+func resolveMembers(ctx context.Context, resolver *Resolver, refs []string) (map[string]bool, error) {
+	ids := make(map[string]bool, len(refs))
+	var failed []string
+	for _, ref := range refs {
+		ref = strings.TrimSpace(ref)
+		if ref == "" {
+			continue
+		}
+
+		user, err := resolver.User(ctx, ref)
+		if IsNotFound(err) && !strings.Contains(ref, "@") {
+			user, err = resolver.User(ctx, "@"+ref)
+		}
+		if err != nil {
+			if _, ok := err.(*ResolveError); !ok {
+				return nil, err
+			}
+			failed = append(failed, err.Error())
+			continue
+		}
+		ids[strv(user.ID)] = true
+	}
+
+	if len(failed) > 0 {
+		return nil, fmt.Errorf("resolve members failed: %s", strings.Join(failed, "; "))
+	}
+	return ids, nil
+}
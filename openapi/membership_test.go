@@ -0,0 +1,183 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// newTestMembershipServer serves a channel with members =u1, =u3 and =bot,
+// onChange is called on each invite or kick if not nil.
+func newTestMembershipServer(onChange func()) (*httptest.Server, func() []string) {
+	var (
+		lock  sync.Mutex
+		calls []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/user.list":
+			w.Write([]byte(`[
+				{"id":"=u1","name":"alice","email":"alice@example.com"},
+				{"id":"=u2","name":"bob","email":"bob@example.com"},
+				{"id":"=u3","name":"carol","email":"carol@example.com"},
+				{"id":"=bot","name":"robot"}
+			]`))
+		case "/channel.info", "/session_channel.info":
+			w.Write([]byte(`{"id":"=ch1","vchannel_id":"=vc1","member_uids":["=u1","=u3","=bot"]}`))
+		case "/channel.invite", "/channel.kick", "/session_channel.invite", "/session_channel.kick":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			lock.Lock()
+			calls = append(calls, r.URL.Path+" "+body["invite_uid"]+body["kick_uid"])
+			lock.Unlock()
+			if onChange != nil {
+				onChange()
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	return server, func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		sorted := append([]string(nil), calls...)
+		sort.Strings(sorted)
+		return sorted
+	}
+}
+
+func TestMembershipService_SyncChannel(t *testing.T) {
+	server, calls := newTestMembershipServer(nil)
+	defer server.Close()
+
+	baseURL, _ := url.Parse(server.URL + "/")
+	client := NewClient("foobar", NewClientWithBaseURL(baseURL))
+	ctx := context.Background()
+
+	opt := &MembershipSyncOptions{
+		Members: []string{"alice@example.com", "bob"},
+		Keep:    []string{"@robot"},
+		DryRun:  true,
+	}
+	report, _, err := client.Membership.SyncChannel(ctx, "=ch1", opt)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if !reflect.DeepEqual(report.Invited, []MembershipChange{{UserID: "=u2"}}) ||
+		!reflect.DeepEqual(report.Kicked, []MembershipChange{{UserID: "=u3"}}) ||
+		!reflect.DeepEqual(report.Unchanged, []string{"=bot", "=u1"}) {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if len(calls()) != 0 {
+		t.Errorf("dry run should not change members: %v", calls())
+	}
+
+	opt.DryRun = false
+	report, _, err = client.Membership.SyncChannel(ctx, "=ch1", opt)
+	if err != nil || report.Err() != nil {
+		t.Fatalf("unexpected error: %+v %+v", err, report.Err())
+	}
+	expected := []string{"/channel.invite =u2", "/channel.kick =u3"}
+	if !reflect.DeepEqual(calls(), expected) {
+		t.Errorf("expected calls %v, got %v", expected, calls())
+	}
+}
+
+func TestMembershipService_SyncSessionChannel(t *testing.T) {
+	server, calls := newTestMembershipServer(nil)
+	defer server.Close()
+
+	baseURL, _ := url.Parse(server.URL + "/")
+	client := NewClient("foobar", NewClientWithBaseURL(baseURL))
+	ctx := context.Background()
+
+	_, _, err := client.Membership.SyncSessionChannel(ctx, "=ch1", &MembershipSyncOptions{
+		Members: []string{"@alice", "@nobody"},
+	})
+	if err == nil {
+		t.Errorf("expected unresolved member error")
+	}
+	if len(calls()) != 0 {
+		t.Errorf("nothing should change with unresolved members: %v", calls())
+	}
+
+	_, _, err = client.Membership.SyncSessionChannel(ctx, "=ch1", &MembershipSyncOptions{
+		Members:     []string{"=u1", "=u2", "=u3", "=bot"},
+		Concurrency: 1,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	expected := []string{"/session_channel.invite =u2"}
+	if !reflect.DeepEqual(calls(), expected) {
+		t.Errorf("expected calls %v, got %v", expected, calls())
+	}
+}
+
+func TestMembershipService_SyncChannel_Empty(t *testing.T) {
+	server, calls := newTestMembershipServer(nil)
+	defer server.Close()
+
+	baseURL, _ := url.Parse(server.URL + "/")
+	client := NewClient("foobar", NewClientWithBaseURL(baseURL))
+	ctx := context.Background()
+
+	for _, members := range [][]string{nil, {" ", ""}} {
+		_, _, err := client.Membership.SyncChannel(ctx, "=ch1", &MembershipSyncOptions{
+			Members: members,
+			Keep:    []string{"@robot"},
+		})
+		if err == nil {
+			t.Errorf("expected error for empty members %q", members)
+		}
+	}
+	if len(calls()) != 0 {
+		t.Errorf("nothing should change with empty members: %v", calls())
+	}
+
+	_, _, err := client.Membership.SyncChannel(ctx, "=ch1", &MembershipSyncOptions{
+		Keep:       []string{"@robot"},
+		AllowEmpty: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	expected := []string{"/channel.kick =u1", "/channel.kick =u3"}
+	if !reflect.DeepEqual(calls(), expected) {
+		t.Errorf("expected calls %v, got %v", expected, calls())
+	}
+}
+
+func TestMembershipService_SyncChannel_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server, calls := newTestMembershipServer(cancel)
+	defer server.Close()
+
+	baseURL, _ := url.Parse(server.URL + "/")
+	client := NewClient("foobar", NewClientWithBaseURL(baseURL))
+
+	report, _, err := client.Membership.SyncChannel(ctx, "=ch1", &MembershipSyncOptions{
+		Members:     []string{"=u1", "=u2"},
+		Keep:        []string{"=bot"},
+		Concurrency: 1,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if len(report.Kicked) != 1 || report.Kicked[0].Err != context.Canceled {
+		t.Errorf("expected kick canceled: %+v", report.Kicked)
+	}
+	expected := []string{"/channel.invite =u2"}
+	if !reflect.DeepEqual(calls(), expected) {
+		t.Errorf("expected calls %v, got %v", expected, calls())
+	}
+}
@@ -45,25 +45,26 @@ type Repost struct {
 }
 
 type Message struct {
-	Repost          *Repost            `json:"repost,omitempty"`
-	Key             *MessageKey        `json:"key,omitempty"`
-	Updated         *Time              `json:"updated,omitempty"`
-	UID             *string            `json:"uid,omitempty"`
-	Created         *Time              `json:"created,omitempty"`
-	VchannelID      *string            `json:"vchannel_id,omitempty"`
-	ReferKey        *string            `json:"refer_key,omitempty"`
-	RobotID         *string            `json:"robot_id,omitempty"`
-	Edited          *bool              `json:"edited,omitempty"`
-	CreatedTS       *VChannelTS        `json:"created_ts,omitempty"`
-	PinID           *string            `json:"pin_id,omitempty"`
-	StarID          *string            `json:"star_id,omitempty"`
-	ID              *string            `json:"id,omitempty"`
-	TeamID          *string            `json:"team_id,omitempty"`
-	TextI18n        *map[string]string `json:"text_i18n,omitempty"`
-	Reactions       []Reaction         `json:"reactions,omitempty"`
-	Subtype         *MessageSubtype    `json:"subtype,omitempty"`
-	Text            *string            `json:"text,omitempty"`
-	DisableMarkdown *bool              `json:"disable_markdown,omitempty"`
+	Repost          *Repost             `json:"repost,omitempty"`
+	Key             *MessageKey         `json:"key,omitempty"`
+	Updated         *Time               `json:"updated,omitempty"`
+	UID             *string             `json:"uid,omitempty"`
+	Created         *Time               `json:"created,omitempty"`
+	VchannelID      *string             `json:"vchannel_id,omitempty"`
+	ReferKey        *string             `json:"refer_key,omitempty"`
+	RobotID         *string             `json:"robot_id,omitempty"`
+	Edited          *bool               `json:"edited,omitempty"`
+	CreatedTS       *VChannelTS         `json:"created_ts,omitempty"`
+	PinID           *string             `json:"pin_id,omitempty"`
+	StarID          *string             `json:"star_id,omitempty"`
+	ID              *string             `json:"id,omitempty"`
+	TeamID          *string             `json:"team_id,omitempty"`
+	TextI18n        *map[string]string  `json:"text_i18n,omitempty"`
+	Reactions       []Reaction          `json:"reactions,omitempty"`
+	Subtype         *MessageSubtype     `json:"subtype,omitempty"`
+	Text            *string             `json:"text,omitempty"`
+	DisableMarkdown *bool               `json:"disable_markdown,omitempty"`
+	Attachments     []MessageAttachment `json:"attachments,omitempty"`
 }
 
 type MessageService service
@@ -0,0 +1,810 @@
+// Code generated by openapimock/gen. DO NOT EDIT.
+
+package openapimock
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/nanmu42/bearychat-go/openapi"
+)
+
+// MetaAPI mocks openapi.MetaAPI, methods call their Func fields.
+type MetaAPI struct {
+	Recorder
+
+	GetFunc func(ctx context.Context) (*openapi.Meta, *http.Response, error)
+}
+
+// Get records the call, and calls GetFunc.
+func (m *MetaAPI) Get(ctx context.Context) (*openapi.Meta, *http.Response, error) {
+	m.record("MetaAPI", "Get")
+	if m.GetFunc == nil {
+		var (
+			r0 *openapi.Meta
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("MetaAPI", "Get")
+	}
+	return m.GetFunc(ctx)
+}
+
+var _ openapi.MetaAPI = (*MetaAPI)(nil)
+
+// TeamAPI mocks openapi.TeamAPI, methods call their Func fields.
+type TeamAPI struct {
+	Recorder
+
+	InfoFunc func(ctx context.Context) (*openapi.Team, *http.Response, error)
+}
+
+// Info records the call, and calls InfoFunc.
+func (m *TeamAPI) Info(ctx context.Context) (*openapi.Team, *http.Response, error) {
+	m.record("TeamAPI", "Info")
+	if m.InfoFunc == nil {
+		var (
+			r0 *openapi.Team
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("TeamAPI", "Info")
+	}
+	return m.InfoFunc(ctx)
+}
+
+var _ openapi.TeamAPI = (*TeamAPI)(nil)
+
+// UserAPI mocks openapi.UserAPI, methods call their Func fields.
+type UserAPI struct {
+	Recorder
+
+	InfoFunc func(ctx context.Context, opt *openapi.UserInfoOptions) (*openapi.User, *http.Response, error)
+	ListFunc func(ctx context.Context) ([]*openapi.User, *http.Response, error)
+	MeFunc   func(ctx context.Context) (*openapi.User, *http.Response, error)
+}
+
+// Info records the call, and calls InfoFunc.
+func (m *UserAPI) Info(ctx context.Context, opt *openapi.UserInfoOptions) (*openapi.User, *http.Response, error) {
+	m.record("UserAPI", "Info", opt)
+	if m.InfoFunc == nil {
+		var (
+			r0 *openapi.User
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("UserAPI", "Info")
+	}
+	return m.InfoFunc(ctx, opt)
+}
+
+// List records the call, and calls ListFunc.
+func (m *UserAPI) List(ctx context.Context) ([]*openapi.User, *http.Response, error) {
+	m.record("UserAPI", "List")
+	if m.ListFunc == nil {
+		var (
+			r0 []*openapi.User
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("UserAPI", "List")
+	}
+	return m.ListFunc(ctx)
+}
+
+// Me records the call, and calls MeFunc.
+func (m *UserAPI) Me(ctx context.Context) (*openapi.User, *http.Response, error) {
+	m.record("UserAPI", "Me")
+	if m.MeFunc == nil {
+		var (
+			r0 *openapi.User
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("UserAPI", "Me")
+	}
+	return m.MeFunc(ctx)
+}
+
+var _ openapi.UserAPI = (*UserAPI)(nil)
+
+// ChannelAPI mocks openapi.ChannelAPI, methods call their Func fields.
+type ChannelAPI struct {
+	Recorder
+
+	InfoFunc      func(ctx context.Context, opt *openapi.ChannelInfoOptions) (*openapi.Channel, *http.Response, error)
+	ListFunc      func(ctx context.Context) ([]*openapi.Channel, *http.Response, error)
+	CreateFunc    func(ctx context.Context, opt *openapi.ChannelCreateOptions) (*openapi.Channel, *http.Response, error)
+	ArchiveFunc   func(ctx context.Context, opt *openapi.ChannelArchiveOptions) (*openapi.Channel, *http.Response, error)
+	UnarchiveFunc func(ctx context.Context, opt *openapi.ChannelUnarchiveOptions) (*openapi.Channel, *http.Response, error)
+	LeaveFunc     func(ctx context.Context, opt *openapi.ChannelLeaveOptions) (*openapi.ResponseNoContent, *http.Response, error)
+	JoinFunc      func(ctx context.Context, opt *openapi.ChannelJoinOptions) (*openapi.Channel, *http.Response, error)
+	InviteFunc    func(ctx context.Context, opt *openapi.ChannelInviteOptions) (*openapi.ResponseNoContent, *http.Response, error)
+	KickFunc      func(ctx context.Context, opt *openapi.ChannelKickOptions) (*openapi.ResponseNoContent, *http.Response, error)
+	KickoutFunc   func(ctx context.Context, opt *openapi.ChannelKickOptions) (*openapi.ResponseNoContent, *http.Response, error)
+}
+
+// Info records the call, and calls InfoFunc.
+func (m *ChannelAPI) Info(ctx context.Context, opt *openapi.ChannelInfoOptions) (*openapi.Channel, *http.Response, error) {
+	m.record("ChannelAPI", "Info", opt)
+	if m.InfoFunc == nil {
+		var (
+			r0 *openapi.Channel
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("ChannelAPI", "Info")
+	}
+	return m.InfoFunc(ctx, opt)
+}
+
+// List records the call, and calls ListFunc.
+func (m *ChannelAPI) List(ctx context.Context) ([]*openapi.Channel, *http.Response, error) {
+	m.record("ChannelAPI", "List")
+	if m.ListFunc == nil {
+		var (
+			r0 []*openapi.Channel
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("ChannelAPI", "List")
+	}
+	return m.ListFunc(ctx)
+}
+
+// Create records the call, and calls CreateFunc.
+func (m *ChannelAPI) Create(ctx context.Context, opt *openapi.ChannelCreateOptions) (*openapi.Channel, *http.Response, error) {
+	m.record("ChannelAPI", "Create", opt)
+	if m.CreateFunc == nil {
+		var (
+			r0 *openapi.Channel
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("ChannelAPI", "Create")
+	}
+	return m.CreateFunc(ctx, opt)
+}
+
+// Archive records the call, and calls ArchiveFunc.
+func (m *ChannelAPI) Archive(ctx context.Context, opt *openapi.ChannelArchiveOptions) (*openapi.Channel, *http.Response, error) {
+	m.record("ChannelAPI", "Archive", opt)
+	if m.ArchiveFunc == nil {
+		var (
+			r0 *openapi.Channel
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("ChannelAPI", "Archive")
+	}
+	return m.ArchiveFunc(ctx, opt)
+}
+
+// Unarchive records the call, and calls UnarchiveFunc.
+func (m *ChannelAPI) Unarchive(ctx context.Context, opt *openapi.ChannelUnarchiveOptions) (*openapi.Channel, *http.Response, error) {
+	m.record("ChannelAPI", "Unarchive", opt)
+	if m.UnarchiveFunc == nil {
+		var (
+			r0 *openapi.Channel
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("ChannelAPI", "Unarchive")
+	}
+	return m.UnarchiveFunc(ctx, opt)
+}
+
+// Leave records the call, and calls LeaveFunc.
+func (m *ChannelAPI) Leave(ctx context.Context, opt *openapi.ChannelLeaveOptions) (*openapi.ResponseNoContent, *http.Response, error) {
+	m.record("ChannelAPI", "Leave", opt)
+	if m.LeaveFunc == nil {
+		var (
+			r0 *openapi.ResponseNoContent
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("ChannelAPI", "Leave")
+	}
+	return m.LeaveFunc(ctx, opt)
+}
+
+// Join records the call, and calls JoinFunc.
+func (m *ChannelAPI) Join(ctx context.Context, opt *openapi.ChannelJoinOptions) (*openapi.Channel, *http.Response, error) {
+	m.record("ChannelAPI", "Join", opt)
+	if m.JoinFunc == nil {
+		var (
+			r0 *openapi.Channel
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("ChannelAPI", "Join")
+	}
+	return m.JoinFunc(ctx, opt)
+}
+
+// Invite records the call, and calls InviteFunc.
+func (m *ChannelAPI) Invite(ctx context.Context, opt *openapi.ChannelInviteOptions) (*openapi.ResponseNoContent, *http.Response, error) {
+	m.record("ChannelAPI", "Invite", opt)
+	if m.InviteFunc == nil {
+		var (
+			r0 *openapi.ResponseNoContent
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("ChannelAPI", "Invite")
+	}
+	return m.InviteFunc(ctx, opt)
+}
+
+// Kick records the call, and calls KickFunc.
+func (m *ChannelAPI) Kick(ctx context.Context, opt *openapi.ChannelKickOptions) (*openapi.ResponseNoContent, *http.Response, error) {
+	m.record("ChannelAPI", "Kick", opt)
+	if m.KickFunc == nil {
+		var (
+			r0 *openapi.ResponseNoContent
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("ChannelAPI", "Kick")
+	}
+	return m.KickFunc(ctx, opt)
+}
+
+// Kickout records the call, and calls KickoutFunc.
+func (m *ChannelAPI) Kickout(ctx context.Context, opt *openapi.ChannelKickOptions) (*openapi.ResponseNoContent, *http.Response, error) {
+	m.record("ChannelAPI", "Kickout", opt)
+	if m.KickoutFunc == nil {
+		var (
+			r0 *openapi.ResponseNoContent
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("ChannelAPI", "Kickout")
+	}
+	return m.KickoutFunc(ctx, opt)
+}
+
+var _ openapi.ChannelAPI = (*ChannelAPI)(nil)
+
+// SessionChannelAPI mocks openapi.SessionChannelAPI, methods call their Func fields.
+type SessionChannelAPI struct {
+	Recorder
+
+	InfoFunc             func(ctx context.Context, opt *openapi.SessionChannelInfoOptions) (*openapi.SessionChannel, *http.Response, error)
+	ListFunc             func(ctx context.Context) ([]*openapi.SessionChannel, *http.Response, error)
+	CreateFunc           func(ctx context.Context, opt *openapi.SessionChannelCreateOptions) (*openapi.SessionChannel, *http.Response, error)
+	ArchiveFunc          func(ctx context.Context, opt *openapi.SessionChannelArchiveOptions) (*openapi.SessionChannel, *http.Response, error)
+	ConvertToChannelFunc func(ctx context.Context, opt *openapi.SessionChannelConvertOptions) (*openapi.Channel, *http.Response, error)
+	LeaveFunc            func(ctx context.Context, opt *openapi.SessionChannelLeaveOptions) (*openapi.ResponseNoContent, *http.Response, error)
+	InviteFunc           func(ctx context.Context, opt *openapi.SessionChannelInviteOptions) (*openapi.ResponseNoContent, *http.Response, error)
+	KickFunc             func(ctx context.Context, opt *openapi.SessionChannelKickOptions) (*openapi.ResponseNoContent, *http.Response, error)
+}
+
+// Info records the call, and calls InfoFunc.
+func (m *SessionChannelAPI) Info(ctx context.Context, opt *openapi.SessionChannelInfoOptions) (*openapi.SessionChannel, *http.Response, error) {
+	m.record("SessionChannelAPI", "Info", opt)
+	if m.InfoFunc == nil {
+		var (
+			r0 *openapi.SessionChannel
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("SessionChannelAPI", "Info")
+	}
+	return m.InfoFunc(ctx, opt)
+}
+
+// List records the call, and calls ListFunc.
+func (m *SessionChannelAPI) List(ctx context.Context) ([]*openapi.SessionChannel, *http.Response, error) {
+	m.record("SessionChannelAPI", "List")
+	if m.ListFunc == nil {
+		var (
+			r0 []*openapi.SessionChannel
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("SessionChannelAPI", "List")
+	}
+	return m.ListFunc(ctx)
+}
+
+// Create records the call, and calls CreateFunc.
+func (m *SessionChannelAPI) Create(ctx context.Context, opt *openapi.SessionChannelCreateOptions) (*openapi.SessionChannel, *http.Response, error) {
+	m.record("SessionChannelAPI", "Create", opt)
+	if m.CreateFunc == nil {
+		var (
+			r0 *openapi.SessionChannel
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("SessionChannelAPI", "Create")
+	}
+	return m.CreateFunc(ctx, opt)
+}
+
+// Archive records the call, and calls ArchiveFunc.
+func (m *SessionChannelAPI) Archive(ctx context.Context, opt *openapi.SessionChannelArchiveOptions) (*openapi.SessionChannel, *http.Response, error) {
+	m.record("SessionChannelAPI", "Archive", opt)
+	if m.ArchiveFunc == nil {
+		var (
+			r0 *openapi.SessionChannel
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("SessionChannelAPI", "Archive")
+	}
+	return m.ArchiveFunc(ctx, opt)
+}
+
+// ConvertToChannel records the call, and calls ConvertToChannelFunc.
+func (m *SessionChannelAPI) ConvertToChannel(ctx context.Context, opt *openapi.SessionChannelConvertOptions) (*openapi.Channel, *http.Response, error) {
+	m.record("SessionChannelAPI", "ConvertToChannel", opt)
+	if m.ConvertToChannelFunc == nil {
+		var (
+			r0 *openapi.Channel
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("SessionChannelAPI", "ConvertToChannel")
+	}
+	return m.ConvertToChannelFunc(ctx, opt)
+}
+
+// Leave records the call, and calls LeaveFunc.
+func (m *SessionChannelAPI) Leave(ctx context.Context, opt *openapi.SessionChannelLeaveOptions) (*openapi.ResponseNoContent, *http.Response, error) {
+	m.record("SessionChannelAPI", "Leave", opt)
+	if m.LeaveFunc == nil {
+		var (
+			r0 *openapi.ResponseNoContent
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("SessionChannelAPI", "Leave")
+	}
+	return m.LeaveFunc(ctx, opt)
+}
+
+// Invite records the call, and calls InviteFunc.
+func (m *SessionChannelAPI) Invite(ctx context.Context, opt *openapi.SessionChannelInviteOptions) (*openapi.ResponseNoContent, *http.Response, error) {
+	m.record("SessionChannelAPI", "Invite", opt)
+	if m.InviteFunc == nil {
+		var (
+			r0 *openapi.ResponseNoContent
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("SessionChannelAPI", "Invite")
+	}
+	return m.InviteFunc(ctx, opt)
+}
+
+// Kick records the call, and calls KickFunc.
+func (m *SessionChannelAPI) Kick(ctx context.Context, opt *openapi.SessionChannelKickOptions) (*openapi.ResponseNoContent, *http.Response, error) {
+	m.record("SessionChannelAPI", "Kick", opt)
+	if m.KickFunc == nil {
+		var (
+			r0 *openapi.ResponseNoContent
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("SessionChannelAPI", "Kick")
+	}
+	return m.KickFunc(ctx, opt)
+}
+
+var _ openapi.SessionChannelAPI = (*SessionChannelAPI)(nil)
+
+// MessageAPI mocks openapi.MessageAPI, methods call their Func fields.
+type MessageAPI struct {
+	Recorder
+
+	QueryFunc      func(ctx context.Context, opt *openapi.MessageQueryOptions) (*openapi.MessageQueryResult, *http.Response, error)
+	InfoFunc       func(ctx context.Context, opt *openapi.MessageInfoOptions) (*openapi.Message, *http.Response, error)
+	CreateFunc     func(ctx context.Context, opt *openapi.MessageCreateOptions) (*openapi.Message, *http.Response, error)
+	DeleteFunc     func(ctx context.Context, opt *openapi.MessageDeleteOptions) (*openapi.ResponseNoContent, *http.Response, error)
+	UpdateTextFunc func(ctx context.Context, opt *openapi.MessageUpdateTextOptions) (*openapi.Message, *http.Response, error)
+	ForwardFunc    func(ctx context.Context, opt *openapi.MessageForwardOptions) (*openapi.Message, *http.Response, error)
+}
+
+// Query records the call, and calls QueryFunc.
+func (m *MessageAPI) Query(ctx context.Context, opt *openapi.MessageQueryOptions) (*openapi.MessageQueryResult, *http.Response, error) {
+	m.record("MessageAPI", "Query", opt)
+	if m.QueryFunc == nil {
+		var (
+			r0 *openapi.MessageQueryResult
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("MessageAPI", "Query")
+	}
+	return m.QueryFunc(ctx, opt)
+}
+
+// Info records the call, and calls InfoFunc.
+func (m *MessageAPI) Info(ctx context.Context, opt *openapi.MessageInfoOptions) (*openapi.Message, *http.Response, error) {
+	m.record("MessageAPI", "Info", opt)
+	if m.InfoFunc == nil {
+		var (
+			r0 *openapi.Message
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("MessageAPI", "Info")
+	}
+	return m.InfoFunc(ctx, opt)
+}
+
+// Create records the call, and calls CreateFunc.
+func (m *MessageAPI) Create(ctx context.Context, opt *openapi.MessageCreateOptions) (*openapi.Message, *http.Response, error) {
+	m.record("MessageAPI", "Create", opt)
+	if m.CreateFunc == nil {
+		var (
+			r0 *openapi.Message
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("MessageAPI", "Create")
+	}
+	return m.CreateFunc(ctx, opt)
+}
+
+// Delete records the call, and calls DeleteFunc.
+func (m *MessageAPI) Delete(ctx context.Context, opt *openapi.MessageDeleteOptions) (*openapi.ResponseNoContent, *http.Response, error) {
+	m.record("MessageAPI", "Delete", opt)
+	if m.DeleteFunc == nil {
+		var (
+			r0 *openapi.ResponseNoContent
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("MessageAPI", "Delete")
+	}
+	return m.DeleteFunc(ctx, opt)
+}
+
+// UpdateText records the call, and calls UpdateTextFunc.
+func (m *MessageAPI) UpdateText(ctx context.Context, opt *openapi.MessageUpdateTextOptions) (*openapi.Message, *http.Response, error) {
+	m.record("MessageAPI", "UpdateText", opt)
+	if m.UpdateTextFunc == nil {
+		var (
+			r0 *openapi.Message
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("MessageAPI", "UpdateText")
+	}
+	return m.UpdateTextFunc(ctx, opt)
+}
+
+// Forward records the call, and calls ForwardFunc.
+func (m *MessageAPI) Forward(ctx context.Context, opt *openapi.MessageForwardOptions) (*openapi.Message, *http.Response, error) {
+	m.record("MessageAPI", "Forward", opt)
+	if m.ForwardFunc == nil {
+		var (
+			r0 *openapi.Message
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("MessageAPI", "Forward")
+	}
+	return m.ForwardFunc(ctx, opt)
+}
+
+var _ openapi.MessageAPI = (*MessageAPI)(nil)
+
+// P2PAPI mocks openapi.P2PAPI, methods call their Func fields.
+type P2PAPI struct {
+	Recorder
+
+	InfoFunc   func(ctx context.Context, opt *openapi.P2PInfoOptions) (*openapi.P2P, *http.Response, error)
+	ListFunc   func(ctx context.Context) ([]*openapi.P2P, *http.Response, error)
+	CreateFunc func(ctx context.Context, opt *openapi.P2PCreateOptions) (*openapi.P2P, *http.Response, error)
+}
+
+// Info records the call, and calls InfoFunc.
+func (m *P2PAPI) Info(ctx context.Context, opt *openapi.P2PInfoOptions) (*openapi.P2P, *http.Response, error) {
+	m.record("P2PAPI", "Info", opt)
+	if m.InfoFunc == nil {
+		var (
+			r0 *openapi.P2P
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("P2PAPI", "Info")
+	}
+	return m.InfoFunc(ctx, opt)
+}
+
+// List records the call, and calls ListFunc.
+func (m *P2PAPI) List(ctx context.Context) ([]*openapi.P2P, *http.Response, error) {
+	m.record("P2PAPI", "List")
+	if m.ListFunc == nil {
+		var (
+			r0 []*openapi.P2P
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("P2PAPI", "List")
+	}
+	return m.ListFunc(ctx)
+}
+
+// Create records the call, and calls CreateFunc.
+func (m *P2PAPI) Create(ctx context.Context, opt *openapi.P2PCreateOptions) (*openapi.P2P, *http.Response, error) {
+	m.record("P2PAPI", "Create", opt)
+	if m.CreateFunc == nil {
+		var (
+			r0 *openapi.P2P
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("P2PAPI", "Create")
+	}
+	return m.CreateFunc(ctx, opt)
+}
+
+var _ openapi.P2PAPI = (*P2PAPI)(nil)
+
+// EmojiAPI mocks openapi.EmojiAPI, methods call their Func fields.
+type EmojiAPI struct {
+	Recorder
+
+	ListFunc func(ctx context.Context) ([]*openapi.Emoji, *http.Response, error)
+}
+
+// List records the call, and calls ListFunc.
+func (m *EmojiAPI) List(ctx context.Context) ([]*openapi.Emoji, *http.Response, error) {
+	m.record("EmojiAPI", "List")
+	if m.ListFunc == nil {
+		var (
+			r0 []*openapi.Emoji
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("EmojiAPI", "List")
+	}
+	return m.ListFunc(ctx)
+}
+
+var _ openapi.EmojiAPI = (*EmojiAPI)(nil)
+
+// StickerAPI mocks openapi.StickerAPI, methods call their Func fields.
+type StickerAPI struct {
+	Recorder
+
+	ListFunc func(ctx context.Context) ([]*openapi.StickerPack, *http.Response, error)
+}
+
+// List records the call, and calls ListFunc.
+func (m *StickerAPI) List(ctx context.Context) ([]*openapi.StickerPack, *http.Response, error) {
+	m.record("StickerAPI", "List")
+	if m.ListFunc == nil {
+		var (
+			r0 []*openapi.StickerPack
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("StickerAPI", "List")
+	}
+	return m.ListFunc(ctx)
+}
+
+var _ openapi.StickerAPI = (*StickerAPI)(nil)
+
+// RTMAPI mocks openapi.RTMAPI, methods call their Func fields.
+type RTMAPI struct {
+	Recorder
+
+	StartFunc func(ctx context.Context) (*openapi.RTMStart, *http.Response, error)
+}
+
+// Start records the call, and calls StartFunc.
+func (m *RTMAPI) Start(ctx context.Context) (*openapi.RTMStart, *http.Response, error) {
+	m.record("RTMAPI", "Start")
+	if m.StartFunc == nil {
+		var (
+			r0 *openapi.RTMStart
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("RTMAPI", "Start")
+	}
+	return m.StartFunc(ctx)
+}
+
+var _ openapi.RTMAPI = (*RTMAPI)(nil)
+
+// MessagePinAPI mocks openapi.MessagePinAPI, methods call their Func fields.
+type MessagePinAPI struct {
+	Recorder
+
+	ListFunc   func(ctx context.Context, opt *openapi.MessagePinListOptions) ([]*openapi.MessagePin, *http.Response, error)
+	CreateFunc func(ctx context.Context, opt *openapi.MessagePinCreateOptions) (*openapi.MessagePin, *http.Response, error)
+	DeleteFunc func(ctx context.Context, opt *openapi.MessagePinDeleteOptions) (*openapi.ResponseNoContent, *http.Response, error)
+}
+
+// List records the call, and calls ListFunc.
+func (m *MessagePinAPI) List(ctx context.Context, opt *openapi.MessagePinListOptions) ([]*openapi.MessagePin, *http.Response, error) {
+	m.record("MessagePinAPI", "List", opt)
+	if m.ListFunc == nil {
+		var (
+			r0 []*openapi.MessagePin
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("MessagePinAPI", "List")
+	}
+	return m.ListFunc(ctx, opt)
+}
+
+// Create records the call, and calls CreateFunc.
+func (m *MessagePinAPI) Create(ctx context.Context, opt *openapi.MessagePinCreateOptions) (*openapi.MessagePin, *http.Response, error) {
+	m.record("MessagePinAPI", "Create", opt)
+	if m.CreateFunc == nil {
+		var (
+			r0 *openapi.MessagePin
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("MessagePinAPI", "Create")
+	}
+	return m.CreateFunc(ctx, opt)
+}
+
+// Delete records the call, and calls DeleteFunc.
+func (m *MessagePinAPI) Delete(ctx context.Context, opt *openapi.MessagePinDeleteOptions) (*openapi.ResponseNoContent, *http.Response, error) {
+	m.record("MessagePinAPI", "Delete", opt)
+	if m.DeleteFunc == nil {
+		var (
+			r0 *openapi.ResponseNoContent
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("MessagePinAPI", "Delete")
+	}
+	return m.DeleteFunc(ctx, opt)
+}
+
+var _ openapi.MessagePinAPI = (*MessagePinAPI)(nil)
+
+// VChannelAPI mocks openapi.VChannelAPI, methods call their Func fields.
+type VChannelAPI struct {
+	Recorder
+
+	ListFunc          func(ctx context.Context, opt *openapi.VChannelListOptions) ([]openapi.VChannel, *http.Response, error)
+	InfoFunc          func(ctx context.Context, opt *openapi.VChannelInfoOptions) (openapi.VChannel, *http.Response, error)
+	CreateMessageFunc func(ctx context.Context, vchannel openapi.VChannel, opt *openapi.VChannelMessageOptions) (*openapi.Message, *http.Response, error)
+	QueryMessagesFunc func(ctx context.Context, vchannel openapi.VChannel, query *openapi.MessageQuery) (*openapi.MessageQueryResult, *http.Response, error)
+}
+
+// List records the call, and calls ListFunc.
+func (m *VChannelAPI) List(ctx context.Context, opt *openapi.VChannelListOptions) ([]openapi.VChannel, *http.Response, error) {
+	m.record("VChannelAPI", "List", opt)
+	if m.ListFunc == nil {
+		var (
+			r0 []openapi.VChannel
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("VChannelAPI", "List")
+	}
+	return m.ListFunc(ctx, opt)
+}
+
+// Info records the call, and calls InfoFunc.
+func (m *VChannelAPI) Info(ctx context.Context, opt *openapi.VChannelInfoOptions) (openapi.VChannel, *http.Response, error) {
+	m.record("VChannelAPI", "Info", opt)
+	if m.InfoFunc == nil {
+		var (
+			r0 openapi.VChannel
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("VChannelAPI", "Info")
+	}
+	return m.InfoFunc(ctx, opt)
+}
+
+// CreateMessage records the call, and calls CreateMessageFunc.
+func (m *VChannelAPI) CreateMessage(ctx context.Context, vchannel openapi.VChannel, opt *openapi.VChannelMessageOptions) (*openapi.Message, *http.Response, error) {
+	m.record("VChannelAPI", "CreateMessage", vchannel, opt)
+	if m.CreateMessageFunc == nil {
+		var (
+			r0 *openapi.Message
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("VChannelAPI", "CreateMessage")
+	}
+	return m.CreateMessageFunc(ctx, vchannel, opt)
+}
+
+// QueryMessages records the call, and calls QueryMessagesFunc.
+func (m *VChannelAPI) QueryMessages(ctx context.Context, vchannel openapi.VChannel, query *openapi.MessageQuery) (*openapi.MessageQueryResult, *http.Response, error) {
+	m.record("VChannelAPI", "QueryMessages", vchannel, query)
+	if m.QueryMessagesFunc == nil {
+		var (
+			r0 *openapi.MessageQueryResult
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("VChannelAPI", "QueryMessages")
+	}
+	return m.QueryMessagesFunc(ctx, vchannel, query)
+}
+
+var _ openapi.VChannelAPI = (*VChannelAPI)(nil)
+
+// MembershipAPI mocks openapi.MembershipAPI, methods call their Func fields.
+type MembershipAPI struct {
+	Recorder
+
+	SyncChannelFunc        func(ctx context.Context, channelID string, opt *openapi.MembershipSyncOptions) (*openapi.MembershipSyncReport, *http.Response, error)
+	SyncSessionChannelFunc func(ctx context.Context, channelID string, opt *openapi.MembershipSyncOptions) (*openapi.MembershipSyncReport, *http.Response, error)
+}
+
+// SyncChannel records the call, and calls SyncChannelFunc.
+func (m *MembershipAPI) SyncChannel(ctx context.Context, channelID string, opt *openapi.MembershipSyncOptions) (*openapi.MembershipSyncReport, *http.Response, error) {
+	m.record("MembershipAPI", "SyncChannel", channelID, opt)
+	if m.SyncChannelFunc == nil {
+		var (
+			r0 *openapi.MembershipSyncReport
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("MembershipAPI", "SyncChannel")
+	}
+	return m.SyncChannelFunc(ctx, channelID, opt)
+}
+
+// SyncSessionChannel records the call, and calls SyncSessionChannelFunc.
+func (m *MembershipAPI) SyncSessionChannel(ctx context.Context, channelID string, opt *openapi.MembershipSyncOptions) (*openapi.MembershipSyncReport, *http.Response, error) {
+	m.record("MembershipAPI", "SyncSessionChannel", channelID, opt)
+	if m.SyncSessionChannelFunc == nil {
+		var (
+			r0 *openapi.MembershipSyncReport
+			r1 *http.Response
+		)
+		return r0, r1, notMocked("MembershipAPI", "SyncSessionChannel")
+	}
+	return m.SyncSessionChannelFunc(ctx, channelID, opt)
+}
+
+var _ openapi.MembershipAPI = (*MembershipAPI)(nil)
+
+// API mocks openapi.API, calls to all services are recorded in order.
+type API struct {
+	Recorder
+
+	Meta           *MetaAPI
+	Team           *TeamAPI
+	User           *UserAPI
+	Channel        *ChannelAPI
+	SessionChannel *SessionChannelAPI
+	Message        *MessageAPI
+	P2P            *P2PAPI
+	Emoji          *EmojiAPI
+	Sticker        *StickerAPI
+	RTM            *RTMAPI
+	MessagePin     *MessagePinAPI
+	VChannel       *VChannelAPI
+	Membership     *MembershipAPI
+}
+
+// New creates an API with all services mocked.
+func New() *API {
+	a := &API{}
+	a.Meta = &MetaAPI{Recorder: Recorder{shared: &a.Recorder}}
+	a.Team = &TeamAPI{Recorder: Recorder{shared: &a.Recorder}}
+	a.User = &UserAPI{Recorder: Recorder{shared: &a.Recorder}}
+	a.Channel = &ChannelAPI{Recorder: Recorder{shared: &a.Recorder}}
+	a.SessionChannel = &SessionChannelAPI{Recorder: Recorder{shared: &a.Recorder}}
+	a.Message = &MessageAPI{Recorder: Recorder{shared: &a.Recorder}}
+	a.P2P = &P2PAPI{Recorder: Recorder{shared: &a.Recorder}}
+	a.Emoji = &EmojiAPI{Recorder: Recorder{shared: &a.Recorder}}
+	a.Sticker = &StickerAPI{Recorder: Recorder{shared: &a.Recorder}}
+	a.RTM = &RTMAPI{Recorder: Recorder{shared: &a.Recorder}}
+	a.MessagePin = &MessagePinAPI{Recorder: Recorder{shared: &a.Recorder}}
+	a.VChannel = &VChannelAPI{Recorder: Recorder{shared: &a.Recorder}}
+	a.Membership = &MembershipAPI{Recorder: Recorder{shared: &a.Recorder}}
+
+	return a
+}
+
+// MetaAPI returns Meta.
+func (a *API) MetaAPI() openapi.MetaAPI { return a.Meta }
+
+// TeamAPI returns Team.
+func (a *API) TeamAPI() openapi.TeamAPI { return a.Team }
+
+// UserAPI returns User.
+func (a *API) UserAPI() openapi.UserAPI { return a.User }
+
+// ChannelAPI returns Channel.
+func (a *API) ChannelAPI() openapi.ChannelAPI { return a.Channel }
+
+// SessionChannelAPI returns SessionChannel.
+func (a *API) SessionChannelAPI() openapi.SessionChannelAPI { return a.SessionChannel }
+
+// MessageAPI returns Message.
+func (a *API) MessageAPI() openapi.MessageAPI { return a.Message }
+
+// P2PAPI returns P2P.
+func (a *API) P2PAPI() openapi.P2PAPI { return a.P2P }
+
+// EmojiAPI returns Emoji.
+func (a *API) EmojiAPI() openapi.EmojiAPI { return a.Emoji }
+
+// StickerAPI returns Sticker.
+func (a *API) StickerAPI() openapi.StickerAPI { return a.Sticker }
+
+// RTMAPI returns RTM.
+func (a *API) RTMAPI() openapi.RTMAPI { return a.RTM }
+
+// MessagePinAPI returns MessagePin.
+func (a *API) MessagePinAPI() openapi.MessagePinAPI { return a.MessagePin }
+
+// VChannelAPI returns VChannel.
+func (a *API) VChannelAPI() openapi.VChannelAPI { return a.VChannel }
+
+// MembershipAPI returns Membership.
+func (a *API) MembershipAPI() openapi.MembershipAPI { return a.Membership }
+
+var _ openapi.API = (*API)(nil)
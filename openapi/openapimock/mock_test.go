@@ -0,0 +1,67 @@
+package openapimock
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/nanmu42/bearychat-go/openapi"
+)
+
+// greet is code under test, depending on openapi.API only.
+func greet(ctx context.Context, api openapi.API, userID string) error {
+	p2p, _, err := api.P2PAPI().Create(ctx, &openapi.P2PCreateOptions{UserID: userID})
+	if err != nil {
+		return err
+	}
+	_, _, err = api.MessageAPI().Create(ctx, &openapi.MessageCreateOptions{
+		VChannelID: *p2p.VChannelID,
+		Text:       "hello",
+	})
+	return err
+}
+
+func TestAPI(t *testing.T) {
+	ctx := context.Background()
+	api := New()
+
+	vchannelID := "=vc1"
+	api.P2P.CreateFunc = func(ctx context.Context, opt *openapi.P2PCreateOptions) (*openapi.P2P, *http.Response, error) {
+		return &openapi.P2P{VChannelID: &vchannelID}, nil, nil
+	}
+	if err := greet(ctx, api, "=u1"); !errors.Is(err, ErrNotMocked) {
+		t.Errorf("expected ErrNotMocked, got %+v", err)
+	}
+
+	api.Reset()
+	api.Message.Reset()
+	api.Message.CreateFunc = func(ctx context.Context, opt *openapi.MessageCreateOptions) (*openapi.Message, *http.Response, error) {
+		return &openapi.Message{VchannelID: &opt.VChannelID, Text: &opt.Text}, nil, nil
+	}
+	if err := greet(ctx, api, "=u1"); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	calls := api.Calls()
+	if len(calls) != 2 || calls[0].API != "P2PAPI" || calls[1].API != "MessageAPI" {
+		t.Fatalf("unexpected calls: %+v", calls)
+	}
+	created := api.Message.CallsOf("Create")
+	if len(created) != 1 {
+		t.Fatalf("unexpected calls: %+v", created)
+	}
+	if opt := created[0].Args[0].(*openapi.MessageCreateOptions); opt.VChannelID != vchannelID || opt.Text != "hello" {
+		t.Errorf("unexpected options: %+v", opt)
+	}
+}
+
+func TestMock_Standalone(t *testing.T) {
+	var channels openapi.ChannelAPI = &ChannelAPI{}
+	if _, _, err := channels.List(context.Background()); !errors.Is(err, ErrNotMocked) {
+		t.Errorf("expected ErrNotMocked, got %+v", err)
+	}
+	if calls := channels.(*ChannelAPI).Calls(); len(calls) != 1 || calls[0].Method != "List" {
+		t.Errorf("unexpected calls: %+v", calls)
+	}
+}
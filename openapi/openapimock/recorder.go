@@ -0,0 +1,89 @@
+// Package openapimock implements in-memory mocks of openapi service interfaces.
+//
+// Mocks call their Func fields, and record every call:
+//
+//      api := openapimock.New()
+//      api.Message.CreateFunc = func(ctx context.Context, opt *openapi.MessageCreateOptions) (*openapi.Message, *http.Response, error) {
+//              return &openapi.Message{Text: &opt.Text}, nil, nil
+//      }
+//
+//      bot := NewBot(api)
+//      bot.Greet(ctx)
+//
+//      calls := api.Message.CallsOf("Create")
+//
+// Methods without Func return zero values and an error wrapping ErrNotMocked.
+//
+// Mocks in mock.go are generated from openapi/api.go, run `go generate` in openapi
+// after changing interfaces.
+package openapimock
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrNotMocked is returned by methods without Func.
+var ErrNotMocked = errors.New("method not mocked")
+
+func notMocked(api, method string) error {
+	return fmt.Errorf("%s.%s: %w", api, method, ErrNotMocked)
+}
+
+// Call is a recorded method call.
+type Call struct {
+	// Interface name, e.g. `MessageAPI`
+	API    string
+	Method string
+	// Arguments, context excluded
+	Args []interface{}
+}
+
+// Recorder records calls, it's embedded in mocks.
+type Recorder struct {
+	lock  sync.Mutex // lock for properties below
+	calls []Call
+
+	// calls are also recorded to shared, if set
+	shared *Recorder
+}
+
+func (r *Recorder) record(api, method string, args ...interface{}) {
+	call := Call{API: api, Method: method, Args: args}
+
+	r.lock.Lock()
+	r.calls = append(r.calls, call)
+	r.lock.Unlock()
+
+	if r.shared != nil {
+		r.shared.record(api, method, args...)
+	}
+}
+
+// Calls returns recorded calls in order.
+func (r *Recorder) Calls() []Call {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return append([]Call{}, r.calls...)
+}
+
+// CallsOf returns recorded calls of method in order.
+func (r *Recorder) CallsOf(method string) []Call {
+	var calls []Call
+	for _, call := range r.Calls() {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// Reset clears recorded calls.
+func (r *Recorder) Reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.calls = nil
+}
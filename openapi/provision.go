@@ -0,0 +1,355 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WorkspaceSpec declares desired state of team channels.
+//
+// It's decoded from JSON by ParseWorkspaceSpec. YAML is not supported,
+// convert YAML specs to JSON first, e.g. with `yq -o json`.
+//
+//      {
+//              "channels": [
+//                      {
+//                              "name": "ops",
+//                              "topic": "on call",
+//                              "private": true,
+//                              "members": ["alice@example.com", "@bob"],
+//                              "pinned_message_keys": ["1485236262366.0193"]
+//                      },
+//                      {"name": "legacy", "archived": true}
+//              ]
+//      }
+type WorkspaceSpec struct {
+	Channels []ChannelSpec `json:"channels"`
+}
+
+// ChannelSpec declares desired state of a channel.
+type ChannelSpec struct {
+	Name string `json:"name"`
+	// Topic and privacy are applied on creation only,
+	// drifts of existing channels are reported as plan warnings.
+	Topic   *string `json:"topic,omitempty"`
+	Private *bool   `json:"private,omitempty"`
+	// Members as user ids, emails or names, membership is left as is if nil.
+	// The token owner is never kicked.
+	Members []string `json:"members,omitempty"`
+	// Pinned message keys, pins are left as is if nil.
+	PinnedMessageKeys []MessageKey `json:"pinned_message_keys,omitempty"`
+	Archived          bool         `json:"archived,omitempty"`
+}
+
+// ParseWorkspaceSpec decodes and validates a JSON spec.
+func ParseWorkspaceSpec(r io.Reader) (*WorkspaceSpec, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	var spec WorkspaceSpec
+	if err := decoder.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("decode workspace spec failed: %s", err)
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// Validate checks channel names are present and unique.
+func (s *WorkspaceSpec) Validate() error {
+	seen := map[string]bool{}
+	for i, c := range s.Channels {
+		name := strings.TrimPrefix(c.Name, "#")
+		if name == "" {
+			return fmt.Errorf("channels[%d]: name is required", i)
+		}
+		if seen[name] {
+			return fmt.Errorf("channels[%d]: duplicated channel %s", i, name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// ProvisionActionType defines changes a ProvisionPlan makes.
+type ProvisionActionType string
+
+const (
+	ProvisionActionCreateChannel    ProvisionActionType = "create_channel"
+	ProvisionActionUnarchiveChannel ProvisionActionType = "unarchive_channel"
+	ProvisionActionInvite           ProvisionActionType = "invite"
+	ProvisionActionKick             ProvisionActionType = "kick"
+	ProvisionActionPin              ProvisionActionType = "pin"
+	ProvisionActionUnpin            ProvisionActionType = "unpin"
+	ProvisionActionArchiveChannel   ProvisionActionType = "archive_channel"
+)
+
+// ProvisionAction is a single change of a ProvisionPlan.
+type ProvisionAction struct {
+	Type    ProvisionActionType
+	Channel string
+	// ChannelID and VChannelID are empty for channels to be created
+	ChannelID  string
+	VChannelID string
+	// For invite and kick
+	UserID string
+	// For pin and unpin
+	MessageKey MessageKey
+	PinID      string
+	// For create_channel
+	Topic   *string
+	Private *bool
+}
+
+func (a ProvisionAction) String() string {
+	switch a.Type {
+	case ProvisionActionInvite, ProvisionActionKick:
+		return fmt.Sprintf("%s %s #%s", a.Type, a.UserID, a.Channel)
+	case ProvisionActionPin, ProvisionActionUnpin:
+		return fmt.Sprintf("%s %s #%s", a.Type, a.MessageKey, a.Channel)
+	default:
+		return fmt.Sprintf("%s #%s", a.Type, a.Channel)
+	}
+}
+
+// ProvisionPlan lists changes to bring the team to a WorkspaceSpec.
+// Actions are ordered to be applied as is.
+type ProvisionPlan struct {
+	Actions []ProvisionAction
+	// Drifts which can't be changed by API, e.g. topic of existing channels
+	Warnings []string
+}
+
+// Empty tells if nothing is to be changed.
+func (p *ProvisionPlan) Empty() bool {
+	return len(p.Actions) == 0
+}
+
+func (p *ProvisionPlan) String() string {
+	lines := make([]string, 0, len(p.Actions)+len(p.Warnings))
+	for _, a := range p.Actions {
+		lines = append(lines, a.String())
+	}
+	for _, w := range p.Warnings {
+		lines = append(lines, "warning: "+w)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Provisioner reconciles team channels with a WorkspaceSpec:
+// Plan compares the spec with current state, Apply makes the changes.
+// Applying a spec twice makes no change the second time.
+//
+//      p := openapi.NewProvisioner(client)
+//      plan, err := p.Plan(ctx, spec)
+//      fmt.Println(plan)
+//      err = p.Apply(ctx, plan)
+type Provisioner struct {
+	client *Client
+}
+
+// NewProvisioner creates a provisioner.
+func NewProvisioner(client *Client) *Provisioner {
+	return &Provisioner{client: client}
+}
+
+// Plan computes changes to bring the team to spec.
+func (p *Provisioner) Plan(ctx context.Context, spec *WorkspaceSpec) (*ProvisionPlan, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	channels, _, err := p.client.Channel.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	channelsByName := make(map[string]*Channel, len(channels))
+	for _, c := range channels {
+		channelsByName[strv(c.Name)] = c
+	}
+
+	me, _, err := p.client.User.Me(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resolver := NewResolver(p.client)
+
+	plan := &ProvisionPlan{}
+	var archives []ProvisionAction
+	for _, s := range spec.Channels {
+		name := strings.TrimPrefix(s.Name, "#")
+		current, present := channelsByName[name]
+
+		if s.Archived {
+			if present && !isArchived(current) {
+				archives = append(archives, ProvisionAction{
+					Type:       ProvisionActionArchiveChannel,
+					Channel:    name,
+					ChannelID:  strv(current.ID),
+					VChannelID: strv(current.VChannelID),
+				})
+			}
+			if !present {
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("#%s: archived channel does not exist", name))
+			}
+			continue
+		}
+
+		base := ProvisionAction{Channel: name}
+		var memberUserIDs []string
+		if present {
+			base.ChannelID = strv(current.ID)
+			base.VChannelID = strv(current.VChannelID)
+			memberUserIDs = current.MemberUserIDs
+
+			if isArchived(current) {
+				plan.add(base, ProvisionActionUnarchiveChannel)
+			}
+			if s.Topic != nil && *s.Topic != strv(current.Topic) {
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("#%s: topic %q differs from %q", name, strv(current.Topic), *s.Topic))
+			}
+			if s.Private != nil && *s.Private != boolv(current.Private) {
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("#%s: private %t differs from %t", name, boolv(current.Private), *s.Private))
+			}
+		} else {
+			create := base
+			create.Type = ProvisionActionCreateChannel
+			create.Topic = s.Topic
+			create.Private = s.Private
+			plan.Actions = append(plan.Actions, create)
+			memberUserIDs = []string{strv(me.ID)}
+		}
+
+		if s.Members != nil {
+			desired, err := resolveMembers(ctx, resolver, s.Members)
+			if err != nil {
+				return nil, fmt.Errorf("#%s: %s", name, err)
+			}
+			desired[strv(me.ID)] = true
+			invites, kicks, _ := diffMembers(memberUserIDs, desired, nil)
+			for _, id := range invites {
+				action := base
+				action.UserID = id
+				plan.add(action, ProvisionActionInvite)
+			}
+			for _, id := range kicks {
+				action := base
+				action.UserID = id
+				plan.add(action, ProvisionActionKick)
+			}
+		}
+
+		if s.PinnedMessageKeys != nil {
+			var pins []*MessagePin
+			if present {
+				pins, _, err = p.client.MessagePin.List(ctx, &MessagePinListOptions{VChannelID: base.VChannelID})
+				if err != nil {
+					return nil, err
+				}
+			}
+			pinned := map[MessageKey]bool{}
+			for _, pin := range pins {
+				if pin.MessageKey != nil {
+					pinned[*pin.MessageKey] = true
+				}
+			}
+			desired := map[MessageKey]bool{}
+			for _, key := range s.PinnedMessageKeys {
+				desired[key] = true
+				if !pinned[key] {
+					action := base
+					action.MessageKey = key
+					plan.add(action, ProvisionActionPin)
+				}
+			}
+			for _, pin := range pins {
+				if pin.MessageKey == nil || desired[*pin.MessageKey] {
+					continue
+				}
+				action := base
+				action.MessageKey = *pin.MessageKey
+				action.PinID = strv(pin.ID)
+				plan.add(action, ProvisionActionUnpin)
+			}
+		}
+	}
+	// archive last, archived channels can't be changed
+	plan.Actions = append(plan.Actions, archives...)
+
+	return plan, nil
+}
+
+func (p *ProvisionPlan) add(action ProvisionAction, t ProvisionActionType) {
+	action.Type = t
+	p.Actions = append(p.Actions, action)
+}
+
+// Apply makes changes of plan in order, stopping at the first failure.
+// Rerun Plan and Apply to resume after a failure.
+func (p *Provisioner) Apply(ctx context.Context, plan *ProvisionPlan) error {
+	// channels created during apply, by name
+	created := map[string]*Channel{}
+
+	for _, action := range plan.Actions {
+		if action.ChannelID == "" {
+			if c, present := created[action.Channel]; present {
+				action.ChannelID = strv(c.ID)
+				action.VChannelID = strv(c.VChannelID)
+			}
+		}
+
+		var err error
+		switch action.Type {
+		case ProvisionActionCreateChannel:
+			var channel *Channel
+			channel, _, err = p.client.Channel.Create(ctx, &ChannelCreateOptions{
+				Name:    action.Channel,
+				Topic:   action.Topic,
+				Private: action.Private,
+			})
+			if err == nil {
+				created[action.Channel] = channel
+			}
+		case ProvisionActionUnarchiveChannel:
+			_, _, err = p.client.Channel.Unarchive(ctx, &ChannelUnarchiveOptions{ChannelID: action.ChannelID})
+		case ProvisionActionArchiveChannel:
+			_, _, err = p.client.Channel.Archive(ctx, &ChannelArchiveOptions{ChannelID: action.ChannelID})
+		case ProvisionActionInvite:
+			_, _, err = p.client.Channel.Invite(ctx, &ChannelInviteOptions{
+				ChannelID:    action.ChannelID,
+				InviteUserID: action.UserID,
+			})
+		case ProvisionActionKick:
+			_, _, err = p.client.Channel.Kick(ctx, &ChannelKickOptions{
+				ChannelID:  action.ChannelID,
+				KickUserID: action.UserID,
+			})
+		case ProvisionActionPin:
+			_, _, err = p.client.MessagePin.Create(ctx, &MessagePinCreateOptions{
+				VChannelID: action.VChannelID,
+				MessageKey: action.MessageKey,
+			})
+		case ProvisionActionUnpin:
+			_, _, err = p.client.MessagePin.Delete(ctx, &MessagePinDeleteOptions{
+				VChannelID: action.VChannelID,
+				PinID:      action.PinID,
+			})
+		default:
+			err = fmt.Errorf("unknown action type")
+		}
+		if err != nil {
+			return fmt.Errorf("%s failed: %s", action, err)
+		}
+	}
+
+	return nil
+}
+
+// isArchived tells if channel is archived, which is listed as inactive.
+func isArchived(c *Channel) bool {
+	return c.IsActive != nil && !*c.IsActive
+}
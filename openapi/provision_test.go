@@ -0,0 +1,200 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// provisionTestServer keeps channels and pins in memory.
+type provisionTestServer struct {
+	lock     sync.Mutex
+	channels []*Channel
+	pins     map[string][]*MessagePin
+	nextID   int
+}
+
+func (s *provisionTestServer) channel(id string) *Channel {
+	for _, c := range s.channels {
+		if *c.ID == id {
+			return c
+		}
+	}
+	return &Channel{}
+}
+
+func (s *provisionTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var body map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&body)
+	str := func(key string) string {
+		v, _ := body[key].(string)
+		return v
+	}
+	s.nextID = s.nextID + 1
+	newID := fmt.Sprintf("=%d", s.nextID)
+
+	var result interface{}
+	switch r.URL.Path {
+	case "/user.me":
+		result = &User{ID: strp("=me")}
+	case "/user.list":
+		result = []*User{
+			{ID: strp("=me"), Name: strp("robot")},
+			{ID: strp("=u1"), Name: strp("alice"), Email: strp("alice@example.com")},
+			{ID: strp("=u2"), Name: strp("bob")},
+		}
+	case "/channel.list":
+		result = s.channels
+	case "/channel.create":
+		c := &Channel{
+			ID:            strp(newID),
+			VChannelID:    strp("=vc" + newID),
+			Name:          strp(str("name")),
+			IsActive:      boolp(true),
+			MemberUserIDs: []string{"=me"},
+		}
+		if topic, ok := body["topic"].(string); ok {
+			c.Topic = strp(topic)
+		}
+		s.channels = append(s.channels, c)
+		result = c
+	case "/channel.archive":
+		c := s.channel(str("channel_id"))
+		c.IsActive = boolp(false)
+		result = c
+	case "/channel.unarchive":
+		c := s.channel(str("channel_id"))
+		c.IsActive = boolp(true)
+		result = c
+	case "/channel.invite":
+		c := s.channel(str("channel_id"))
+		c.MemberUserIDs = append(c.MemberUserIDs, str("invite_uid"))
+	case "/channel.kick":
+		c := s.channel(str("channel_id"))
+		var members []string
+		for _, id := range c.MemberUserIDs {
+			if id != str("kick_uid") {
+				members = append(members, id)
+			}
+		}
+		c.MemberUserIDs = members
+	case "/message_pin.list":
+		result = s.pins[r.URL.Query().Get("vchannel_id")]
+	case "/message_pin.create":
+		key := MessageKey(str("message_key"))
+		vchannelID := str("vchannel_id")
+		pin := &MessagePin{ID: strp(newID), MessageKey: &key}
+		s.pins[vchannelID] = append(s.pins[vchannelID], pin)
+		result = pin
+	case "/message_pin.delete":
+		vchannelID := str("vchannel_id")
+		var pins []*MessagePin
+		for _, pin := range s.pins[vchannelID] {
+			if *pin.ID != str("pin_id") {
+				pins = append(pins, pin)
+			}
+		}
+		s.pins[vchannelID] = pins
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if result == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+func TestProvisioner(t *testing.T) {
+	s := &provisionTestServer{
+		channels: []*Channel{
+			{ID: strp("=c1"), VChannelID: strp("=vc1"), Name: strp("ops"), Topic: strp("old"), IsActive: boolp(true), MemberUserIDs: []string{"=me", "=u2"}},
+			{ID: strp("=c2"), VChannelID: strp("=vc2"), Name: strp("legacy"), IsActive: boolp(true)},
+			{ID: strp("=c3"), VChannelID: strp("=vc3"), Name: strp("revived"), IsActive: boolp(false)},
+		},
+		pins: map[string][]*MessagePin{
+			"=vc1": {{ID: strp("=pin1"), MessageKey: messageKey("old.key")}},
+		},
+	}
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	baseURL, _ := url.Parse(server.URL + "/")
+	p := NewProvisioner(NewClient("foobar", NewClientWithBaseURL(baseURL)))
+	ctx := context.Background()
+
+	spec, err := ParseWorkspaceSpec(strings.NewReader(`{"channels":[
+		{"name":"ops","topic":"on call","members":["alice@example.com"],"pinned_message_keys":["new.key"]},
+		{"name":"#new","topic":"hello","members":["alice","@bob"]},
+		{"name":"legacy","archived":true},
+		{"name":"revived"}
+	]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	plan, err := p.Plan(ctx, spec)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	expected := strings.Join([]string{
+		"invite =u1 #ops",
+		"kick =u2 #ops",
+		"pin new.key #ops",
+		"unpin old.key #ops",
+		"create_channel #new",
+		"invite =u1 #new",
+		"invite =u2 #new",
+		"unarchive_channel #revived",
+		"archive_channel #legacy",
+		`warning: #ops: topic "old" differs from "on call"`,
+	}, "\n")
+	if plan.String() != expected {
+		t.Errorf("expected plan:\n%s\ngot:\n%s", expected, plan)
+	}
+
+	if err := p.Apply(ctx, plan); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	plan, err = p.Plan(ctx, spec)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if !plan.Empty() {
+		t.Errorf("rerun should plan nothing, got:\n%s", plan)
+	}
+}
+
+func TestParseWorkspaceSpec(t *testing.T) {
+	cases := []string{
+		`{"channels":[{"name":""}]}`,
+		`{"channels":[{"name":"ops"},{"name":"#ops"}]}`,
+		`{"channels":[{"name":"ops","unknown":true}]}`,
+	}
+	for _, c := range cases {
+		if _, err := ParseWorkspaceSpec(strings.NewReader(c)); err == nil {
+			t.Errorf("expected error for %s", c)
+		}
+	}
+}
+
+func messageKey(key string) *MessageKey {
+	k := MessageKey(key)
+	return &k
+}
+
+func strp(s string) *string { return &s }
+
+func boolp(b bool) *bool { return &b }
@@ -0,0 +1,305 @@
+package openapi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ResolveErrorKind tells why a reference can't be resolved.
+type ResolveErrorKind string
+
+const (
+	ResolveErrorNotFound  ResolveErrorKind = "not found"
+	ResolveErrorAmbiguous ResolveErrorKind = "ambiguous"
+)
+
+// ResolveError represents a reference resolved to none or many entities.
+type ResolveError struct {
+	Kind ResolveErrorKind
+	// Reference to resolve, e.g. `#ops`, `@alice`
+	Ref string
+	// Candidates ids when ambiguous
+	Candidates []string
+}
+
+func (e *ResolveError) Error() string {
+	if len(e.Candidates) > 0 {
+		return fmt.Sprintf("%s: %s (candidates: %s)", e.Ref, e.Kind, strings.Join(e.Candidates, ", "))
+	}
+	return fmt.Sprintf("%s: %s", e.Ref, e.Kind)
+}
+
+// IsNotFound tells if err is a not found ResolveError.
+func IsNotFound(err error) bool {
+	e, ok := err.(*ResolveError)
+	return ok && e.Kind == ResolveErrorNotFound
+}
+
+// IsAmbiguous tells if err is an ambiguous ResolveError.
+func IsAmbiguous(err error) bool {
+	e, ok := err.(*ResolveError)
+	return ok && e.Kind == ResolveErrorAmbiguous
+}
+
+// IsID tells if ref is an id like `=bw52O` syntactically, rather than a name.
+func IsID(ref string) bool {
+	if len(ref) < 2 || ref[0] != '=' {
+		return false
+	}
+	for _, r := range ref[1:] {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// Resolver maps names used in configs to API ids:
+//
+//      #ops              -> channel
+//      @alice            -> user
+//      alice@example.com -> user
+//      =bw52O            -> user or channel id as is
+//
+// Users and channels lists are cached on first use, call Reset to reload.
+//
+//      r := openapi.NewResolver(client)
+//      vchannelID, err := r.VChannelID(ctx, "@alice")
+type Resolver struct {
+	client *Client
+
+	lock     sync.Mutex // lock for properties below
+	users    []*User
+	channels []*Channel
+	p2ps     map[string]*P2P
+}
+
+// NewResolver creates a resolver.
+func NewResolver(client *Client) *Resolver {
+	return &Resolver{
+		client: client,
+		p2ps:   map[string]*P2P{},
+	}
+}
+
+// Reset drops cached lists.
+func (r *Resolver) Reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.users = nil
+	r.channels = nil
+	r.p2ps = map[string]*P2P{}
+}
+
+// User resolves `@name`, email or user id to user.
+func (r *Resolver) User(ctx context.Context, ref string) (*User, error) {
+	users, err := r.loadUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ref = strings.TrimSpace(ref)
+	var match func(u *User) (exact, fold bool)
+	switch {
+	case strings.HasPrefix(ref, "@"):
+		name := ref[1:]
+		match = func(u *User) (bool, bool) {
+			return strv(u.Name) == name, strings.EqualFold(strv(u.Name), name)
+		}
+	case strings.Contains(ref, "@"):
+		match = func(u *User) (bool, bool) {
+			return false, strings.EqualFold(strv(u.Email), ref)
+		}
+	default:
+		match = func(u *User) (bool, bool) {
+			return strv(u.ID) == ref, false
+		}
+	}
+
+	var exact, fold []*User
+	for _, u := range users {
+		e, f := match(u)
+		if e {
+			exact = append(exact, u)
+		} else if f {
+			fold = append(fold, u)
+		}
+	}
+	if len(exact) == 1 {
+		return exact[0], nil
+	}
+	candidates := append(exact, fold...)
+	switch len(candidates) {
+	case 0:
+		return nil, &ResolveError{Kind: ResolveErrorNotFound, Ref: ref}
+	case 1:
+		return candidates[0], nil
+	}
+
+	ids := make([]string, 0, len(candidates))
+	for _, u := range candidates {
+		ids = append(ids, strv(u.ID))
+	}
+	return nil, &ResolveError{Kind: ResolveErrorAmbiguous, Ref: ref, Candidates: ids}
+}
+
+// Channel resolves `#name` or channel id to channel.
+func (r *Resolver) Channel(ctx context.Context, ref string) (*Channel, error) {
+	channels, err := r.loadChannels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ref = strings.TrimSpace(ref)
+	var exact, fold []*Channel
+	if strings.HasPrefix(ref, "#") {
+		name := ref[1:]
+		for _, c := range channels {
+			if strv(c.Name) == name {
+				exact = append(exact, c)
+			} else if strings.EqualFold(strv(c.Name), name) {
+				fold = append(fold, c)
+			}
+		}
+	} else {
+		for _, c := range channels {
+			if strv(c.ID) == ref || strv(c.VChannelID) == ref {
+				exact = append(exact, c)
+			}
+		}
+	}
+
+	if len(exact) == 1 {
+		return exact[0], nil
+	}
+	candidates := append(exact, fold...)
+	switch len(candidates) {
+	case 0:
+		return nil, &ResolveError{Kind: ResolveErrorNotFound, Ref: ref}
+	case 1:
+		return candidates[0], nil
+	}
+
+	ids := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		ids = append(ids, strv(c.ID))
+	}
+	return nil, &ResolveError{Kind: ResolveErrorAmbiguous, Ref: ref, Candidates: ids}
+}
+
+// P2P resolves user reference to the p2p channel with user,
+// creating it via `p2p.create` when needed.
+func (r *Resolver) P2P(ctx context.Context, userRef string) (*P2P, error) {
+	user, err := r.User(ctx, userRef)
+	if err != nil {
+		return nil, err
+	}
+	userID := strv(user.ID)
+
+	r.lock.Lock()
+	p2p, present := r.p2ps[userID]
+	r.lock.Unlock()
+	if present {
+		return p2p, nil
+	}
+
+	p2p, _, err = r.client.P2P.Create(ctx, &P2PCreateOptions{UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+
+	r.lock.Lock()
+	r.p2ps[userID] = p2p
+	r.lock.Unlock()
+
+	return p2p, nil
+}
+
+// VChannelID resolves `#channel`, `@user`, email or id to vchannel id
+// messages can be sent to. Unknown ids are returned as is, as they
+// may refer to vchannels like session channels, other unknown refs
+// are not found.
+func (r *Resolver) VChannelID(ctx context.Context, ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+
+	if strings.HasPrefix(ref, "#") {
+		channel, err := r.Channel(ctx, ref)
+		if err != nil {
+			return "", err
+		}
+		return strv(channel.VChannelID), nil
+	}
+	if strings.Contains(ref, "@") {
+		p2p, err := r.P2P(ctx, ref)
+		if err != nil {
+			return "", err
+		}
+		return strv(p2p.VChannelID), nil
+	}
+
+	if channel, err := r.Channel(ctx, ref); err == nil {
+		return strv(channel.VChannelID), nil
+	} else if !IsNotFound(err) {
+		return "", err
+	}
+	if _, err := r.User(ctx, ref); err == nil {
+		p2p, err := r.P2P(ctx, ref)
+		if err != nil {
+			return "", err
+		}
+		return strv(p2p.VChannelID), nil
+	} else if !IsNotFound(err) {
+		return "", err
+	}
+
+	if !IsID(ref) {
+		return "", &ResolveError{Kind: ResolveErrorNotFound, Ref: ref}
+	}
+	return ref, nil
+}
+
+func (r *Resolver) loadUsers(ctx context.Context) ([]*User, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.users != nil {
+		return r.users, nil
+	}
+	users, _, err := r.client.User.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if users == nil {
+		users = []*User{}
+	}
+	r.users = users
+	return users, nil
+}
+
+func (r *Resolver) loadChannels(ctx context.Context) ([]*Channel, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.channels != nil {
+		return r.channels, nil
+	}
+	channels, _, err := r.client.Channel.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if channels == nil {
+		channels = []*Channel{}
+	}
+	r.channels = channels
+	return channels, nil
+}
+
+func strv(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
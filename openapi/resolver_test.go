@@ -0,0 +1,71 @@
+package openapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestResolver(t *testing.T) {
+	p2pCreated := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/user.list":
+			w.Write([]byte(`[
+				{"id":"=bw52O","name":"alice","email":"alice@example.com"},
+				{"id":"=bw52P","name":"Ann","email":"ann@example.com"},
+				{"id":"=bw52Q","name":"ANN","email":"ann2@example.com"}
+			]`))
+		case "/channel.list":
+			w.Write([]byte(`[{"id":"=ch1","name":"ops","vchannel_id":"=vc1"}]`))
+		case "/p2p.create":
+			p2pCreated = p2pCreated + 1
+			w.Write([]byte(`{"id":"=p2p","vchannel_id":"=vc2"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	baseURL, _ := url.Parse(server.URL + "/")
+	r := NewResolver(NewClient("foobar", NewClientWithBaseURL(baseURL)))
+	ctx := context.Background()
+
+	if u, err := r.User(ctx, "@ALICE"); err != nil || *u.ID != "=bw52O" {
+		t.Errorf("unexpected user: %+v %+v", u, err)
+	}
+	if u, err := r.User(ctx, "@ANN"); err != nil || *u.ID != "=bw52Q" {
+		t.Errorf("exact match should win: %+v %+v", u, err)
+	}
+	if _, err := r.User(ctx, "@ann"); !IsAmbiguous(err) {
+		t.Errorf("expected ambiguous: %+v", err)
+	}
+	if _, err := r.User(ctx, "@bob"); !IsNotFound(err) {
+		t.Errorf("expected not found: %+v", err)
+	}
+	if c, err := r.Channel(ctx, "#ops"); err != nil || *c.ID != "=ch1" {
+		t.Errorf("unexpected channel: %+v %+v", c, err)
+	}
+
+	for ref, expected := range map[string]string{
+		"#ops":              "=vc1",
+		"@alice":            "=vc2",
+		"alice@example.com": "=vc2",
+		"=bw52O":            "=vc2",
+		"=session":          "=session",
+	} {
+		if vchannelID, err := r.VChannelID(ctx, ref); err != nil || vchannelID != expected {
+			t.Errorf("expected %s for %s, got %s %+v", expected, ref, vchannelID, err)
+		}
+	}
+	for _, ref := range []string{"ops", "alice", "=bad id", ""} {
+		if _, err := r.VChannelID(ctx, ref); !IsNotFound(err) {
+			t.Errorf("expected not found for %q: %+v", ref, err)
+		}
+	}
+	if p2pCreated != 1 {
+		t.Errorf("p2p should be cached: created %d times", p2pCreated)
+	}
+}
@@ -0,0 +1,84 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	timeLayout      = "2006-01-02T15:04:05-0700"
+	timeLayoutMilli = "2006-01-02T15:04:05.000-0700"
+)
+
+// timeLayouts are tried in order when decoding Time.
+// Fractional seconds are accepted by all of them, e.g.
+// `2018-10-28T14:16:52.000+0000` as sent by RTM.
+var timeLayouts = []string{
+	timeLayout,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+}
+
+// Time with custom JSON format.
+//
+// It decodes `null`, empty string, layouts like `2006-01-02T15:04:05-0700`
+// and RFC 3339, and encodes zero time as `null`.
+type Time struct {
+	time.Time
+}
+
+func (t *Time) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if bytes.Equal(b, []byte("null")) {
+		t.Time = time.Time{}
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("time should be a string, got %s", b)
+	}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		t.Time = time.Time{}
+		return nil
+	}
+
+	for _, layout := range timeLayouts {
+		parsed, err := time.Parse(layout, s)
+		if err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot parse time %q", s)
+}
+
+func (t Time) MarshalJSON() ([]byte, error) {
+	if t.Time.IsZero() {
+		return []byte("null"), nil
+	}
+
+	layout := timeLayout
+	if t.Time.Nanosecond() != 0 {
+		layout = timeLayoutMilli
+	}
+	return []byte(fmt.Sprintf("\"%s\"", t.Time.Format(layout))), nil
+}
+
+// VChannelTS represents unix timestamp type in milliseconds.
+type VChannelTS int64
+
+// NewVChannelTS converts t to VChannelTS.
+func NewVChannelTS(t time.Time) VChannelTS {
+	return VChannelTS(t.UnixNano() / int64(time.Millisecond))
+}
+
+// Time converts ts to time.Time.
+func (ts VChannelTS) Time() time.Time {
+	return time.Unix(0, int64(ts)*int64(time.Millisecond))
+}
@@ -0,0 +1,84 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTime_UnmarshalJSON(t *testing.T) {
+	expected := time.Date(2018, 10, 28, 14, 16, 52, 0, time.UTC)
+
+	cases := []string{
+		`"2018-10-28T14:16:52+0000"`,
+		`"2018-10-28T14:16:52.000+0000"`,
+		`"2018-10-28T22:16:52+0800"`,
+		`"2018-10-28T14:16:52Z"`,
+		`"2018-10-28T22:16:52+08:00"`,
+		`"2018-10-28T14:16:52"`,
+	}
+	for _, c := range cases {
+		var v struct {
+			Created Time  `json:"created"`
+			Updated *Time `json:"updated"`
+		}
+		if err := json.Unmarshal([]byte(`{"created":`+c+`,"updated":`+c+`}`), &v); err != nil {
+			t.Errorf("unmarshal %s failed: %+v", c, err)
+			continue
+		}
+		if !v.Created.Equal(expected) {
+			t.Errorf("expected %s for %s, got %s", expected, c, v.Created)
+		}
+		if v.Updated == nil || !v.Updated.Equal(expected) {
+			t.Errorf("expected %s for %s, got %+v", expected, c, v.Updated)
+		}
+	}
+
+	for _, c := range []string{`null`, `""`} {
+		var v struct {
+			Created Time `json:"created"`
+		}
+		if err := json.Unmarshal([]byte(`{"created":`+c+`}`), &v); err != nil || !v.Created.IsZero() {
+			t.Errorf("expected zero time for %s: %s %+v", c, v.Created, err)
+		}
+	}
+
+	var v Time
+	if err := json.Unmarshal([]byte(`"yesterday"`), &v); err == nil {
+		t.Errorf("expected error for invalid time")
+	}
+}
+
+func TestTime_RoundTrip(t *testing.T) {
+	cases := []string{
+		`{"created":"2018-10-28T14:16:52+0000"}`,
+		`{"created":"2018-10-28T14:16:52.123+0800"}`,
+		`{"created":null}`,
+	}
+	for _, c := range cases {
+		var v struct {
+			Created Time `json:"created"`
+		}
+		if err := json.Unmarshal([]byte(c), &v); err != nil {
+			t.Fatalf("unmarshal %s failed: %+v", c, err)
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("marshal %s failed: %+v", c, err)
+		}
+		if string(b) != c {
+			t.Errorf("expected %s, got %s", c, b)
+		}
+	}
+}
+
+func TestVChannelTS_Time(t *testing.T) {
+	ts := VChannelTS(1540736786063)
+	expected := time.Date(2018, 10, 28, 14, 26, 26, 63*int(time.Millisecond), time.UTC)
+	if !ts.Time().Equal(expected) {
+		t.Errorf("expected %s, got %s", expected, ts.Time())
+	}
+	if NewVChannelTS(expected) != ts {
+		t.Errorf("expected %d, got %d", ts, NewVChannelTS(expected))
+	}
+}
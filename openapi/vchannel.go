@@ -1,7 +1,237 @@
 package openapi
 
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
 // VChannelType defines chat channel/inbox types.
 type VChannelType string
 
-// VChannelTS represents unix timestamp type.
-type VChannelTS int64
+const (
+	VChannelTypeChannel        VChannelType = "channel"
+	VChannelTypeSessionChannel VChannelType = "session_channel"
+	VChannelTypeP2P            VChannelType = "p2p"
+)
+
+// VChannel is what Channel, SessionChannel and P2P have in common:
+// a vchannel messages can be sent to and queried from.
+type VChannel interface {
+	GetID() string
+	GetVChannelID() string
+	GetType() VChannelType
+	GetIsMember() bool
+	GetMemberUserIDs() []string
+	GetLatestTS() VChannelTS
+}
+
+var (
+	_ VChannel = (*Channel)(nil)
+	_ VChannel = (*SessionChannel)(nil)
+	_ VChannel = (*P2P)(nil)
+)
+
+// GetID returns ID or empty string.
+func (c *Channel) GetID() string { return strv(c.ID) }
+
+// GetVChannelID returns VChannelID or empty string.
+func (c *Channel) GetVChannelID() string { return strv(c.VChannelID) }
+
+// GetType returns Type, defaults to VChannelTypeChannel.
+func (c *Channel) GetType() VChannelType {
+	if c.Type == nil {
+		return VChannelTypeChannel
+	}
+	return *c.Type
+}
+
+// GetIsMember returns IsMember or false.
+func (c *Channel) GetIsMember() bool { return boolv(c.IsMember) }
+
+// GetMemberUserIDs returns MemberUserIDs.
+func (c *Channel) GetMemberUserIDs() []string { return c.MemberUserIDs }
+
+// GetLatestTS returns LatestTS or 0.
+func (c *Channel) GetLatestTS() VChannelTS { return tsv(c.LatestTS) }
+
+// GetID returns ID or empty string.
+func (s *SessionChannel) GetID() string { return strv(s.ID) }
+
+// GetVChannelID returns VChannelID or empty string.
+func (s *SessionChannel) GetVChannelID() string { return strv(s.VChannelID) }
+
+// GetType returns Type, defaults to VChannelTypeSessionChannel.
+func (s *SessionChannel) GetType() VChannelType {
+	if s.Type == nil {
+		return VChannelTypeSessionChannel
+	}
+	return *s.Type
+}
+
+// GetIsMember returns IsMember or false.
+func (s *SessionChannel) GetIsMember() bool { return boolv(s.IsMember) }
+
+// GetMemberUserIDs returns MemberUserIDs.
+func (s *SessionChannel) GetMemberUserIDs() []string { return s.MemberUserIDs }
+
+// GetLatestTS returns LatestTS or 0.
+func (s *SessionChannel) GetLatestTS() VChannelTS { return tsv(s.LatestTS) }
+
+// GetID returns ID or empty string.
+func (p *P2P) GetID() string { return strv(p.ID) }
+
+// GetVChannelID returns VChannelID or empty string.
+func (p *P2P) GetVChannelID() string { return strv(p.VChannelID) }
+
+// GetType returns Type, defaults to VChannelTypeP2P.
+func (p *P2P) GetType() VChannelType {
+	if p.Type == nil {
+		return VChannelTypeP2P
+	}
+	return *p.Type
+}
+
+// GetIsMember returns IsMember or false.
+func (p *P2P) GetIsMember() bool { return boolv(p.IsMember) }
+
+// GetMemberUserIDs returns MemberUserIDs.
+func (p *P2P) GetMemberUserIDs() []string { return p.MemberUserIDs }
+
+// GetLatestTS returns LatestTS or 0.
+func (p *P2P) GetLatestTS() VChannelTS { return tsv(p.LatestTS) }
+
+// VChannelService works on vchannels regardless of their kind,
+// on top of Channel, SessionChannel, P2P and Message services.
+type VChannelService service
+
+type VChannelListOptions struct {
+	// Only list vchannels of these types, all types if empty
+	Types []VChannelType
+}
+
+// List lists channels, session channels and p2p channels.
+func (v *VChannelService) List(ctx context.Context, opt *VChannelListOptions) ([]VChannel, *http.Response, error) {
+	want := func(t VChannelType) bool {
+		if opt == nil || len(opt.Types) == 0 {
+			return true
+		}
+		for _, wanted := range opt.Types {
+			if wanted == t {
+				return true
+			}
+		}
+		return false
+	}
+
+	var vchannels []VChannel
+	if want(VChannelTypeChannel) {
+		channels, resp, err := v.client.Channel.List(ctx)
+		if err != nil {
+			return nil, resp, err
+		}
+		for _, c := range channels {
+			vchannels = append(vchannels, c)
+		}
+	}
+	if want(VChannelTypeSessionChannel) {
+		channels, resp, err := v.client.SessionChannel.List(ctx)
+		if err != nil {
+			return nil, resp, err
+		}
+		for _, c := range channels {
+			vchannels = append(vchannels, c)
+		}
+	}
+	if want(VChannelTypeP2P) {
+		p2ps, resp, err := v.client.P2P.List(ctx)
+		if err != nil {
+			return nil, resp, err
+		}
+		for _, p := range p2ps {
+			vchannels = append(vchannels, p)
+		}
+	}
+
+	return vchannels, nil, nil
+}
+
+type VChannelInfoOptions struct {
+	VChannelID string
+	// Type narrows lookup when known
+	Type VChannelType
+}
+
+// Info finds a vchannel by vchannel id.
+func (v *VChannelService) Info(ctx context.Context, opt *VChannelInfoOptions) (VChannel, *http.Response, error) {
+	listOpt := &VChannelListOptions{}
+	if opt.Type != "" {
+		listOpt.Types = []VChannelType{opt.Type}
+	}
+
+	vchannels, resp, err := v.List(ctx, listOpt)
+	if err != nil {
+		return nil, resp, err
+	}
+	for _, vchannel := range vchannels {
+		if vchannel.GetVChannelID() == opt.VChannelID {
+			return vchannel, nil, nil
+		}
+	}
+
+	return nil, nil, &ResolveError{Kind: ResolveErrorNotFound, Ref: opt.VChannelID}
+}
+
+// ClassifyVChannels groups vchannels by type.
+func ClassifyVChannels(vchannels []VChannel) map[VChannelType][]VChannel {
+	classified := map[VChannelType][]VChannel{}
+	for _, vchannel := range vchannels {
+		t := vchannel.GetType()
+		classified[t] = append(classified[t], vchannel)
+	}
+	return classified
+}
+
+type VChannelMessageOptions struct {
+	Text        string
+	Attachments []MessageAttachment
+}
+
+// CreateMessage sends a message to vchannel.
+func (v *VChannelService) CreateMessage(ctx context.Context, vchannel VChannel, opt *VChannelMessageOptions) (*Message, *http.Response, error) {
+	if vchannel.GetVChannelID() == "" {
+		return nil, nil, fmt.Errorf("vchannel id is required")
+	}
+
+	return v.client.Message.Create(ctx, &MessageCreateOptions{
+		VChannelID:  vchannel.GetVChannelID(),
+		Text:        opt.Text,
+		Attachments: opt.Attachments,
+	})
+}
+
+// QueryMessages queries messages of vchannel.
+func (v *VChannelService) QueryMessages(ctx context.Context, vchannel VChannel, query *MessageQuery) (*MessageQueryResult, *http.Response, error) {
+	if vchannel.GetVChannelID() == "" {
+		return nil, nil, fmt.Errorf("vchannel id is required")
+	}
+
+	return v.client.Message.Query(ctx, &MessageQueryOptions{
+		VChannelID: vchannel.GetVChannelID(),
+		Query:      query,
+	})
+}
+
+func boolv(b *bool) bool {
+	if b == nil {
+		return false
+	}
+	return *b
+}
+
+func tsv(ts *VChannelTS) VChannelTS {
+	if ts == nil {
+		return 0
+	}
+	return *ts
+}
@@ -0,0 +1,68 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestVChannelService(t *testing.T) {
+	var created MessageCreateOptions
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/channel.list":
+			w.Write([]byte(`[{"id":"=ch1","vchannel_id":"=vc1","type":"channel","latest_ts":1}]`))
+		case "/session_channel.list":
+			w.Write([]byte(`[{"id":"=sc1","vchannel_id":"=vc2","member_uids":["=u1","=u2"]}]`))
+		case "/p2p.list":
+			w.Write([]byte(`[{"id":"=p1","vchannel_id":"=vc3","type":"p2p"}]`))
+		case "/message.create":
+			json.NewDecoder(r.Body).Decode(&created)
+			w.Write([]byte(`{"key":"1"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	baseURL, _ := url.Parse(server.URL + "/")
+	client := NewClient("foobar", NewClientWithBaseURL(baseURL))
+	ctx := context.Background()
+
+	vchannels, _, err := client.VChannel.List(ctx, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	classified := ClassifyVChannels(vchannels)
+	for _, vt := range []VChannelType{VChannelTypeChannel, VChannelTypeSessionChannel, VChannelTypeP2P} {
+		if len(classified[vt]) != 1 {
+			t.Errorf("expected one %s: %+v", vt, classified)
+		}
+	}
+
+	vchannels, _, _ = client.VChannel.List(ctx, &VChannelListOptions{Types: []VChannelType{VChannelTypeP2P}})
+	if len(vchannels) != 1 || vchannels[0].GetID() != "=p1" {
+		t.Errorf("unexpected vchannels: %+v", vchannels)
+	}
+
+	vchannel, _, err := client.VChannel.Info(ctx, &VChannelInfoOptions{VChannelID: "=vc2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if _, ok := vchannel.(*SessionChannel); !ok || len(vchannel.GetMemberUserIDs()) != 2 {
+		t.Errorf("unexpected vchannel: %+v", vchannel)
+	}
+	if _, _, err := client.VChannel.Info(ctx, &VChannelInfoOptions{VChannelID: "=missing"}); !IsNotFound(err) {
+		t.Errorf("expected not found: %+v", err)
+	}
+
+	if _, _, err := client.VChannel.CreateMessage(ctx, vchannel, &VChannelMessageOptions{Text: "hi"}); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if created.VChannelID != "=vc2" || created.Text != "hi" {
+		t.Errorf("unexpected message: %+v", created)
+	}
+}
@@ -0,0 +1,148 @@
+package bearychat
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// max outgoing payload size accepted
+const MAX_OUTGOING_PAYLOAD_SIZE = 1 << 20
+
+// Outgoing is the payload BearyChat outgoing robot posts to our server.
+//
+// For full documentation, visit https://bearychat.com/integrations/outgoing .
+type Outgoing struct {
+	Token       string `json:"token"`
+	TS          int64  `json:"ts"`
+	Text        string `json:"text"`
+	TriggerWord string `json:"trigger_word"`
+	Subdomain   string `json:"subdomain"`
+	ChannelName string `json:"channel_name"`
+	UserName    string `json:"user_name"`
+}
+
+// Time converts millisecond `ts` to time.
+func (m Outgoing) Time() time.Time {
+	return time.Unix(0, m.TS*int64(time.Millisecond))
+}
+
+// OutgoingHandlerFunc handles an outgoing robot request.
+// Returning a nil message replies nothing.
+type OutgoingHandlerFunc func(ctx context.Context, m Outgoing) (*Incoming, error)
+
+// OutgoingHandler is an http.Handler serving BearyChat outgoing robot.
+//
+//      handler, err := NewOutgoingHandler(
+//              "YOUR ROBOT TOKEN",
+//              func(ctx context.Context, m Outgoing) (*Incoming, error) {
+//                      return &Incoming{Text: "pong"}, nil
+//              },
+//      )
+//      http.Handle("/robot", handler)
+//
+// Handler errors are replied as a generic error, and sent to ErrC.
+type OutgoingHandler struct {
+	token   string
+	handler OutgoingHandlerFunc
+
+	errC chan error
+}
+
+// NewOutgoingHandler creates a handler verifying requests with robot token.
+func NewOutgoingHandler(token string, handler OutgoingHandlerFunc) (*OutgoingHandler, error) {
+	if token == "" {
+		return nil, errors.New("robot token is required")
+	}
+	if handler == nil {
+		return nil, errors.New("handler is required")
+	}
+
+	return &OutgoingHandler{
+		token:   token,
+		handler: handler,
+
+		errC: make(chan error, 1024),
+	}, nil
+}
+
+// ErrC returns error channel for failed handlers and replies.
+func (h *OutgoingHandler) ErrC() chan error {
+	return h.errC
+}
+
+func (h *OutgoingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeOutgoingError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var m Outgoing
+	body := http.MaxBytesReader(w, r.Body, MAX_OUTGOING_PAYLOAD_SIZE)
+	if err := json.NewDecoder(body).Decode(&m); err != nil {
+		writeOutgoingError(w, http.StatusBadRequest, "decode payload failed")
+		return
+	}
+
+	if !h.VerifyToken(m.Token) {
+		writeOutgoingError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+
+	reply, err := h.handler(r.Context(), m)
+	if err != nil {
+		h.reportError(errors.Wrapf(err, "handler for %s failed", m.TriggerWord))
+		writeOutgoingError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if err := writeOutgoingReply(w, reply); err != nil {
+		h.reportError(errors.Wrapf(err, "reply for %s failed", m.TriggerWord))
+	}
+}
+
+// VerifyToken compares token with robot token in constant time,
+// an empty token is never valid.
+func (h *OutgoingHandler) VerifyToken(token string) bool {
+	if token == "" || h.token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(h.token), []byte(token)) == 1
+}
+
+func (h *OutgoingHandler) reportError(err error) {
+	select {
+	case h.errC <- err:
+	default:
+	}
+}
+
+// writeOutgoingReply writes reply, or a generic error if it's invalid.
+func writeOutgoingReply(w http.ResponseWriter, reply *Incoming) error {
+	if reply == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+
+	payload, err := reply.Build()
+	if err != nil {
+		writeOutgoingError(w, http.StatusInternalServerError, "internal error")
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, payload)
+	return nil
+}
+
+func writeOutgoingError(w http.ResponseWriter, code int, reason string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": reason})
+}
@@ -0,0 +1,361 @@
+package bearychat
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/nanmu42/bearychat-go/trace"
+	"github.com/pkg/errors"
+)
+
+const (
+	DEFAULT_ROUTER_ASYNC_TIMEOUT = 5 * time.Minute
+)
+
+var (
+	ErrOutgoingHandlerTimeout = errors.New("outgoing handler timeout")
+)
+
+// OutgoingCommand is an outgoing robot request routed by trigger word.
+type OutgoingCommand struct {
+	Outgoing
+
+	// Text with trigger word stripped
+	Content string
+	// Content split by spaces, quoted with "" or '' for spaces in argument
+	Args []string
+}
+
+// OutgoingCommandHandlerFunc handles a routed outgoing command.
+// Returning a nil message replies nothing.
+type OutgoingCommandHandlerFunc func(ctx context.Context, c OutgoingCommand) (*Incoming, error)
+
+type outgoingRoute struct {
+	handler OutgoingCommandHandlerFunc
+	timeout time.Duration
+}
+
+type outgoingRouteSetter func(*outgoingRoute)
+
+// WithRouteTimeout overrides router timeout for one handler.
+func WithRouteTimeout(timeout time.Duration) outgoingRouteSetter {
+	return func(r *outgoingRoute) {
+		r.timeout = timeout
+	}
+}
+
+// OutgoingRouter dispatches outgoing robot requests to handlers
+// registered per trigger word and per channel.
+//
+// Handlers running longer than their timeout get their reply sent
+// later via the async webhook client (or dropped with ErrOutgoingHandlerTimeout
+// when there is none), errors of such handlers are sent to ErrC. Their ctx
+// is canceled once the reply is dropped, or after the async timeout.
+//
+//      router, _ := NewOutgoingRouter(
+//              WithRouterTimeout(3 * time.Second),
+//              WithRouterAsyncClient(NewIncomingWebhookClient("YOUR WEBHOOK URL")),
+//      )
+//      router.Handle("!deploy", deploy)
+//      router.HandleChannel("ops", "!restart", restart)
+//      handler, _ := NewOutgoingHandler("YOUR ROBOT TOKEN", router.ServeOutgoing)
+//      http.Handle("/robot", handler)
+type OutgoingRouter struct {
+	timeout      time.Duration
+	asyncTimeout time.Duration
+	asyncClient  WebhookClient
+	tracer      trace.Tracer
+
+	lock          sync.RWMutex // lock for properties below
+	routes        map[string]*outgoingRoute
+	channelRoutes map[string]map[string]*outgoingRoute
+	defaultRoute  *outgoingRoute
+
+	errC chan error
+}
+
+type outgoingRouterSetter func(*OutgoingRouter) error
+
+// WithRouterTimeout sets default handler timeout, 0 means no timeout.
+func WithRouterTimeout(timeout time.Duration) outgoingRouterSetter {
+	return func(r *OutgoingRouter) error {
+		r.timeout = timeout
+		return nil
+	}
+}
+
+// WithRouterAsyncTimeout sets how long a slow handler and its async reply
+// may run in total, DEFAULT_ROUTER_ASYNC_TIMEOUT by default. Handler's ctx
+// is canceled after it.
+func WithRouterAsyncTimeout(timeout time.Duration) outgoingRouterSetter {
+	return func(r *OutgoingRouter) error {
+		if timeout <= 0 {
+			return errors.New("async timeout should be positive")
+		}
+		r.asyncTimeout = timeout
+		return nil
+	}
+}
+
+// WithRouterAsyncClient sets webhook client sending replies of slow handlers.
+func WithRouterAsyncClient(client WebhookClient) outgoingRouterSetter {
+	return func(r *OutgoingRouter) error {
+		r.asyncClient = client
+		return nil
+	}
+}
+
+// WithRouterTracer sets tracer for handler execution, traces nothing by default.
+// Handler's ctx carries its span, async replies are traced as its children.
+func WithRouterTracer(tracer trace.Tracer) outgoingRouterSetter {
+	return func(r *OutgoingRouter) error {
+		r.tracer = tracer
+		return nil
+	}
+}
+
+// NewOutgoingRouter creates a router.
+func NewOutgoingRouter(setters ...outgoingRouterSetter) (*OutgoingRouter, error) {
+	r := &OutgoingRouter{
+		routes:        map[string]*outgoingRoute{},
+		channelRoutes: map[string]map[string]*outgoingRoute{},
+		asyncTimeout:  DEFAULT_ROUTER_ASYNC_TIMEOUT,
+		tracer:        trace.Nop,
+
+		errC: make(chan error, 1024),
+	}
+	for _, setter := range setters {
+		if err := setter(r); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// Handle registers handler for trigger word in all channels.
+func (r *OutgoingRouter) Handle(triggerWord string, handler OutgoingCommandHandlerFunc, setters ...outgoingRouteSetter) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.routes[triggerWord] = r.newRoute(handler, setters)
+}
+
+// HandleChannel registers handler for trigger word in channel,
+// it takes precedence over handler registered by Handle.
+func (r *OutgoingRouter) HandleChannel(channel, triggerWord string, handler OutgoingCommandHandlerFunc, setters ...outgoingRouteSetter) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	channel = strings.TrimPrefix(channel, "#")
+	if r.channelRoutes[channel] == nil {
+		r.channelRoutes[channel] = map[string]*outgoingRoute{}
+	}
+	r.channelRoutes[channel][triggerWord] = r.newRoute(handler, setters)
+}
+
+// HandleDefault registers handler for requests matching no other handler.
+func (r *OutgoingRouter) HandleDefault(handler OutgoingCommandHandlerFunc, setters ...outgoingRouteSetter) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.defaultRoute = r.newRoute(handler, setters)
+}
+
+// ErrC returns error channel for slow handlers.
+func (r *OutgoingRouter) ErrC() chan error {
+	return r.errC
+}
+
+// ServeOutgoing dispatches request to matched handler,
+// it can be used as OutgoingHandlerFunc.
+func (r *OutgoingRouter) ServeOutgoing(ctx context.Context, m Outgoing) (*Incoming, error) {
+	route := r.match(m)
+	if route == nil {
+		return nil, nil
+	}
+
+	content := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(m.Text), m.TriggerWord))
+	c := OutgoingCommand{
+		Outgoing: m,
+		Content:  content,
+		Args:     ParseOutgoingArgs(content),
+	}
+
+	ctx, span := r.tracer.Start(
+		ctx,
+		"bearychat.outgoing.handle",
+		trace.Attr("trigger_word", m.TriggerWord),
+		trace.Attr("channel_name", m.ChannelName),
+		trace.Attr("user_name", m.UserName),
+	)
+
+	if route.timeout <= 0 {
+		defer span.End()
+		reply, err := route.handler(ctx, c)
+		if err != nil {
+			span.RecordError(err)
+		}
+		return reply, err
+	}
+
+	type result struct {
+		reply *Incoming
+		err   error
+	}
+	// detach from request, handler may outlive it until async timeout
+	hctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.asyncTimeout)
+	done := make(chan result, 1)
+	go func() {
+		reply, err := route.handler(hctx, c)
+		done <- result{reply, err}
+	}()
+
+	timer := time.NewTimer(route.timeout)
+	defer timer.Stop()
+
+	select {
+	case res := <-done:
+		cancel()
+		if res.err != nil {
+			span.RecordError(res.err)
+		}
+		span.End()
+		return res.reply, res.err
+	case <-timer.C:
+	}
+
+	span.SetAttributes(trace.Attr("timeout", true))
+	if r.asyncClient == nil {
+		// reply would be dropped
+		cancel()
+		span.RecordError(ErrOutgoingHandlerTimeout)
+		span.End()
+		return nil, ErrOutgoingHandlerTimeout
+	}
+
+	// span ends after async reply is sent or dropped
+	go func() {
+		defer span.End()
+		defer cancel()
+
+		var res result
+		select {
+		case res = <-done:
+		case <-hctx.Done():
+			span.RecordError(hctx.Err())
+			r.reportError(errors.Wrapf(hctx.Err(), "async handler for %s timed out", m.TriggerWord))
+			return
+		}
+		if res.err != nil {
+			span.RecordError(res.err)
+			r.reportError(errors.Wrapf(res.err, "async handler for %s failed", m.TriggerWord))
+			return
+		}
+		if res.reply == nil {
+			return
+		}
+		if err := r.sendAsync(hctx, m, *res.reply); err != nil {
+			span.RecordError(err)
+			r.reportError(errors.Wrapf(err, "async reply for %s failed", m.TriggerWord))
+		}
+	}()
+
+	return nil, nil
+}
+
+func (r *OutgoingRouter) newRoute(handler OutgoingCommandHandlerFunc, setters []outgoingRouteSetter) *outgoingRoute {
+	route := &outgoingRoute{
+		handler: handler,
+		timeout: r.timeout,
+	}
+	for _, setter := range setters {
+		setter(route)
+	}
+	return route
+}
+
+func (r *OutgoingRouter) match(m Outgoing) *outgoingRoute {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	if route, present := r.channelRoutes[m.ChannelName][m.TriggerWord]; present {
+		return route
+	}
+	if route, present := r.routes[m.TriggerWord]; present {
+		return route
+	}
+	return r.defaultRoute
+}
+
+func (r *OutgoingRouter) sendAsync(ctx context.Context, m Outgoing, reply Incoming) error {
+	if reply.Channel == "" && reply.User == "" {
+		reply.Channel = m.ChannelName
+	}
+
+	payload, err := reply.Build()
+	if err != nil {
+		return err
+	}
+	var resp *WebhookResponse
+	if sender, ok := r.asyncClient.(WebhookContextSender); ok {
+		resp, err = sender.SendContext(ctx, payload)
+	} else {
+		resp, err = r.asyncClient.Send(payload)
+	}
+	if err != nil {
+		return err
+	}
+	if !resp.IsOk() {
+		return errors.Errorf("webhook responded: %d %s", resp.Code, resp.Error)
+	}
+	return nil
+}
+
+func (r *OutgoingRouter) reportError(err error) {
+	select {
+	case r.errC <- err:
+	default:
+	}
+}
+
+// ParseOutgoingArgs splits command content by spaces,
+// quote argument containing spaces with "" or ''.
+func ParseOutgoingArgs(s string) []string {
+	var (
+		args    []string
+		cur     strings.Builder
+		quote   rune
+		pending bool
+	)
+	for _, c := range s {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(c)
+			}
+		case c == '"' || c == '\'':
+			quote = c
+			pending = true
+		case unicode.IsSpace(c):
+			if pending {
+				args = append(args, cur.String())
+				cur.Reset()
+				pending = false
+			}
+		default:
+			cur.WriteRune(c)
+			pending = true
+		}
+	}
+	if pending {
+		args = append(args, cur.String())
+	}
+
+	return args
+}
@@ -0,0 +1,182 @@
+package bearychat
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestParseOutgoingArgs(t *testing.T) {
+	cases := []struct {
+		in       string
+		expected []string
+	}{
+		{"", nil},
+		{"a b  c", []string{"a", "b", "c"}},
+		{`deploy "my service" 'v1 2' ""`, []string{"deploy", "my service", "v1 2", ""}},
+		{`a"b c"d`, []string{"ab cd"}},
+	}
+
+	for _, c := range cases {
+		if args := ParseOutgoingArgs(c.in); !reflect.DeepEqual(args, c.expected) {
+			t.Errorf("expected %q for %q, got %q", c.expected, c.in, args)
+		}
+	}
+}
+
+func replyText(text string) OutgoingCommandHandlerFunc {
+	return func(ctx context.Context, c OutgoingCommand) (*Incoming, error) {
+		return &Incoming{Text: text}, nil
+	}
+}
+
+func TestOutgoingRouter_ServeOutgoing(t *testing.T) {
+	r, _ := NewOutgoingRouter()
+
+	var received OutgoingCommand
+	r.Handle("!deploy", func(ctx context.Context, c OutgoingCommand) (*Incoming, error) {
+		received = c
+		return &Incoming{Text: "global"}, nil
+	})
+	r.HandleChannel("#ops", "!deploy", replyText("ops"))
+
+	reply, err := r.ServeOutgoing(context.Background(), Outgoing{
+		Text:        "!deploy api \"v1.0 rc\"",
+		TriggerWord: "!deploy",
+		ChannelName: "dev",
+	})
+	if err != nil || reply.Text != "global" {
+		t.Errorf("unexpected reply: %+v %+v", reply, err)
+	}
+	if received.Content != "api \"v1.0 rc\"" {
+		t.Errorf("unexpected content: %s", received.Content)
+	}
+	if !reflect.DeepEqual(received.Args, []string{"api", "v1.0 rc"}) {
+		t.Errorf("unexpected args: %q", received.Args)
+	}
+
+	reply, _ = r.ServeOutgoing(context.Background(), Outgoing{
+		Text:        "!deploy",
+		TriggerWord: "!deploy",
+		ChannelName: "ops",
+	})
+	if reply.Text != "ops" {
+		t.Errorf("channel handler should take precedence: %+v", reply)
+	}
+
+	reply, err = r.ServeOutgoing(context.Background(), Outgoing{TriggerWord: "!unknown"})
+	if reply != nil || err != nil {
+		t.Errorf("unexpected reply without default handler: %+v %+v", reply, err)
+	}
+
+	r.HandleDefault(replyText("default"))
+	reply, _ = r.ServeOutgoing(context.Background(), Outgoing{TriggerWord: "!unknown"})
+	if reply.Text != "default" {
+		t.Errorf("unexpected reply: %+v", reply)
+	}
+}
+
+func TestOutgoingRouter_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	slow := func(ctx context.Context, c OutgoingCommand) (*Incoming, error) {
+		<-release
+		return &Incoming{Text: "done"}, nil
+	}
+
+	canceled := make(chan struct{})
+	dropped := func(ctx context.Context, c OutgoingCommand) (*Incoming, error) {
+		<-ctx.Done()
+		close(canceled)
+		return nil, ctx.Err()
+	}
+
+	r, _ := NewOutgoingRouter(WithRouterTimeout(10 * time.Millisecond))
+	r.Handle("!slow", slow)
+	r.Handle("!dropped", dropped)
+	r.Handle("!fast", replyText("fast"), WithRouteTimeout(time.Second))
+
+	if reply, _ := r.ServeOutgoing(context.Background(), Outgoing{TriggerWord: "!fast"}); reply.Text != "fast" {
+		t.Errorf("unexpected reply: %+v", reply)
+	}
+	if _, err := r.ServeOutgoing(context.Background(), Outgoing{TriggerWord: "!slow"}); err != ErrOutgoingHandlerTimeout {
+		t.Errorf("expected timeout: %+v", err)
+	}
+	close(release)
+
+	// reply is dropped without async client
+	if _, err := r.ServeOutgoing(context.Background(), Outgoing{TriggerWord: "!dropped"}); err != ErrOutgoingHandlerTimeout {
+		t.Errorf("expected timeout: %+v", err)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Errorf("expected handler ctx canceled")
+	}
+}
+
+func TestOutgoingRouter_AsyncTimeout(t *testing.T) {
+	client := &testWebhookClient{}
+	if _, err := NewOutgoingRouter(WithRouterAsyncTimeout(0)); err == nil {
+		t.Errorf("should reject non-positive async timeout")
+	}
+
+	r, _ := NewOutgoingRouter(
+		WithRouterTimeout(10*time.Millisecond),
+		WithRouterAsyncTimeout(30*time.Millisecond),
+		WithRouterAsyncClient(client),
+	)
+	r.Handle("!hung", func(ctx context.Context, c OutgoingCommand) (*Incoming, error) {
+		<-ctx.Done()
+		return &Incoming{Text: "late"}, nil
+	})
+
+	if reply, err := r.ServeOutgoing(context.Background(), Outgoing{TriggerWord: "!hung"}); reply != nil || err != nil {
+		t.Errorf("unexpected reply: %+v %+v", reply, err)
+	}
+	select {
+	case err := <-r.ErrC():
+		if errors.Cause(err) != context.DeadlineExceeded {
+			t.Errorf("unexpected error: %+v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected async timeout reported")
+	}
+	if sent := client.sent(); len(sent) != 0 {
+		t.Errorf("timed out reply should be dropped: %+v", sent)
+	}
+}
+
+func TestOutgoingRouter_Timeout_Async(t *testing.T) {
+	client := &testWebhookClient{}
+	release := make(chan struct{})
+
+	r, _ := NewOutgoingRouter(
+		WithRouterTimeout(10*time.Millisecond),
+		WithRouterAsyncClient(client),
+	)
+	r.Handle("!slow", func(ctx context.Context, c OutgoingCommand) (*Incoming, error) {
+		<-release
+		return &Incoming{Text: "done"}, nil
+	})
+
+	reply, err := r.ServeOutgoing(context.Background(), Outgoing{
+		TriggerWord: "!slow",
+		ChannelName: "ops",
+	})
+	if reply != nil || err != nil {
+		t.Errorf("unexpected reply: %+v %+v", reply, err)
+	}
+	close(release)
+
+	deadline := time.Now().Add(time.Second)
+	for len(client.sent()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	sent := client.sent()
+	if len(sent) != 1 || sent[0].Text != "done" || sent[0].Channel != "ops" {
+		t.Errorf("unexpected async reply: %+v", sent)
+	}
+}
@@ -0,0 +1,133 @@
+package bearychat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	testOutgoingToken   = "foobar"
+	testOutgoingPayload = `{
+		"token": "foobar",
+		"ts": 1540736786063,
+		"text": "!ping hello",
+		"trigger_word": "!ping",
+		"subdomain": "nanmu",
+		"channel_name": "ops",
+		"user_name": "alice"
+	}`
+)
+
+func doOutgoingRequest(h http.Handler, method, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(method, "/robot", strings.NewReader(body))
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func newTestOutgoingHandler(t *testing.T, handler OutgoingHandlerFunc) *OutgoingHandler {
+	h, err := NewOutgoingHandler(testOutgoingToken, handler)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	return h
+}
+
+func TestNewOutgoingHandler(t *testing.T) {
+	handler := func(ctx context.Context, m Outgoing) (*Incoming, error) {
+		return nil, nil
+	}
+	if _, err := NewOutgoingHandler("", handler); err == nil {
+		t.Errorf("should reject empty token")
+	}
+	if _, err := NewOutgoingHandler(testOutgoingToken, nil); err == nil {
+		t.Errorf("should reject nil handler")
+	}
+}
+
+func TestOutgoingHandler_Reply(t *testing.T) {
+	var received Outgoing
+	h := newTestOutgoingHandler(t, func(ctx context.Context, m Outgoing) (*Incoming, error) {
+		received = m
+		return &Incoming{Text: "pong"}, nil
+	})
+
+	w := doOutgoingRequest(h, http.MethodPost, testOutgoingPayload)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d %s", w.Code, w.Body)
+	}
+
+	var reply Incoming
+	if err := json.NewDecoder(w.Body).Decode(&reply); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if reply.Text != "pong" {
+		t.Errorf("unexpected reply: %+v", reply)
+	}
+
+	if received.TriggerWord != "!ping" || received.UserName != "alice" || received.ChannelName != "ops" {
+		t.Errorf("unexpected payload: %+v", received)
+	}
+	if received.Time().Unix() != 1540736786 {
+		t.Errorf("unexpected time: %s", received.Time())
+	}
+}
+
+func TestOutgoingHandler_NoReply(t *testing.T) {
+	h := newTestOutgoingHandler(t, func(ctx context.Context, m Outgoing) (*Incoming, error) {
+		return nil, nil
+	})
+
+	if w := doOutgoingRequest(h, http.MethodPost, testOutgoingPayload); w.Code != http.StatusNoContent {
+		t.Errorf("unexpected status: %d", w.Code)
+	}
+}
+
+func TestOutgoingHandler_Errors(t *testing.T) {
+	called := false
+	h := newTestOutgoingHandler(t, func(ctx context.Context, m Outgoing) (*Incoming, error) {
+		called = true
+		if m.Text == "fail" {
+			return nil, errors.New("failed")
+		}
+		return &Incoming{}, nil
+	})
+
+	cases := []struct {
+		method string
+		body   string
+		code   int
+	}{
+		{http.MethodGet, "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "{", http.StatusBadRequest},
+		{http.MethodPost, `{"token": "wrong"}`, http.StatusUnauthorized},
+		{http.MethodPost, `{"token": ""}`, http.StatusUnauthorized},
+		{http.MethodPost, `{"text": "no token"}`, http.StatusUnauthorized},
+		{http.MethodPost, `{"token": "foobar", "text": "fail"}`, http.StatusInternalServerError},
+		// invalid reply
+		{http.MethodPost, `{"token": "foobar"}`, http.StatusInternalServerError},
+	}
+	for _, c := range cases {
+		w := doOutgoingRequest(h, c.method, c.body)
+		if w.Code != c.code {
+			t.Errorf("expected status %d for %s %s, got %d", c.code, c.method, c.body, w.Code)
+		}
+		if c.code == http.StatusInternalServerError && strings.TrimSpace(w.Body.String()) != `{"error":"internal error"}` {
+			t.Errorf("internal errors should not be replied: %s", w.Body.String())
+		}
+	}
+	if !called {
+		t.Errorf("handler should be called for verified requests")
+	}
+	if h.VerifyToken("") {
+		t.Errorf("empty token should never be valid")
+	}
+	if len(h.ErrC()) != 2 {
+		t.Errorf("expected errors reported, got %d", len(h.ErrC()))
+	}
+}
@@ -1,6 +1,9 @@
 package bearychat
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 type RTMChannelService struct {
 	rtm *RTMClient
@@ -12,8 +15,12 @@ func newRTMChannelService(rtm *RTMClient) error {
 }
 
 func (s *RTMChannelService) Info(channelId string) (*Channel, error) {
+	return s.InfoContext(context.Background(), channelId)
+}
+
+func (s *RTMChannelService) InfoContext(ctx context.Context, channelId string) (*Channel, error) {
 	channel := new(Channel)
 	resource := fmt.Sprintf("v1/channel.info?channel_id=%s", channelId)
-	_, err := s.rtm.Get(resource, channel)
+	_, err := s.rtm.GetContext(ctx, resource, channel)
 	return channel, err
 }
@@ -2,11 +2,18 @@ package bearychat
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nanmu42/bearychat-go/metrics"
+	"github.com/nanmu42/bearychat-go/trace"
 )
 
 const (
@@ -28,6 +35,9 @@ type RTMClient struct {
 	Channel     *RTMChannelService
 
 	httpClient *http.Client
+	logger     Logger
+	metrics    metrics.Metrics
+	tracer     trace.Tracer
 }
 
 type rtmOptSetter func(*RTMClient) error
@@ -84,8 +94,82 @@ func WithRTMHTTPClient(httpClient *http.Client) rtmOptSetter {
 	}
 }
 
+// WithRTMLogger sets logger for api requests, logs nothing by default.
+func WithRTMLogger(logger Logger) rtmOptSetter {
+	return func(c *RTMClient) error {
+		c.logger = logger
+		return nil
+	}
+}
+
+// WithRTMMetrics sets metrics for api requests, records nothing by default.
+func WithRTMMetrics(m metrics.Metrics) rtmOptSetter {
+	return func(c *RTMClient) error {
+		c.metrics = m
+		return nil
+	}
+}
+
+// WithRTMTracer sets tracer for api requests, traces nothing by default.
+func WithRTMTracer(tracer trace.Tracer) rtmOptSetter {
+	return func(c *RTMClient) error {
+		c.tracer = tracer
+		return nil
+	}
+}
+
+// observe records latency of request to resource, with status code or `error`.
+func (c RTMClient) observe(resource string, start time.Time, status string) {
+	if c.metrics == nil {
+		return
+	}
+	endpoint := strings.SplitN(resource, "?", 2)[0]
+	c.metrics.Observe(
+		"bearychat_http_request_duration_seconds",
+		time.Since(start).Seconds(),
+		"client", "rtm",
+		"endpoint", endpoint,
+		"status", status,
+	)
+}
+
+func (c RTMClient) log() Logger {
+	if c.logger == nil {
+		return nopLogger
+	}
+	return c.logger
+}
+
+func (c RTMClient) trace() trace.Tracer {
+	if c.tracer == nil {
+		return trace.Nop
+	}
+	return c.tracer
+}
+
 // Do performs an api request.
 func (c RTMClient) Do(resource, method string, in, result interface{}) (*http.Response, error) {
+	return c.DoContext(context.Background(), resource, method, in, result)
+}
+
+// DoContext performs an api request with ctx, which is also traced as parent span.
+func (c RTMClient) DoContext(ctx context.Context, resource, method string, in, result interface{}) (resp *http.Response, err error) {
+	ctx, span := c.trace().Start(
+		ctx,
+		"bearychat.rtm.request",
+		trace.Attr("http.method", method),
+		trace.Attr("endpoint", strings.SplitN(resource, "?", 2)[0]),
+	)
+	defer func() {
+		if resp != nil {
+			span.SetAttributes(trace.Attr("http.status_code", resp.StatusCode))
+		}
+		if err != nil {
+			span.RecordError(redactURLError(err))
+		}
+		span.End()
+	}()
+
 	uri, err := addTokenToResourceUri(
 		fmt.Sprintf("%s/%s", c.APIBase, resource),
 		c.Token,
@@ -105,7 +189,7 @@ func (c RTMClient) Do(resource, method string, in, result interface{}) (*http.Re
 	}
 
 	// build request
-	req, err := http.NewRequest(method, uri, buf)
+	req, err := http.NewRequestWithContext(ctx, method, uri, buf)
 	if err != nil {
 		return nil, err
 	}
@@ -114,20 +198,42 @@ func (c RTMClient) Do(resource, method string, in, result interface{}) (*http.Re
 	}
 	req.Header.Set("Accept", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	c.log().Debug("rtm api request", "method", method, "resource", resource)
+	start := time.Now()
+
+	resp, err = c.httpClient.Do(req)
 	if err != nil {
+		c.observe(resource, start, "error")
+		c.log().Warn("rtm api request failed", "method", method, "resource", resource, "error", redactURLError(err))
 		return nil, err
 	}
 
 	// parse response
 	defer resp.Body.Close()
-	response := new(RTMAPIResponse)
+	c.observe(resource, start, strconv.Itoa(resp.StatusCode))
+	c.log().Debug(
+		"rtm api response",
+		"method", method,
+		"resource", resource,
+		"status", resp.StatusCode,
+		"duration", time.Since(start),
+	)
+	response := &RTMAPIResponse{StatusCode: resp.StatusCode}
 	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		c.log().Warn("rtm api response decode failed", "method", method, "resource", resource, "error", err)
 		return resp, err
 	}
 
 	// request failed
 	if resp.StatusCode/100 != 2 || response.Code != 0 {
+		c.log().Warn(
+			"rtm api error response",
+			"method", method,
+			"resource", resource,
+			"status", resp.StatusCode,
+			"code", response.Code,
+			"reason", response.ErrorReason,
+		)
 		return resp, response
 	}
 
@@ -140,33 +246,52 @@ func (c RTMClient) Do(resource, method string, in, result interface{}) (*http.Re
 }
 
 func (c RTMClient) Get(resource string, result interface{}) (*http.Response, error) {
-	return c.Do(resource, "GET", nil, result)
+	return c.GetContext(context.Background(), resource, result)
+}
+
+func (c RTMClient) GetContext(ctx context.Context, resource string, result interface{}) (*http.Response, error) {
+	return c.DoContext(ctx, resource, "GET", nil, result)
 }
 
 func (c RTMClient) Post(resource string, in, result interface{}) (*http.Response, error) {
-	return c.Do(resource, "POST", in, result)
+	return c.PostContext(context.Background(), resource, in, result)
+}
+
+func (c RTMClient) PostContext(ctx context.Context, resource string, in, result interface{}) (*http.Response, error) {
+	return c.DoContext(ctx, resource, "POST", in, result)
 }
 
 // Start performs rtm.start
 func (c RTMClient) Start() (*User, string, error) {
+	return c.StartContext(context.Background())
+}
+
+// StartContext performs rtm.start with ctx.
+func (c RTMClient) StartContext(ctx context.Context) (*User, string, error) {
 	userAndWSHost := new(struct {
 		User   *User  `json:"user"`
 		WSHost string `json:"ws_host"`
 	})
-	_, err := c.Post("start", nil, userAndWSHost)
+	_, err := c.PostContext(ctx, "start", nil, userAndWSHost)
 
 	return userAndWSHost.User, userAndWSHost.WSHost, err
 }
 
 // Incoming performs rtm.message
 func (c RTMClient) Incoming(m RTMIncoming) error {
-	_, err := c.Post("message", m, nil)
+	return c.IncomingContext(context.Background(), m)
+}
+
+// IncomingContext performs rtm.message with ctx.
+func (c RTMClient) IncomingContext(ctx context.Context, m RTMIncoming) error {
+	_, err := c.PostContext(ctx, "message", m, nil)
 
 	return err
 }
 
 // RTM api request response
 type RTMAPIResponse struct {
+	StatusCode  int             `json:"-"`
 	Code        int             `json:"code"`
 	Result      json.RawMessage `json:"result,omitempty"`
 	ErrorReason string          `json:"error,omitempty"`
@@ -176,6 +301,12 @@ func (r *RTMAPIResponse) Error() string {
 	return r.ErrorReason
 }
 
+// IsTokenRejected tells if the request is rejected for invalid token,
+// which is not worth retrying.
+func (r *RTMAPIResponse) IsTokenRejected() bool {
+	return r.StatusCode == http.StatusUnauthorized || r.StatusCode == http.StatusForbidden
+}
+
 func addTokenToResourceUri(resource, token string) (string, error) {
 	uri, err := url.Parse(resource)
 	if err != nil {
@@ -1,53 +1,540 @@
 package bearychat
 
-import "time"
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
 
+	"github.com/nanmu42/bearychat-go/metrics"
+	"github.com/nanmu42/bearychat-go/trace"
+	"github.com/pkg/errors"
+)
+
+const (
+	DEFAULT_RTM_KEEPALIVE_INTERVAL    = 10 * time.Second
+	DEFAULT_RTM_PONG_TIMEOUT          = 5 * time.Second
+	DEFAULT_RTM_RECONNECT_MIN_BACKOFF = 1 * time.Second
+	DEFAULT_RTM_RECONNECT_MAX_BACKOFF = 30 * time.Second
+)
+
+// RTMHandlerFunc handles a message received by RTMContext.
+// Reply with c.Send, ctx is canceled when RTMContext shuts down.
+type RTMHandlerFunc func(ctx context.Context, c *RTMContext, m RTMMessage)
+
+type rtmHandler struct {
+	match   func(c *RTMContext, m RTMMessage) bool
+	handler RTMHandlerFunc
+}
+
+func matchRTMMessageType(mtype RTMMessageType) func(c *RTMContext, m RTMMessage) bool {
+	return func(c *RTMContext, m RTMMessage) bool {
+		return m.Type() == mtype
+	}
+}
+
+func matchRTMChatMessage(c *RTMContext, m RTMMessage) bool {
+	return m.IsChatMessage() && !m.IsFromUID(c.UID())
+}
+
+func matchRTMMessage(c *RTMContext, m RTMMessage) bool {
+	return true
+}
+
+// RTMContext is a bot runtime: it connects to RTM, keeps the connection
+// alive, reconnects with backoff when it's lost, and dispatches received
+// messages to registered handlers.
+//
+//      c, _ := NewRTMContext("rtm-token", WithRTMContextKeepalive(5*time.Second))
+//      c.HandleChat(func(ctx context.Context, c *RTMContext, m RTMMessage) {
+//              if mentioned, content := m.ParseMentionUID(c.UID()); mentioned {
+//                      c.SendContext(ctx, m.Refer(content))
+//              }
+//      })
+//      c.Run(context.Background())
+//
+// Handlers are called in order of registration from one goroutine, start
+// goroutines for slow work. Time spent in handlers is not counted toward
+// pong timeout. Errors not stopping Run are sent to ErrC.
+//
+// Handler's ctx carries the span of handled message, pass it to api calls
+// and SendContext to trace them as its children.
 type RTMContext struct {
-	Loop RTMLoop
+	client            *RTMClient
+	apiBase           string
+	httpClient        *http.Client
+	backlog           int
+	keepaliveInterval time.Duration
+	pongTimeout       time.Duration
+	minBackoff        time.Duration
+	maxBackoff        time.Duration
+	newLoop           func(wsHost string) (RTMLoop, error)
+	logger            Logger
+	metrics           metrics.Metrics
+	tracer            trace.Tracer
 
-	uid string
+	lock            sync.RWMutex // lock for properties below
+	user            *User
+	wsHost          string
+	loop            RTMLoop
+	handlers        []rtmHandler
+	connectHooks    []func(c *RTMContext)
+	disconnectHooks []func(c *RTMContext, err error)
+
+	errC chan error
 }
 
-func (c *RTMContext) UID() string {
-	return c.uid
+type rtmContextSetter func(*RTMContext) error
+
+// WithRTMContextAPIBase sets rtm api base, DEFAULT_RTM_API_BASE by default.
+func WithRTMContextAPIBase(apiBase string) rtmContextSetter {
+	return func(c *RTMContext) error {
+		c.apiBase = apiBase
+		return nil
+	}
+}
+
+// WithRTMContextHTTPClient sets http client for rtm api.
+func WithRTMContextHTTPClient(httpClient *http.Client) rtmContextSetter {
+	return func(c *RTMContext) error {
+		c.httpClient = httpClient
+		return nil
+	}
+}
+
+// WithRTMContextBacklog sets RTM message chan backlog.
+func WithRTMContextBacklog(backlog int) rtmContextSetter {
+	return func(c *RTMContext) error {
+		c.backlog = backlog
+		return nil
+	}
+}
+
+// WithRTMContextKeepalive sets ping interval, DEFAULT_RTM_KEEPALIVE_INTERVAL by default.
+func WithRTMContextKeepalive(interval time.Duration) rtmContextSetter {
+	return func(c *RTMContext) error {
+		if interval <= 0 {
+			return errors.New("keepalive interval should be positive")
+		}
+		c.keepaliveInterval = interval
+		return nil
+	}
+}
+
+// WithRTMContextPongTimeout sets how long to wait for pong of a ping before
+// reconnecting, DEFAULT_RTM_PONG_TIMEOUT by default.
+func WithRTMContextPongTimeout(timeout time.Duration) rtmContextSetter {
+	return func(c *RTMContext) error {
+		if timeout <= 0 {
+			return errors.New("pong timeout should be positive")
+		}
+		c.pongTimeout = timeout
+		return nil
+	}
+}
+
+// WithRTMContextBackoff sets reconnect backoff, which doubles from min to max
+// after each failed attempt.
+func WithRTMContextBackoff(min, max time.Duration) rtmContextSetter {
+	return func(c *RTMContext) error {
+		if min <= 0 || max < min {
+			return errors.New("invalid reconnect backoff")
+		}
+		c.minBackoff = min
+		c.maxBackoff = max
+		return nil
+	}
+}
+
+// WithRTMContextLogger sets logger of the context, its client and loops,
+// logs nothing by default.
+func WithRTMContextLogger(logger Logger) rtmContextSetter {
+	return func(c *RTMContext) error {
+		c.logger = logger
+		return nil
+	}
+}
+
+// WithRTMContextMetrics sets metrics of the context, its client and loops,
+// records nothing by default.
+func WithRTMContextMetrics(m metrics.Metrics) rtmContextSetter {
+	return func(c *RTMContext) error {
+		c.metrics = m
+		return nil
+	}
 }
 
-func NewRTMContext(token string) (*RTMContext, error) {
-	rtmClient, err := NewRTMClient(token)
+// WithRTMContextTracer sets tracer of the context, its client and loops,
+// traces nothing by default.
+func WithRTMContextTracer(tracer trace.Tracer) rtmContextSetter {
+	return func(c *RTMContext) error {
+		c.tracer = tracer
+		return nil
+	}
+}
+
+// WithRTMContextLoop sets how loops are created, e.g. to record sessions:
+//
+//      WithRTMContextLoop(func(wsHost string) (RTMLoop, error) {
+//              loop, err := NewRTMLoop(wsHost)
+//              if err != nil {
+//                      return nil, err
+//              }
+//              return NewRTMRecorder(loop, f), nil
+//      })
+func WithRTMContextLoop(newLoop func(wsHost string) (RTMLoop, error)) rtmContextSetter {
+	return func(c *RTMContext) error {
+		c.newLoop = newLoop
+		return nil
+	}
+}
+
+// NewRTMContext creates a context, and performs rtm.start to get self user.
+func NewRTMContext(token string, setters ...rtmContextSetter) (*RTMContext, error) {
+	c := &RTMContext{
+		apiBase:           DEFAULT_RTM_API_BASE,
+		keepaliveInterval: DEFAULT_RTM_KEEPALIVE_INTERVAL,
+		pongTimeout:       DEFAULT_RTM_PONG_TIMEOUT,
+		minBackoff:        DEFAULT_RTM_RECONNECT_MIN_BACKOFF,
+		maxBackoff:        DEFAULT_RTM_RECONNECT_MAX_BACKOFF,
+		logger:            nopLogger,
+		metrics:           metrics.Nop,
+		tracer:            trace.Nop,
+
+		errC: make(chan error, 1024),
+	}
+	for _, setter := range setters {
+		if err := setter(c); err != nil {
+			return nil, err
+		}
+	}
+	if c.newLoop == nil {
+		c.newLoop = func(wsHost string) (RTMLoop, error) {
+			return NewRTMLoop(
+				wsHost,
+				WithRTMLoopBacklog(c.backlog),
+				WithRTMLoopLogger(c.logger),
+				WithRTMLoopMetrics(c.metrics),
+				WithRTMLoopTracer(c.tracer),
+			)
+		}
+	}
+
+	clientSetters := []rtmOptSetter{
+		WithRTMAPIBase(c.apiBase),
+		WithRTMLogger(c.logger),
+		WithRTMMetrics(c.metrics),
+		WithRTMTracer(c.tracer),
+	}
+	if c.httpClient != nil {
+		clientSetters = append(clientSetters, WithRTMHTTPClient(c.httpClient))
+	}
+	client, err := NewRTMClient(token, clientSetters...)
 	if err != nil {
 		return nil, err
 	}
+	c.client = client
 
-	user, wsHost, err := rtmClient.Start()
+	user, wsHost, err := client.Start()
 	if err != nil {
 		return nil, err
 	}
+	c.user = user
+	c.wsHost = wsHost
+
+	return c, nil
+}
+
+// Client returns the rtm client.
+func (c *RTMContext) Client() *RTMClient {
+	return c.client
+}
+
+// User returns self user, updated on each connect.
+func (c *RTMContext) User() *User {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.user
+}
+
+// UID returns self user id.
+func (c *RTMContext) UID() string {
+	return c.User().Id
+}
+
+// Loop returns current loop, nil when not connected.
+func (c *RTMContext) Loop() RTMLoop {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.loop
+}
+
+// Send sends message with current loop.
+func (c *RTMContext) Send(m RTMMessage) error {
+	loop := c.Loop()
+	if loop == nil {
+		return ErrRTMLoopClosed
+	}
+
+	return loop.Send(m)
+}
+
+// SendContext sends message with current loop, traced as child span of ctx
+// if the loop supports.
+func (c *RTMContext) SendContext(ctx context.Context, m RTMMessage) error {
+	loop := c.Loop()
+	if loop == nil {
+		return ErrRTMLoopClosed
+	}
+
+	if sender, ok := loop.(rtmLoopContextSender); ok {
+		return sender.SendContext(ctx, m)
+	}
+	return loop.Send(m)
+}
+
+// ErrC returns channel of errors not stopping Run, e.g. connect failures.
+func (c *RTMContext) ErrC() chan error {
+	return c.errC
+}
+
+// Handle registers handler for messages of type mtype.
+func (c *RTMContext) Handle(mtype RTMMessageType, handler RTMHandlerFunc) {
+	c.handle(matchRTMMessageType(mtype), handler)
+}
+
+// HandleChat registers handler for p2p and channel messages not sent by self.
+func (c *RTMContext) HandleChat(handler RTMHandlerFunc) {
+	c.handle(matchRTMChatMessage, handler)
+}
+
+// HandleAll registers handler for all messages.
+func (c *RTMContext) HandleAll(handler RTMHandlerFunc) {
+	c.handle(matchRTMMessage, handler)
+}
+
+func (c *RTMContext) handle(match func(c *RTMContext, m RTMMessage) bool, handler RTMHandlerFunc) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.handlers = append(c.handlers, rtmHandler{match: match, handler: handler})
+}
+
+// OnConnect registers hook called after each connect.
+func (c *RTMContext) OnConnect(hook func(c *RTMContext)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.connectHooks = append(c.connectHooks, hook)
+}
+
+// OnDisconnect registers hook called after each disconnect, with the error
+// causing it, or nil when shutting down.
+func (c *RTMContext) OnDisconnect(hook func(c *RTMContext, err error)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.disconnectHooks = append(c.disconnectHooks, hook)
+}
+
+// Run connects and serves until ctx is canceled, e.g. by signal.NotifyContext,
+// reconnecting when connection is lost or a ping gets no pong in time.
+// It returns nil when shutting down, or the error when rtm.start rejects
+// the token, other rtm.start errors are retried with backoff.
+func (c *RTMContext) Run(ctx context.Context) error {
+	backoff := c.minBackoff
+	for {
+		loop, err := c.connect(ctx)
+		if err != nil && ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			if resp, ok := errors.Cause(err).(*RTMAPIResponse); ok && resp.IsTokenRejected() {
+				c.logger.Error("rtm start rejected", "error", err)
+				return err
+			}
+			c.logger.Warn("rtm connect failed", "error", err)
+			c.fail(errors.Wrap(err, "connect failed"))
+		} else {
+			backoff = c.minBackoff
+			for _, hook := range c.hooks() {
+				hook(c)
+			}
+
+			err = c.serve(ctx, loop)
+			loop.Stop()
+			c.lock.Lock()
+			c.loop = nil
+			disconnectHooks := append([]func(*RTMContext, error){}, c.disconnectHooks...)
+			c.lock.Unlock()
+			for _, hook := range disconnectHooks {
+				hook(c, err)
+			}
+
+			if err == nil {
+				return nil
+			}
+			c.fail(errors.Wrap(err, "disconnected"))
+		}
+
+		c.logger.Info("rtm reconnecting", "backoff", backoff)
+		c.metrics.Add("bearychat_rtm_reconnects_total", 1)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = backoff * 2
+		if backoff > c.maxBackoff {
+			backoff = c.maxBackoff
+		}
+	}
+}
 
-	rtmLoop, err := NewRTMLoop(wsHost)
+func (c *RTMContext) hooks() []func(*RTMContext) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return append([]func(*RTMContext){}, c.connectHooks...)
+}
+
+// connect starts a loop, ws host from last rtm.start is used once.
+func (c *RTMContext) connect(ctx context.Context) (RTMLoop, error) {
+	c.lock.Lock()
+	wsHost := c.wsHost
+	c.wsHost = ""
+	c.lock.Unlock()
+
+	if wsHost == "" {
+		user, newWSHost, err := c.client.StartContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.lock.Lock()
+		c.user = user
+		c.lock.Unlock()
+		wsHost = newWSHost
+	}
+
+	loop, err := c.newLoop(wsHost)
 	if err != nil {
 		return nil, err
 	}
+	if err := loop.Start(); err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	c.loop = loop
+	c.lock.Unlock()
 
-	return &RTMContext{
-		Loop: rtmLoop,
-		uid:  user.Id,
-	}, nil
+	return loop, nil
 }
 
-func (c *RTMContext) Run() (error, chan RTMMessage, chan error) {
-	err := c.Loop.Start()
+// serve dispatches messages until ctx is done (returns nil) or connection is lost.
+func (c *RTMContext) serve(ctx context.Context, loop RTMLoop) error {
+	messageC, err := loop.ReadC()
 	if err != nil {
-		return err, nil, nil
+		return err
 	}
-	defer c.Loop.Stop()
+	errC := loop.ErrC()
 
-	go c.Loop.Keepalive(time.NewTicker(10 * time.Second))
+	keepalive := time.NewTicker(c.keepaliveInterval)
+	defer keepalive.Stop()
+	// fires if pong of last ping is not received in time, nil if not waiting
+	var pongTimeout <-chan time.Time
+	var pongTimer *time.Timer
+	var pongDeadline time.Time
+	defer func() {
+		if pongTimer != nil {
+			pongTimer.Stop()
+		}
+	}()
 
-	errC := c.Loop.ErrC()
-	messageC, err := c.Loop.ReadC()
-	if err != nil {
-		return err, nil, nil
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errC:
+			if loop.State() != RTMLoopStateOpen {
+				return err
+			}
+			c.fail(err)
+		case <-keepalive.C:
+			if pongTimeout != nil {
+				continue
+			}
+			if err := loop.Ping(); err != nil {
+				return errors.Wrap(err, "keepalive failed")
+			}
+			pongDeadline = time.Now().Add(c.pongTimeout)
+			pongTimer = time.NewTimer(c.pongTimeout)
+			pongTimeout = pongTimer.C
+		case <-pongTimeout:
+			return errors.New("keepalive failed: pong timeout")
+		case m, ok := <-messageC:
+			if !ok {
+				// closed after connection is lost, with the error sent first
+				select {
+				case err := <-errC:
+					return err
+				default:
+					return ErrRTMLoopClosed
+				}
+			}
+			if m.Type() == RTMMessageTypePong && pongTimeout != nil {
+				pongTimer.Stop()
+				pongTimeout = nil
+			}
+
+			start := time.Now()
+			c.dispatch(ctx, m)
+			if pongTimeout != nil {
+				// time in handlers is not counted, the pong may be queued behind
+				pongTimer.Stop()
+				pongDeadline = pongDeadline.Add(time.Since(start))
+				pongTimer = time.NewTimer(time.Until(pongDeadline))
+				pongTimeout = pongTimer.C
+			}
+		}
+	}
+}
+
+func (c *RTMContext) dispatch(ctx context.Context, m RTMMessage) {
+	c.lock.RLock()
+	handlers := append([]rtmHandler{}, c.handlers...)
+	c.lock.RUnlock()
+
+	var matched []RTMHandlerFunc
+	for _, h := range handlers {
+		if h.match(c, m) {
+			matched = append(matched, h.handler)
+		}
+	}
+	// messages without handlers (e.g. pongs) are not traced
+	if len(matched) == 0 {
+		return
 	}
 
-	return nil, messageC, errC
+	ctx, span := c.tracer.Start(
+		ctx,
+		"bearychat.rtm.handle",
+		trace.Attr("type", string(m.Type())),
+		trace.Attr("uid", m["uid"]),
+		trace.Attr("vchannel_id", m["vchannel_id"]),
+	)
+	defer span.End()
+
+	for _, handler := range matched {
+		handler(ctx, c, m)
+	}
+}
+
+func (c *RTMContext) fail(err error) {
+	select {
+	case c.errC <- err:
+	default:
+	}
 }
@@ -0,0 +1,235 @@
+package bearychat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRTMContext_Run(t *testing.T) {
+	var starts, rejected int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&rejected) == 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code":1,"error":"invalid token"}`))
+			return
+		}
+		n := atomic.AddInt32(&starts, 1)
+		fmt.Fprintf(w, `{"code":0,"result":{"user":{"id":"=bot"},"ws_host":"ws://rtm/%d"}}`, n)
+	}))
+	defer server.Close()
+
+	loops := make(chan *testRTMLoop, 10)
+	c, err := NewRTMContext(
+		testRTMToken,
+		WithRTMContextAPIBase(server.URL),
+		WithRTMContextBackoff(time.Millisecond, time.Millisecond),
+		WithRTMContextLoop(func(wsHost string) (RTMLoop, error) {
+			loop := newTestRTMLoop()
+			loops <- loop
+			return loop, nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if c.UID() != "=bot" || c.Loop() != nil {
+		t.Errorf("unexpected context: %+v", c)
+	}
+
+	connected := make(chan struct{}, 10)
+	disconnected := make(chan error, 10)
+	c.OnConnect(func(c *RTMContext) { connected <- struct{}{} })
+	c.OnDisconnect(func(c *RTMContext, err error) { disconnected <- err })
+
+	handled := make(chan RTMMessage, 10)
+	c.HandleChat(func(ctx context.Context, c *RTMContext, m RTMMessage) {
+		c.Send(m.Reply("pong"))
+		handled <- m
+	})
+	c.Handle(RTMMessageTypeUpdateUserConnection, func(ctx context.Context, c *RTMContext, m RTMMessage) {
+		handled <- m
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- c.Run(ctx) }()
+
+	loop := <-loops
+	<-connected
+	loop.push(`{"type":"message","uid":"=bot","vchannel_id":"=vc1","text":"self"}`)
+	loop.push(`{"type":"message","uid":"=u1","vchannel_id":"=vc1","text":"ping"}`)
+	loop.push(`{"type":"update_user_connection","data":{"uid":"=u1"}}`)
+	if m := <-handled; m["text"] != "ping" {
+		t.Errorf("unexpected handled message: %+v", m)
+	}
+	if m := <-handled; m.Type() != RTMMessageTypeUpdateUserConnection {
+		t.Errorf("unexpected handled message: %+v", m)
+	}
+	loop.lock.Lock()
+	if len(loop.sent) != 1 || loop.sent[0]["text"] != "pong" {
+		t.Errorf("unexpected sent: %+v", loop.sent)
+	}
+	loop.lock.Unlock()
+
+	// lost connection
+	loop.Stop()
+	loop.errC <- errors.New("read socket failed")
+	if err := <-disconnected; err == nil {
+		t.Errorf("expected disconnect error")
+	}
+	loop = <-loops
+	<-connected
+	if n := atomic.LoadInt32(&starts); n != 2 {
+		t.Errorf("expected rtm.start on reconnect, got %d", n)
+	}
+	if c.Loop() != RTMLoop(loop) {
+		t.Errorf("expected current loop updated")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+	if err := <-disconnected; err != nil {
+		t.Errorf("unexpected disconnect error: %+v", err)
+	}
+	if loop.State() != RTMLoopStateClosed {
+		t.Errorf("expected loop stopped")
+	}
+
+	// rejected on reconnect
+	atomic.StoreInt32(&rejected, 1)
+	if err := c.Run(context.Background()); err == nil {
+		t.Errorf("expected error for rejected token")
+	}
+	if _, err := NewRTMContext(testRTMToken, WithRTMContextAPIBase(server.URL)); err == nil {
+		t.Errorf("expected error for rejected token")
+	}
+}
+
+func TestRTMContext_Run_Reconnect(t *testing.T) {
+	var starts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&starts, 1)
+		if n == 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"code":1,"error":"service unavailable"}`))
+			return
+		}
+		fmt.Fprintf(w, `{"code":0,"result":{"user":{"id":"=bot"},"ws_host":"ws://rtm/%d"}}`, n)
+	}))
+	defer server.Close()
+
+	loops := make(chan *testRTMLoop, 10)
+	c, _ := NewRTMContext(
+		testRTMToken,
+		WithRTMContextAPIBase(server.URL),
+		WithRTMContextBackoff(time.Millisecond, time.Millisecond),
+		WithRTMContextKeepalive(5*time.Millisecond),
+		WithRTMContextPongTimeout(5*time.Millisecond),
+		WithRTMContextLoop(func(wsHost string) (RTMLoop, error) {
+			loop := newTestRTMLoop()
+			select {
+			case loops <- loop:
+			default:
+			}
+			return loop, nil
+		}),
+	)
+	disconnected := make(chan error, 1)
+	c.OnDisconnect(func(c *RTMContext, err error) {
+		select {
+		case disconnected <- err:
+		default:
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- c.Run(ctx) }()
+
+	// pings are never answered
+	<-loops
+	if err := <-disconnected; err == nil || err.Error() != "keepalive failed: pong timeout" {
+		t.Errorf("expected pong timeout, got %+v", err)
+	}
+
+	// transient rtm.start failure is retried
+	select {
+	case <-loops:
+	case <-time.After(time.Second):
+		t.Fatalf("expected reconnected")
+	}
+	if n := atomic.LoadInt32(&starts); n < 3 {
+		t.Errorf("expected rtm.start retried, got %d", n)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+}
+
+func TestRTMContext_Run_SlowHandler(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":0,"result":{"user":{"id":"=bot"},"ws_host":"ws://rtm"}}`))
+	}))
+	defer server.Close()
+
+	loops := make(chan *testRTMLoop, 10)
+	c, _ := NewRTMContext(
+		testRTMToken,
+		WithRTMContextAPIBase(server.URL),
+		WithRTMContextKeepalive(5*time.Millisecond),
+		WithRTMContextPongTimeout(20*time.Millisecond),
+		WithRTMContextLoop(func(wsHost string) (RTMLoop, error) {
+			loop := newTestRTMLoop()
+			loops <- loop
+			return loop, nil
+		}),
+	)
+	disconnected := make(chan error, 10)
+	c.OnDisconnect(func(c *RTMContext, err error) { disconnected <- err })
+	c.HandleChat(func(ctx context.Context, c *RTMContext, m RTMMessage) {
+		time.Sleep(60 * time.Millisecond)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- c.Run(ctx) }()
+
+	loop := <-loops
+	for pinged := false; !pinged; {
+		time.Sleep(time.Millisecond)
+		loop.lock.Lock()
+		pinged = len(loop.sent) > 0
+		loop.lock.Unlock()
+	}
+	loop.push(`{"type":"message","uid":"=u1","vchannel_id":"=vc1","text":"slow"}`)
+
+	// pong arrives during the handler, and is read after it
+	ponged := make(chan struct{})
+	go func() {
+		loop.push(`{"type":"pong"}`)
+		close(ponged)
+	}()
+	select {
+	case <-ponged:
+	case err := <-disconnected:
+		t.Fatalf("slow handler should not time out pong: %+v", err)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+}
@@ -1,5 +1,7 @@
 package bearychat
 
+import "context"
+
 type RTMCurrentTeamService struct {
 	rtm *RTMClient
 }
@@ -12,21 +14,36 @@ func newRTMCurrentTeamService(rtm *RTMClient) error {
 
 // Retrieves current team's information.
 func (s *RTMCurrentTeamService) Info() (*Team, error) {
+	return s.InfoContext(context.Background())
+}
+
+// Retrieves current team's information with ctx.
+func (s *RTMCurrentTeamService) InfoContext(ctx context.Context) (*Team, error) {
 	team := new(Team)
-	_, err := s.rtm.Get("v1/current_team.info", team)
+	_, err := s.rtm.GetContext(ctx, "v1/current_team.info", team)
 	return team, err
 }
 
 // Retrieves current team's members.
 func (s *RTMCurrentTeamService) Members() ([]*User, error) {
+	return s.MembersContext(context.Background())
+}
+
+// Retrieves current team's members with ctx.
+func (s *RTMCurrentTeamService) MembersContext(ctx context.Context) ([]*User, error) {
 	members := []*User{}
-	_, err := s.rtm.Get("v1/current_team.members?all=true", &members)
+	_, err := s.rtm.GetContext(ctx, "v1/current_team.members?all=true", &members)
 	return members, err
 }
 
 // Retrieves current team's channels.
 func (s *RTMCurrentTeamService) Channels() ([]*Channel, error) {
+	return s.ChannelsContext(context.Background())
+}
+
+// Retrieves current team's channels with ctx.
+func (s *RTMCurrentTeamService) ChannelsContext(ctx context.Context) ([]*Channel, error) {
 	channels := []*Channel{}
-	_, err := s.rtm.Get("v1/current_team.channels", &channels)
+	_, err := s.rtm.GetContext(ctx, "v1/current_team.channels", &channels)
 	return channels, err
 }
@@ -0,0 +1,319 @@
+package bearychat
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nanmu42/bearychat-go/openapi"
+	"github.com/pkg/errors"
+)
+
+const (
+	DEFAULT_DIRECTORY_TTL = 10 * time.Minute
+)
+
+// Directory is an in-memory user/channel cache of current team.
+//
+// It bootstraps from `current_team.members` and `current_team.channels`,
+// refreshes every TTL once started, and keeps user presence updated
+// from `update_user_connection` messages fed to HandleMessage.
+// It's safe for concurrent use.
+//
+//      dir, _ := NewDirectory(rtmClient)
+//      dir.Start()
+//      defer dir.Stop()
+//
+//      for message := range messageC {
+//              dir.HandleMessage(message)
+//              user, _ := dir.User(message["uid"].(string))
+//      }
+type Directory struct {
+	team *RTMCurrentTeamService
+	ttl  time.Duration
+	p2p  openapi.P2PAPI
+
+	lock               sync.RWMutex // lock for properties below
+	users              map[string]*User
+	usersByName        map[string]*User
+	usersByEmail       map[string]*User
+	usersByVChannel    map[string]*User
+	channels           map[string]*Channel
+	channelsByName     map[string]*Channel
+	channelsByVChannel map[string]*Channel
+	p2pVChannels       map[string]string // vchannel ids of p2p created, by user id
+	refreshedAt        time.Time
+	stopC              chan struct{}
+	doneC              chan struct{}
+
+	errC chan error
+}
+
+type directorySetter func(*Directory) error
+
+// WithDirectoryTTL sets refresh interval.
+func WithDirectoryTTL(ttl time.Duration) directorySetter {
+	return func(d *Directory) error {
+		if ttl <= 0 {
+			return errors.New("directory ttl should be positive")
+		}
+		d.ttl = ttl
+		return nil
+	}
+}
+
+// WithDirectoryP2P sets openapi p2p service, e.g. `client.P2P`, creating
+// p2p vchannel when resolving users having none yet.
+func WithDirectoryP2P(p2p openapi.P2PAPI) directorySetter {
+	return func(d *Directory) error {
+		d.p2p = p2p
+		return nil
+	}
+}
+
+// NewDirectory creates a directory and loads it from rtm client.
+func NewDirectory(client *RTMClient, setters ...directorySetter) (*Directory, error) {
+	d := &Directory{
+		team: client.CurrentTeam,
+		ttl:  DEFAULT_DIRECTORY_TTL,
+
+		p2pVChannels: map[string]string{},
+
+		errC: make(chan error, 1024),
+	}
+	for _, setter := range setters {
+		if err := setter(d); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := d.Refresh(); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Refresh reloads users and channels.
+func (d *Directory) Refresh() error {
+	members, err := d.team.Members()
+	if err != nil {
+		return errors.Wrap(err, "load members failed")
+	}
+	channels, err := d.team.Channels()
+	if err != nil {
+		return errors.Wrap(err, "load channels failed")
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.users = make(map[string]*User, len(members))
+	d.usersByName = make(map[string]*User, len(members))
+	d.usersByEmail = make(map[string]*User, len(members))
+	d.usersByVChannel = make(map[string]*User, len(members))
+	for _, u := range members {
+		d.users[u.Id] = u
+		d.usersByName[u.Name] = u
+		if u.Email != "" {
+			d.usersByEmail[strings.ToLower(u.Email)] = u
+		}
+		if u.VChannelId != "" {
+			d.usersByVChannel[u.VChannelId] = u
+		}
+	}
+
+	d.channels = make(map[string]*Channel, len(channels))
+	d.channelsByName = make(map[string]*Channel, len(channels))
+	d.channelsByVChannel = make(map[string]*Channel, len(channels))
+	for _, c := range channels {
+		d.channels[c.Id] = c
+		d.channelsByName[c.Name] = c
+		if c.VChannelId != "" {
+			d.channelsByVChannel[c.VChannelId] = c
+		}
+	}
+
+	d.refreshedAt = time.Now()
+	return nil
+}
+
+// RefreshedAt returns last refresh time.
+func (d *Directory) RefreshedAt() time.Time {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	return d.refreshedAt
+}
+
+// Start refreshes directory every TTL in background.
+// Refresh errors are sent to ErrC.
+func (d *Directory) Start() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.stopC != nil {
+		return
+	}
+	d.stopC = make(chan struct{})
+	d.doneC = make(chan struct{})
+
+	go d.loop(d.stopC, d.doneC)
+}
+
+// Stop stops background refreshing.
+func (d *Directory) Stop() {
+	d.lock.Lock()
+	stopC, doneC := d.stopC, d.doneC
+	d.stopC, d.doneC = nil, nil
+	d.lock.Unlock()
+
+	if stopC != nil {
+		close(stopC)
+		<-doneC
+	}
+}
+
+// ErrC returns error channel for background refreshing.
+func (d *Directory) ErrC() chan error {
+	return d.errC
+}
+
+func (d *Directory) loop(stopC, doneC chan struct{}) {
+	defer close(doneC)
+
+	ticker := time.NewTicker(d.ttl)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopC:
+			return
+		case <-ticker.C:
+			if err := d.Refresh(); err != nil {
+				select {
+				case d.errC <- err:
+				default:
+				}
+			}
+		}
+	}
+}
+
+// rtm `update_user_connection` message
+type updateUserConnection struct {
+	Data struct {
+		Connection string `json:"connection"`
+		UID        string `json:"uid"`
+	} `json:"data"`
+}
+
+// HandleMessage updates user presence from `update_user_connection` message,
+// other messages are ignored.
+func (d *Directory) HandleMessage(m RTMMessage) {
+	if m.Type() != RTMMessageTypeUpdateUserConnection {
+		return
+	}
+
+	rawMessage, ok := m[JSONRawTag].([]byte)
+	if !ok {
+		return
+	}
+	var update updateUserConnection
+	if err := json.Unmarshal(rawMessage, &update); err != nil {
+		return
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if u, present := d.users[update.Data.UID]; present {
+		u.Conn = update.Data.Connection
+	}
+}
+
+// User looks up user by id.
+func (d *Directory) User(id string) (*User, bool) {
+	return d.lookupUser(func() map[string]*User { return d.users }, id)
+}
+
+// UserByName looks up user by name, with or without leading `@`.
+func (d *Directory) UserByName(name string) (*User, bool) {
+	return d.lookupUser(func() map[string]*User { return d.usersByName }, strings.TrimPrefix(name, "@"))
+}
+
+// UserByEmail looks up user by email, case insensitive.
+func (d *Directory) UserByEmail(email string) (*User, bool) {
+	return d.lookupUser(func() map[string]*User { return d.usersByEmail }, strings.ToLower(email))
+}
+
+// UserByVChannel looks up user by p2p vchannel id.
+func (d *Directory) UserByVChannel(vchannelID string) (*User, bool) {
+	return d.lookupUser(func() map[string]*User { return d.usersByVChannel }, vchannelID)
+}
+
+// Users returns all users.
+func (d *Directory) Users() []User {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	users := make([]User, 0, len(d.users))
+	for _, u := range d.users {
+		users = append(users, *u)
+	}
+	return users
+}
+
+// Channel looks up channel by id.
+func (d *Directory) Channel(id string) (*Channel, bool) {
+	return d.lookupChannel(func() map[string]*Channel { return d.channels }, id)
+}
+
+// ChannelByName looks up channel by name, with or without leading `#`.
+func (d *Directory) ChannelByName(name string) (*Channel, bool) {
+	return d.lookupChannel(func() map[string]*Channel { return d.channelsByName }, strings.TrimPrefix(name, "#"))
+}
+
+// ChannelByVChannel looks up channel by vchannel id.
+func (d *Directory) ChannelByVChannel(vchannelID string) (*Channel, bool) {
+	return d.lookupChannel(func() map[string]*Channel { return d.channelsByVChannel }, vchannelID)
+}
+
+// Channels returns all channels.
+func (d *Directory) Channels() []Channel {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	channels := make([]Channel, 0, len(d.channels))
+	for _, c := range d.channels {
+		channels = append(channels, *c)
+	}
+	return channels
+}
+
+// lookupUser returns a copy, entries are updated in place.
+// Index is selected under lock as maps are replaced on refresh.
+func (d *Directory) lookupUser(index func() map[string]*User, key string) (*User, bool) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	u, present := index()[key]
+	if !present {
+		return nil, false
+	}
+	user := *u
+	return &user, true
+}
+
+func (d *Directory) lookupChannel(index func() map[string]*Channel, key string) (*Channel, bool) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	c, present := index()[key]
+	if !present {
+		return nil, false
+	}
+	channel := *c
+	return &channel, true
+}
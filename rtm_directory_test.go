@@ -0,0 +1,108 @@
+package bearychat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func newTestDirectoryServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/current_team.members":
+			w.Write([]byte(`{"code":0,"result":[
+				{"id":"=bw52O","name":"alice","email":"Alice@example.com","vchannel_id":"=vc1","conn":"offline"},
+				{"id":"=bw52P","name":"bob","email":"bob@example.com","vchannel_id":"=vc2","conn":"connected"}
+			]}`))
+		case "/v1/current_team.channels":
+			w.Write([]byte(`{"code":0,"result":[
+				{"id":"=ch1","name":"ops","vchannel_id":"=vc3"}
+			]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code":1,"error":"not found"}`))
+		}
+	}))
+}
+
+func TestDirectory_Lookup(t *testing.T) {
+	server := newTestDirectoryServer()
+	defer server.Close()
+
+	client, _ := NewRTMClient(testRTMToken, WithRTMAPIBase(server.URL))
+	d, err := NewDirectory(client)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if u, ok := d.User("=bw52O"); !ok || u.Name != "alice" {
+		t.Errorf("unexpected user: %+v", u)
+	}
+	if u, ok := d.UserByName("@bob"); !ok || u.Id != "=bw52P" {
+		t.Errorf("unexpected user: %+v", u)
+	}
+	if u, ok := d.UserByEmail("alice@EXAMPLE.com"); !ok || u.Id != "=bw52O" {
+		t.Errorf("unexpected user: %+v", u)
+	}
+	if u, ok := d.UserByVChannel("=vc2"); !ok || u.Id != "=bw52P" {
+		t.Errorf("unexpected user: %+v", u)
+	}
+	if _, ok := d.User("=missing"); ok {
+		t.Errorf("unexpected user")
+	}
+	if c, ok := d.ChannelByName("#ops"); !ok || c.Id != "=ch1" {
+		t.Errorf("unexpected channel: %+v", c)
+	}
+	if c, ok := d.ChannelByVChannel("=vc3"); !ok || c.Name != "ops" {
+		t.Errorf("unexpected channel: %+v", c)
+	}
+	if len(d.Users()) != 2 || len(d.Channels()) != 1 {
+		t.Errorf("unexpected users/channels: %+v %+v", d.Users(), d.Channels())
+	}
+}
+
+func TestDirectory_HandleMessage(t *testing.T) {
+	server := newTestDirectoryServer()
+	defer server.Close()
+
+	client, _ := NewRTMClient(testRTMToken, WithRTMAPIBase(server.URL))
+	d, _ := NewDirectory(client)
+
+	u, _ := d.User("=bw52O")
+	u.Name = "changed"
+	if u, _ := d.User("=bw52O"); u.Name != "alice" {
+		t.Errorf("lookup should return copy: %+v", u)
+	}
+
+	d.HandleMessage(RTMMessage{
+		"type":     RTMMessageTypeUpdateUserConnection,
+		JSONRawTag: []byte(`{"type":"update_user_connection","data":{"connection":"connected","uid":"=bw52O"}}`),
+	})
+	if u, _ := d.User("=bw52O"); !u.IsOnline() {
+		t.Errorf("user should be online: %+v", u)
+	}
+}
+
+func TestDirectory_Race(t *testing.T) {
+	server := newTestDirectoryServer()
+	defer server.Close()
+
+	client, _ := NewRTMClient(testRTMToken, WithRTMAPIBase(server.URL))
+	d, _ := NewDirectory(client)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i = i + 1 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			d.Refresh()
+		}()
+		go func() {
+			defer wg.Done()
+			d.UserByName("alice")
+			d.Channels()
+		}()
+	}
+	wg.Wait()
+}
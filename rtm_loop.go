@@ -1,6 +1,7 @@
 package bearychat
 
 import (
+	"context"
 	"errors"
 	"time"
 )
@@ -35,3 +36,9 @@ type RTMLoop interface {
 	// Get error channel
 	ErrC() chan error
 }
+
+// rtmLoopContextSender is implemented by RTMLoop sending messages with
+// context, like the default one which traces sending.
+type rtmLoopContextSender interface {
+	SendContext(ctx context.Context, m RTMMessage) error
+}
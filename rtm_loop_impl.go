@@ -1,12 +1,16 @@
 package bearychat
 
 import (
+	"context"
 	"encoding/json"
+	"net/url"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/nanmu42/bearychat-go/metrics"
+	"github.com/nanmu42/bearychat-go/trace"
 	"github.com/pkg/errors"
 )
 
@@ -19,10 +23,17 @@ type rtmLoop struct {
 	state  RTMLoopState
 	callId uint64
 	llock  *sync.RWMutex // lock for properties below
+	wlock  sync.Mutex    // lock for socket writing, which is not concurrent safe
 
 	rtmCBacklog int
-	rtmC        chan RTMMessage
+	rtmC        chan RTMMessage // of current connection, closed when its reader exits
+	stopC       chan struct{}   // of current connection, closed on Stop
 	errC        chan error
+
+	logger  Logger
+	metrics metrics.Metrics
+	tracer  trace.Tracer
+	pingAt  int64 // unix nano of last ping waiting for pong
 }
 
 type rtmLoopSetter func(*rtmLoop) error
@@ -35,6 +46,30 @@ func WithRTMLoopBacklog(backlog int) rtmLoopSetter {
 	}
 }
 
+// Set logger for connection and messages, logs nothing by default.
+func WithRTMLoopLogger(logger Logger) rtmLoopSetter {
+	return func(r *rtmLoop) error {
+		r.logger = logger
+		return nil
+	}
+}
+
+// Set metrics for messages, queue depth and ping rtt, records nothing by default.
+func WithRTMLoopMetrics(m metrics.Metrics) rtmLoopSetter {
+	return func(r *rtmLoop) error {
+		r.metrics = m
+		return nil
+	}
+}
+
+// Set tracer for sending messages, traces nothing by default.
+func WithRTMLoopTracer(tracer trace.Tracer) rtmLoopSetter {
+	return func(r *rtmLoop) error {
+		r.tracer = tracer
+		return nil
+	}
+}
+
 func NewRTMLoop(wsHost string, setters ...rtmLoopSetter) (*rtmLoop, error) {
 	l := &rtmLoop{
 		wsHost: wsHost,
@@ -43,6 +78,10 @@ func NewRTMLoop(wsHost string, setters ...rtmLoopSetter) (*rtmLoop, error) {
 		llock:  &sync.RWMutex{},
 
 		errC: make(chan error, 1024),
+
+		logger:  nopLogger,
+		metrics: metrics.Nop,
+		tracer:  trace.Nop,
 	}
 	for _, setter := range setters {
 		if err := setter(l); err != nil {
@@ -50,12 +89,6 @@ func NewRTMLoop(wsHost string, setters ...rtmLoopSetter) (*rtmLoop, error) {
 		}
 	}
 
-	if l.rtmCBacklog <= 0 {
-		l.rtmC = make(chan RTMMessage)
-	} else {
-		l.rtmC = make(chan RTMMessage, l.rtmCBacklog)
-	}
-
 	return l, nil
 }
 
@@ -63,21 +96,40 @@ func (l *rtmLoop) Start() error {
 	l.llock.Lock()
 	defer l.llock.Unlock()
 
+	l.logger.Debug("rtm dialing", "host", l.host())
 	conn, _, err := websocket.DefaultDialer.Dial(l.wsHost, nil)
 	if err != nil {
+		l.logger.Warn("rtm dial failed", "host", l.host(), "error", err)
 		return err
 	}
 
 	l.conn = conn
 	l.state = RTMLoopStateOpen
+	backlog := l.rtmCBacklog
+	if backlog < 0 {
+		backlog = 0
+	}
+	l.rtmC = make(chan RTMMessage, backlog)
+	l.stopC = make(chan struct{})
+	l.logger.Info("rtm connected", "host", l.host())
 
-	go l.readMessage()
+	go l.readMessage(conn, l.rtmC, l.stopC)
 
 	return nil
 }
 
 func (l *rtmLoop) Stop() error {
-	return nil
+	l.llock.Lock()
+	defer l.llock.Unlock()
+
+	if l.state == RTMLoopStateClosed {
+		return nil
+	}
+	l.state = RTMLoopStateClosed
+	close(l.stopC)
+	l.logger.Info("rtm disconnected", "host", l.host())
+
+	return l.conn.Close()
 }
 
 func (l *rtmLoop) State() RTMLoopState {
@@ -88,15 +140,22 @@ func (l *rtmLoop) State() RTMLoopState {
 }
 
 func (l *rtmLoop) Ping() error {
+	atomic.StoreInt64(&l.pingAt, time.Now().UnixNano())
 	return l.Send(RTMMessage{"type": RTMMessageTypePing})
 }
 
 func (l *rtmLoop) Keepalive(interval *time.Ticker) error {
+	return keepalive(l.Ping, interval)
+}
+
+// keepalive pings every interval until ping fails, shared by RTMLoop
+// implementations. Closes ticker before return.
+func keepalive(ping func() error, interval *time.Ticker) error {
 	defer interval.Stop()
 	for {
 		select {
 		case <-interval.C:
-			if err := l.Ping(); err != nil {
+			if err := ping(); err != nil {
 				return errors.Wrap(err, "keepalive closed")
 			}
 		}
@@ -104,6 +163,20 @@ func (l *rtmLoop) Keepalive(interval *time.Ticker) error {
 }
 
 func (l *rtmLoop) Send(m RTMMessage) error {
+	return l.SendContext(context.Background(), m)
+}
+
+// SendContext sends a message, traced as child span of ctx.
+func (l *rtmLoop) SendContext(ctx context.Context, m RTMMessage) (err error) {
+	_, span := l.tracer.Start(ctx, "bearychat.rtm.send", trace.Attr("type", string(m.Type())))
+	defer func() {
+		span.SetAttributes(trace.Attr("call_id", m["call_id"]))
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
+	}()
+
 	if l.State() != RTMLoopStateOpen {
 		return ErrRTMLoopClosed
 	}
@@ -114,18 +187,30 @@ func (l *rtmLoop) Send(m RTMMessage) error {
 
 	rawMessage, err := json.Marshal(m)
 	if err != nil {
+		l.logger.Warn("rtm message encode failed", "type", m.Type(), "error", err)
 		return errors.Wrap(err, "encode message failed")
 	}
 
+	l.wlock.Lock()
+	defer l.wlock.Unlock()
 	if err := l.conn.WriteMessage(websocket.TextMessage, rawMessage); err != nil {
+		l.logger.Warn("rtm message send failed", "type", m.Type(), "call_id", m["call_id"], "error", err)
+		l.metrics.Add("bearychat_rtm_send_failures_total", 1, "type", string(m.Type()))
 		return errors.Wrap(err, "write socket failed")
 	}
+	l.logger.Debug("rtm message sent", "type", m.Type(), "call_id", m["call_id"])
+	l.metrics.Add("bearychat_rtm_messages_sent_total", 1, "type", string(m.Type()))
 
 	return nil
 }
 
+// ReadC returns message chan of current connection, which is closed
+// after the connection is stopped or lost.
 func (l *rtmLoop) ReadC() (chan RTMMessage, error) {
-	if l.State() != RTMLoopStateOpen {
+	l.llock.RLock()
+	defer l.llock.RUnlock()
+
+	if l.state != RTMLoopStateOpen {
 		return nil, ErrRTMLoopClosed
 	}
 
@@ -136,29 +221,70 @@ func (l *rtmLoop) ErrC() chan error {
 	return l.errC
 }
 
-// Listen & read message from BearyChat
-func (l *rtmLoop) readMessage() {
+// Listen & read message from BearyChat, until conn is stopped or lost.
+func (l *rtmLoop) readMessage(conn *websocket.Conn, rtmC chan RTMMessage, stopC chan struct{}) {
+	defer close(rtmC)
+
 	for {
 		if l.State() == RTMLoopStateClosed {
 			return
 		}
 
-		_, rawMessage, err := l.conn.ReadMessage()
+		_, rawMessage, err := conn.ReadMessage()
 		if err != nil {
-			l.errC <- errors.Wrap(err, "read socket failed")
-			continue
+			// connection is unusable after a read failure
+			if l.State() == RTMLoopStateClosed {
+				return
+			}
+			l.llock.Lock()
+			l.state = RTMLoopStateClosed
+			l.llock.Unlock()
+
+			l.logger.Error("rtm connection lost", "host", l.host(), "error", err)
+			l.fail(errors.Wrap(err, "read socket failed"))
+			return
 		}
 
 		message := RTMMessage{}
 		if err = json.Unmarshal(rawMessage, &message); err != nil {
-			l.errC <- errors.Wrap(err, "decode message failed")
+			l.logger.Warn("rtm message decode failed", "error", err)
+			l.fail(errors.Wrap(err, "decode message failed"))
 			continue
 		}
+		l.logger.Debug("rtm message received", "type", message.Type())
+		l.metrics.Add("bearychat_rtm_messages_received_total", 1, "type", string(message.Type()))
+		if message.Type() == RTMMessageTypePong {
+			if pingAt := atomic.SwapInt64(&l.pingAt, 0); pingAt != 0 {
+				l.metrics.Observe("bearychat_rtm_ping_rtt_seconds", time.Since(time.Unix(0, pingAt)).Seconds())
+			}
+		}
 
 		// store raw message for later use
 		message[JSONRawTag] = rawMessage
-		l.rtmC <- message
+		select {
+		case rtmC <- message:
+		case <-stopC:
+			// nobody reads after Stop
+			return
+		}
+		l.metrics.Set("bearychat_rtm_queue_depth", float64(len(rtmC)))
+	}
+}
+
+func (l *rtmLoop) fail(err error) {
+	select {
+	case l.errC <- err:
+	default:
+	}
+}
+
+// host returns host of ws host, the url has a connect ticket which is not logged.
+func (l *rtmLoop) host() string {
+	u, err := url.Parse(l.wsHost)
+	if err != nil {
+		return ""
 	}
+	return u.Host
 }
 
 func (l *rtmLoop) advanceCallId() uint64 {
@@ -1,8 +1,14 @@
 package bearychat
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
 )
 
 const (
@@ -90,3 +96,47 @@ func TestRTMLoop_advanceCallId_Race(t *testing.T) {
 		t.Errorf("unexepcted call id after data race: %d", l.callId)
 	}
 }
+
+func TestRTMLoop_Stop_Unread(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var upgrader websocket.Upgrader
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for i := 0; i < 2; i = i + 1 {
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"message","text":"unread"}`))
+		}
+		conn.ReadMessage()
+	}))
+	defer server.Close()
+
+	l, err := NewRTMLoop("ws" + strings.TrimPrefix(server.URL, "http"))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if err := l.Start(); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	messageC, err := l.ReadC()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	// reader is blocked on sending the first message
+	time.Sleep(50 * time.Millisecond)
+	l.Stop()
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-messageC:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("expected message chan closed after Stop")
+		}
+	}
+}
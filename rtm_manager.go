@@ -0,0 +1,479 @@
+package bearychat
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DEFAULT_RTM_MANAGER_EVENT_BACKLOG = 1024
+	DEFAULT_RTM_BOT_RESTART_BACKOFF   = 5 * time.Second
+)
+
+var (
+	ErrRTMBotExists      = errors.New("rtm bot already exists")
+	ErrRTMBotNotFound    = errors.New("rtm bot not found")
+	ErrRTMManagerStopped = errors.New("rtm manager is stopped")
+)
+
+// RTMBotState is state of a bot in RTMManager.
+type RTMBotState string
+
+const (
+	RTMBotStateStarting     RTMBotState = "starting"
+	RTMBotStateConnected    RTMBotState = "connected"
+	RTMBotStateDisconnected RTMBotState = "disconnected"
+	RTMBotStateRestarting   RTMBotState = "restarting"
+	RTMBotStateFailed       RTMBotState = "failed"
+	RTMBotStateStopped      RTMBotState = "stopped"
+)
+
+// RTMEventType is type of RTMEvent.
+type RTMEventType string
+
+const (
+	RTMEventMessage      RTMEventType = "message"
+	RTMEventConnected    RTMEventType = "connected"
+	RTMEventDisconnected RTMEventType = "disconnected"
+	RTMEventError        RTMEventType = "error"
+	// Bot gave up restarting by its restart policy
+	RTMEventFailed  RTMEventType = "failed"
+	RTMEventStopped RTMEventType = "stopped"
+)
+
+// RTMEvent is an event of a bot in RTMManager.
+type RTMEvent struct {
+	Type RTMEventType
+	// Token of the bot
+	Token string
+	// Team of the bot, nil if bot failed before getting it
+	Team *Team
+	// Set for RTMEventMessage
+	Message RTMMessage
+	// Set for RTMEventError, RTMEventFailed, and RTMEventDisconnected unless stopped
+	Err error
+}
+
+// RTMRestartPolicy decides how a bot is restarted when it fails, e.g.
+// rtm.start is rejected or a handler panics.
+type RTMRestartPolicy struct {
+	// Restarts in a row without connecting before giving up,
+	// negative for always restarting.
+	MaxRestarts int
+	// Wait before restarting.
+	Backoff time.Duration
+}
+
+// RTMBotHealth is health report of a bot.
+type RTMBotHealth struct {
+	Token           string
+	Team            *Team
+	State           RTMBotState
+	Restarts        int
+	Connects        int
+	LastConnectedAt time.Time
+	LastMessageAt   time.Time
+	LastError       error
+	// Events dropped as event stream is full
+	DroppedEvents int
+}
+
+type rtmBot struct {
+	token          string
+	policy         RTMRestartPolicy
+	cancel         context.CancelFunc
+	done           chan struct{}
+	contextSetters []rtmContextSetter
+
+	lock   sync.Mutex // lock for health
+	health RTMBotHealth
+}
+
+// RTMManager runs a bot per token, e.g. one per team:
+//
+//      manager, _ := NewRTMManager()
+//      manager.HandleChat(func(ctx context.Context, c *RTMContext, m RTMMessage) {
+//              c.Send(m.Refer("hi"))
+//      })
+//      manager.Add("rtm-token-team-a")
+//      manager.Add("rtm-token-team-b", WithRTMContextKeepalive(5*time.Second))
+//
+//      for event := range manager.Events() {
+//              log.Printf("%s %s: %+v", event.Team.Name, event.Type, event.Err)
+//      }
+//
+// Bots share handlers, and are restarted by their own restart policy when
+// they fail. Handler panics are recovered and reported as RTMEventError.
+// Bots giving up by their restart policy are removed after RTMEventFailed,
+// and can be added again.
+// Events is closed once the manager is stopped by Stop.
+type RTMManager struct {
+	policy         RTMRestartPolicy
+	contextSetters []rtmContextSetter
+	stopOnce       sync.Once
+
+	lock     sync.RWMutex // lock for properties below
+	bots     map[string]*rtmBot
+	handlers []rtmHandler
+	stopped  bool
+
+	// running bots, eventC is closed after they are done
+	running sync.WaitGroup
+	eventC  chan RTMEvent
+}
+
+type rtmManagerSetter func(*RTMManager) error
+
+// WithRTMManagerRestartPolicy sets default restart policy of bots,
+// which restarts always after DEFAULT_RTM_BOT_RESTART_BACKOFF by default.
+func WithRTMManagerRestartPolicy(policy RTMRestartPolicy) rtmManagerSetter {
+	return func(m *RTMManager) error {
+		m.policy = policy
+		return nil
+	}
+}
+
+// WithRTMManagerContext sets options of every bot's RTMContext.
+func WithRTMManagerContext(setters ...rtmContextSetter) rtmManagerSetter {
+	return func(m *RTMManager) error {
+		m.contextSetters = append(m.contextSetters, setters...)
+		return nil
+	}
+}
+
+// WithRTMManagerEventBacklog sets event stream backlog,
+// events are dropped when it's full.
+func WithRTMManagerEventBacklog(backlog int) rtmManagerSetter {
+	return func(m *RTMManager) error {
+		if backlog < 0 {
+			return errors.New("event backlog should not be negative")
+		}
+		m.eventC = make(chan RTMEvent, backlog)
+		return nil
+	}
+}
+
+// NewRTMManager creates a manager without bots.
+func NewRTMManager(setters ...rtmManagerSetter) (*RTMManager, error) {
+	m := &RTMManager{
+		policy: RTMRestartPolicy{MaxRestarts: -1, Backoff: DEFAULT_RTM_BOT_RESTART_BACKOFF},
+		bots:   map[string]*rtmBot{},
+		eventC: make(chan RTMEvent, DEFAULT_RTM_MANAGER_EVENT_BACKLOG),
+	}
+	for _, setter := range setters {
+		if err := setter(m); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// Handle registers handler for messages of type mtype of all bots.
+func (m *RTMManager) Handle(mtype RTMMessageType, handler RTMHandlerFunc) {
+	m.handle(matchRTMMessageType(mtype), handler)
+}
+
+// HandleChat registers handler for p2p and channel messages of all bots, not sent by themselves.
+func (m *RTMManager) HandleChat(handler RTMHandlerFunc) {
+	m.handle(matchRTMChatMessage, handler)
+}
+
+// HandleAll registers handler for all messages of all bots.
+func (m *RTMManager) HandleAll(handler RTMHandlerFunc) {
+	m.handle(matchRTMMessage, handler)
+}
+
+func (m *RTMManager) handle(match func(c *RTMContext, m RTMMessage) bool, handler RTMHandlerFunc) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.handlers = append(m.handlers, rtmHandler{match: match, handler: handler})
+}
+
+// Events returns stream of all bots' events, closed after the manager is
+// stopped and all bots' events are sent.
+func (m *RTMManager) Events() <-chan RTMEvent {
+	return m.eventC
+}
+
+// Add starts a bot of token, setters override options of the manager.
+func (m *RTMManager) Add(token string, setters ...rtmContextSetter) error {
+	return m.AddWithPolicy(token, m.policy, setters...)
+}
+
+// AddWithPolicy starts a bot of token with its own restart policy.
+func (m *RTMManager) AddWithPolicy(token string, policy RTMRestartPolicy, setters ...rtmContextSetter) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.stopped {
+		return ErrRTMManagerStopped
+	}
+	if _, exists := m.bots[token]; exists {
+		return ErrRTMBotExists
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	bot := &rtmBot{
+		token:          token,
+		policy:         policy,
+		cancel:         cancel,
+		done:           make(chan struct{}),
+		health:         RTMBotHealth{Token: token, State: RTMBotStateStarting},
+		contextSetters: append(append([]rtmContextSetter{}, m.contextSetters...), setters...),
+	}
+	m.bots[token] = bot
+	m.running.Add(1)
+	go m.supervise(ctx, bot)
+
+	return nil
+}
+
+// Remove stops and removes bot of token, returns after it's stopped.
+func (m *RTMManager) Remove(token string) error {
+	m.lock.Lock()
+	bot, exists := m.bots[token]
+	delete(m.bots, token)
+	m.lock.Unlock()
+
+	if !exists {
+		return ErrRTMBotNotFound
+	}
+	bot.cancel()
+	<-bot.done
+
+	return nil
+}
+
+// Stop stops and removes all bots, and closes Events after they are stopped.
+// Bots can't be added after.
+func (m *RTMManager) Stop() {
+	m.stopOnce.Do(func() {
+		m.lock.Lock()
+		m.stopped = true
+		m.lock.Unlock()
+
+		for _, token := range m.Tokens() {
+			m.Remove(token)
+		}
+
+		// including bots removed or failed meanwhile
+		m.running.Wait()
+		close(m.eventC)
+	})
+}
+
+// Tokens returns tokens of bots.
+func (m *RTMManager) Tokens() []string {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	tokens := make([]string, 0, len(m.bots))
+	for token := range m.bots {
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+// Health returns health of bot of token.
+func (m *RTMManager) Health(token string) (RTMBotHealth, bool) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	bot, exists := m.bots[token]
+	if !exists {
+		return RTMBotHealth{}, false
+	}
+	return bot.healthReport(), true
+}
+
+// HealthAll returns health of all bots, keyed by token.
+func (m *RTMManager) HealthAll() map[string]RTMBotHealth {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	health := map[string]RTMBotHealth{}
+	for token, bot := range m.bots {
+		health[token] = bot.healthReport()
+	}
+	return health
+}
+
+// supervise runs bot, and restarts it by policy until it's removed or
+// it gives up.
+func (m *RTMManager) supervise(ctx context.Context, bot *rtmBot) {
+	defer m.running.Done()
+	defer close(bot.done)
+
+	// restarts in a row without connecting
+	restarts := 0
+	for {
+		connects := bot.connects()
+		err := m.run(ctx, bot)
+		if ctx.Err() != nil || err == nil {
+			m.update(bot, func(h *RTMBotHealth) { h.State = RTMBotStateStopped })
+			m.emit(bot, RTMEvent{Type: RTMEventStopped})
+			return
+		}
+		m.update(bot, func(h *RTMBotHealth) { h.LastError = err })
+		m.emit(bot, RTMEvent{Type: RTMEventError, Err: err})
+
+		if bot.connects() > connects {
+			restarts = 0
+		}
+		if bot.policy.MaxRestarts >= 0 && restarts >= bot.policy.MaxRestarts {
+			m.update(bot, func(h *RTMBotHealth) { h.State = RTMBotStateFailed })
+			m.emit(bot, RTMEvent{Type: RTMEventFailed, Err: err})
+			m.forget(bot)
+			return
+		}
+		restarts = restarts + 1
+		m.update(bot, func(h *RTMBotHealth) {
+			h.State = RTMBotStateRestarting
+			h.Restarts = h.Restarts + 1
+		})
+
+		select {
+		case <-ctx.Done():
+			m.update(bot, func(h *RTMBotHealth) { h.State = RTMBotStateStopped })
+			m.emit(bot, RTMEvent{Type: RTMEventStopped})
+			return
+		case <-time.After(bot.policy.Backoff):
+		}
+	}
+}
+
+// run runs bot once, panics are returned as errors.
+func (m *RTMManager) run(ctx context.Context, bot *rtmBot) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("bot panicked: %v", r)
+		}
+	}()
+
+	c, err := NewRTMContext(bot.token, bot.contextSetters...)
+	if err != nil {
+		return errors.Wrap(err, "create rtm context failed")
+	}
+	team, err := c.Client().CurrentTeam.InfoContext(ctx)
+	if err != nil {
+		return errors.Wrap(err, "get team failed")
+	}
+	m.update(bot, func(h *RTMBotHealth) { h.Team = team })
+
+	c.OnConnect(func(c *RTMContext) {
+		m.update(bot, func(h *RTMBotHealth) {
+			h.State = RTMBotStateConnected
+			h.Connects = h.Connects + 1
+			h.LastConnectedAt = time.Now()
+		})
+		m.emit(bot, RTMEvent{Type: RTMEventConnected})
+	})
+	c.OnDisconnect(func(c *RTMContext, err error) {
+		m.update(bot, func(h *RTMBotHealth) {
+			h.State = RTMBotStateDisconnected
+			if err != nil {
+				h.LastError = err
+			}
+		})
+		m.emit(bot, RTMEvent{Type: RTMEventDisconnected, Err: err})
+	})
+	c.HandleAll(func(ctx context.Context, c *RTMContext, message RTMMessage) {
+		m.update(bot, func(h *RTMBotHealth) { h.LastMessageAt = time.Now() })
+		m.emit(bot, RTMEvent{Type: RTMEventMessage, Message: message})
+		m.dispatch(ctx, bot, c, message)
+	})
+
+	// errors are forwarded until run returns, not after bot is done
+	errDone := make(chan struct{})
+	forwarded := make(chan struct{})
+	defer func() {
+		close(errDone)
+		<-forwarded
+	}()
+	go func() {
+		defer close(forwarded)
+		for {
+			select {
+			case <-errDone:
+				return
+			case err := <-c.ErrC():
+				m.update(bot, func(h *RTMBotHealth) { h.LastError = err })
+				m.emit(bot, RTMEvent{Type: RTMEventError, Err: err})
+			}
+		}
+	}()
+
+	return c.Run(ctx)
+}
+
+func (m *RTMManager) dispatch(ctx context.Context, bot *rtmBot, c *RTMContext, message RTMMessage) {
+	m.lock.RLock()
+	handlers := append([]rtmHandler{}, m.handlers...)
+	m.lock.RUnlock()
+
+	for _, h := range handlers {
+		if !h.match(c, message) {
+			continue
+		}
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					err := errors.Errorf("handler panicked: %v", r)
+					m.update(bot, func(h *RTMBotHealth) { h.LastError = err })
+					m.emit(bot, RTMEvent{Type: RTMEventError, Err: err, Message: message})
+				}
+			}()
+			h.handler(ctx, c, message)
+		}()
+	}
+}
+
+// forget removes bot from the manager, unless it's removed already.
+func (m *RTMManager) forget(bot *rtmBot) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.bots[bot.token] == bot {
+		delete(m.bots, bot.token)
+	}
+}
+
+func (m *RTMManager) update(bot *rtmBot, update func(h *RTMBotHealth)) {
+	bot.lock.Lock()
+	defer bot.lock.Unlock()
+
+	update(&bot.health)
+}
+
+// emit sends event without blocking, dropped events are counted in health.
+func (m *RTMManager) emit(bot *rtmBot, event RTMEvent) {
+	bot.lock.Lock()
+	defer bot.lock.Unlock()
+
+	event.Token = bot.token
+	event.Team = bot.health.Team
+	select {
+	case m.eventC <- event:
+	default:
+		bot.health.DroppedEvents = bot.health.DroppedEvents + 1
+	}
+}
+
+func (bot *rtmBot) connects() int {
+	bot.lock.Lock()
+	defer bot.lock.Unlock()
+
+	return bot.health.Connects
+}
+
+func (bot *rtmBot) healthReport() RTMBotHealth {
+	bot.lock.Lock()
+	defer bot.lock.Unlock()
+
+	return bot.health
+}
@@ -0,0 +1,188 @@
+package bearychat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRTMManager(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if token == "bad" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code":1,"error":"invalid token"}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/start":
+			fmt.Fprintf(w, `{"code":0,"result":{"user":{"id":"=bot-%s"},"ws_host":"ws://rtm/%s"}}`, token, token)
+		case "/v1/current_team.info":
+			fmt.Fprintf(w, `{"code":0,"result":{"id":"=t-%s","name":"team-%s"}}`, token, token)
+		}
+	}))
+	defer server.Close()
+
+	manager, _ := NewRTMManager(
+		WithRTMManagerContext(WithRTMContextAPIBase(server.URL)),
+		WithRTMManagerRestartPolicy(RTMRestartPolicy{MaxRestarts: 1, Backoff: time.Millisecond}),
+	)
+	defer manager.Stop()
+
+	manager.HandleChat(func(ctx context.Context, c *RTMContext, m RTMMessage) {
+		if m["text"] == "panic" {
+			panic("boom")
+		}
+		c.Send(m.Reply("pong"))
+	})
+
+	loops := map[string]chan *testRTMLoop{}
+	for _, token := range []string{"a", "b"} {
+		loopC := make(chan *testRTMLoop, 10)
+		loops[token] = loopC
+		err := manager.Add(token, WithRTMContextLoop(func(wsHost string) (RTMLoop, error) {
+			loop := newTestRTMLoop()
+			loopC <- loop
+			return loop, nil
+		}))
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+	}
+	if err := manager.Add("a"); err != ErrRTMBotExists {
+		t.Errorf("expected ErrRTMBotExists, got %+v", err)
+	}
+
+	next := func(etype RTMEventType) RTMEvent {
+		t.Helper()
+		for {
+			select {
+			case event := <-manager.Events():
+				if event.Type == etype {
+					return event
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("expected %s event", etype)
+			}
+		}
+	}
+
+	connected := map[string]bool{}
+	for len(connected) < 2 {
+		event := next(RTMEventConnected)
+		if event.Team == nil || event.Team.Name != "team-"+event.Token {
+			t.Errorf("unexpected team of %s: %+v", event.Token, event.Team)
+		}
+		connected[event.Token] = true
+	}
+
+	loopB := <-loops["b"]
+	loopB.push(`{"type":"message","uid":"=u1","vchannel_id":"=vc1","text":"panic"}`)
+	if event := next(RTMEventError); event.Token != "b" || !strings.Contains(event.Err.Error(), "boom") {
+		t.Errorf("unexpected error event: %+v", event)
+	}
+	loopB.push(`{"type":"message","uid":"=u1","vchannel_id":"=vc1","text":"ping"}`)
+	if event := next(RTMEventMessage); event.Token != "b" || event.Team.Name != "team-b" || event.Message["text"] != "ping" {
+		t.Errorf("unexpected message event: %+v", event)
+	}
+
+	health, _ := manager.Health("b")
+	if health.State != RTMBotStateConnected || health.Connects != 1 || health.LastMessageAt.IsZero() || health.LastError == nil {
+		t.Errorf("unexpected health: %+v", health)
+	}
+
+	// failures are isolated
+	manager.Add("bad")
+	if event := next(RTMEventFailed); event.Token != "bad" || event.Err == nil {
+		t.Errorf("unexpected failed event: %+v", event)
+	}
+	// failed bots are removed
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, exists := manager.Health("bad"); !exists {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected failed bot removed, got %+v", manager.HealthAll())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if health, _ := manager.Health("a"); health.State != RTMBotStateConnected {
+		t.Errorf("unexpected health: %+v", health)
+	}
+
+	if err := manager.Remove("a"); err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+	if _, exists := manager.Health("a"); exists {
+		t.Errorf("expected bot removed")
+	}
+	if len(manager.HealthAll()) != 1 {
+		t.Errorf("unexpected health: %+v", manager.HealthAll())
+	}
+	if err := manager.Remove("a"); err != ErrRTMBotNotFound {
+		t.Errorf("expected ErrRTMBotNotFound, got %+v", err)
+	}
+
+	manager.Stop()
+	for range manager.Events() {
+	}
+	if err := manager.Add("c"); err != ErrRTMManagerStopped {
+		t.Errorf("expected ErrRTMManagerStopped, got %+v", err)
+	}
+}
+
+func TestRTMManager_Restart(t *testing.T) {
+	var starts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/start":
+			// every other rtm.start is rejected, after the bot connected
+			if atomic.AddInt32(&starts, 1)%2 == 0 {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"code":1,"error":"invalid token"}`))
+				return
+			}
+			w.Write([]byte(`{"code":0,"result":{"user":{"id":"=bot"},"ws_host":"ws://rtm"}}`))
+		case "/v1/current_team.info":
+			w.Write([]byte(`{"code":0,"result":{"id":"=t","name":"team"}}`))
+		}
+	}))
+	defer server.Close()
+
+	manager, _ := NewRTMManager(
+		WithRTMManagerContext(
+			WithRTMContextAPIBase(server.URL),
+			WithRTMContextBackoff(time.Millisecond, time.Millisecond),
+		),
+		WithRTMManagerRestartPolicy(RTMRestartPolicy{MaxRestarts: 1, Backoff: time.Millisecond}),
+	)
+	defer manager.Stop()
+
+	loops := make(chan *testRTMLoop, 10)
+	manager.Add("flaky", WithRTMContextLoop(func(wsHost string) (RTMLoop, error) {
+		loop := newTestRTMLoop()
+		loops <- loop
+		return loop, nil
+	}))
+
+	// restarts in a row are limited, not restarts in total
+	for i := 0; i < 3; i++ {
+		select {
+		case loop := <-loops:
+			loop.Stop()
+			loop.errC <- errors.New("read socket failed")
+		case <-time.After(time.Second):
+			t.Fatalf("expected bot restarted, got %+v", manager.HealthAll())
+		}
+	}
+	if health, _ := manager.Health("flaky"); health.State == RTMBotStateFailed || health.Restarts < 2 {
+		t.Errorf("unexpected health: %+v", health)
+	}
+}
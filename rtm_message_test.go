@@ -3,6 +3,7 @@ package bearychat
 import (
 	"fmt"
 	"testing"
+	"time"
 )
 
 func TestRTMMessage_Type(t *testing.T) {
@@ -347,6 +348,9 @@ func TestRTMMessage_ParseReferredFile(t *testing.T) {
 	if file.ImageURL == "" {
 		t.Fatal("ImageURL is empty")
 	}
+	if created := time.Date(2018, 10, 28, 14, 16, 52, 0, time.UTC); !file.Created.Equal(created) {
+		t.Errorf("expected created %s, got %s", created, file.Created)
+	}
 
 	var msg2 = RTMMessage{
 		JSONRawTag: []byte(`{
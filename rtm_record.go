@@ -0,0 +1,225 @@
+package bearychat
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type RTMRecordDirection string
+
+const (
+	RTMRecordInbound  RTMRecordDirection = "in"
+	RTMRecordOutbound RTMRecordDirection = "out"
+)
+
+// RTMRecord is a line of recorded RTM session.
+type RTMRecord struct {
+	Time      time.Time          `json:"time"`
+	Direction RTMRecordDirection `json:"direction"`
+	Message   json.RawMessage    `json:"message"`
+}
+
+// RTMRecorder wraps a RTMLoop and writes every inbound and outbound message
+// into a JSONL log of RTMRecord, which can be replayed with RTMReplayer.
+//
+//      f, _ := os.Create("session.jsonl")
+//      loop, _ := NewRTMLoop(wsHost)
+//      recorder := NewRTMRecorder(loop, f)
+//      recorder.Start()
+//
+// Recording failures are sent to ErrC.
+type RTMRecorder struct {
+	loop RTMLoop
+
+	wlock sync.Mutex // lock for writer
+	w     io.Writer
+
+	lock sync.Mutex // lock for properties below
+	// of current connection, closed when its tee exits
+	rtmC chan RTMMessage
+	// closed on Stop
+	stopC chan struct{}
+}
+
+// NewRTMRecorder creates a recorder of loop writing to w.
+func NewRTMRecorder(loop RTMLoop, w io.Writer) *RTMRecorder {
+	return &RTMRecorder{
+		loop: loop,
+		w:    w,
+	}
+}
+
+func (r *RTMRecorder) Start() error {
+	if err := r.loop.Start(); err != nil {
+		return err
+	}
+	messageC, err := r.loop.ReadC()
+	if err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.stopC != nil {
+		close(r.stopC)
+	}
+	r.rtmC = make(chan RTMMessage)
+	r.stopC = make(chan struct{})
+	go r.tee(messageC, r.rtmC, r.stopC)
+
+	return nil
+}
+
+func (r *RTMRecorder) Stop() error {
+	r.lock.Lock()
+	if r.stopC != nil {
+		close(r.stopC)
+		r.stopC = nil
+	}
+	r.lock.Unlock()
+
+	return r.loop.Stop()
+}
+
+func (r *RTMRecorder) State() RTMLoopState {
+	return r.loop.State()
+}
+
+func (r *RTMRecorder) Ping() error {
+	return r.Send(RTMMessage{"type": RTMMessageTypePing})
+}
+
+func (r *RTMRecorder) Keepalive(interval *time.Ticker) error {
+	return keepalive(r.Ping, interval)
+}
+
+// Send records message then sends it.
+func (r *RTMRecorder) Send(m RTMMessage) error {
+	if err := r.loop.Send(m); err != nil {
+		return err
+	}
+
+	// call_id is set by loop on sending
+	rawMessage, err := json.Marshal(withoutJSONRaw(m))
+	if err != nil {
+		return errors.Wrap(err, "encode message failed")
+	}
+	r.record(RTMRecordOutbound, rawMessage)
+
+	return nil
+}
+
+func (r *RTMRecorder) ReadC() (chan RTMMessage, error) {
+	if r.State() != RTMLoopStateOpen {
+		return nil, ErrRTMLoopClosed
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.rtmC, nil
+}
+
+func (r *RTMRecorder) ErrC() chan error {
+	return r.loop.ErrC()
+}
+
+// tee records messages from messageC and forwards them to rtmC until
+// stopped or messageC is closed.
+func (r *RTMRecorder) tee(messageC, rtmC chan RTMMessage, stopC chan struct{}) {
+	defer close(rtmC)
+
+	for {
+		select {
+		case <-stopC:
+			return
+		case m, ok := <-messageC:
+			if !ok {
+				return
+			}
+			rawMessage, ok := m[JSONRawTag].([]byte)
+			if !ok {
+				var err error
+				if rawMessage, err = json.Marshal(m); err != nil {
+					r.fail(errors.Wrap(err, "encode message failed"))
+					continue
+				}
+			}
+			r.record(RTMRecordInbound, rawMessage)
+
+			select {
+			case <-stopC:
+				return
+			case rtmC <- m:
+			}
+		}
+	}
+}
+
+func (r *RTMRecorder) record(direction RTMRecordDirection, rawMessage []byte) {
+	line, err := json.Marshal(RTMRecord{
+		Time:      time.Now(),
+		Direction: direction,
+		Message:   rawMessage,
+	})
+	if err != nil {
+		r.fail(errors.Wrap(err, "encode record failed"))
+		return
+	}
+
+	r.wlock.Lock()
+	defer r.wlock.Unlock()
+
+	if _, err := r.w.Write(append(line, '\n')); err != nil {
+		r.fail(errors.Wrap(err, "write record failed"))
+	}
+}
+
+func (r *RTMRecorder) fail(err error) {
+	select {
+	case r.ErrC() <- err:
+	default:
+	}
+}
+
+// ReadRTMRecords reads a JSONL log written by RTMRecorder.
+func ReadRTMRecords(r io.Reader) ([]RTMRecord, error) {
+	var records []RTMRecord
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line = line + 1 {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record RTMRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, errors.Wrapf(err, "decode record at line %d failed", line)
+		}
+		records = append(records, record)
+	}
+
+	return records, scanner.Err()
+}
+
+func withoutJSONRaw(m RTMMessage) RTMMessage {
+	if _, present := m[JSONRawTag]; !present {
+		return m
+	}
+
+	copied := RTMMessage{}
+	for k, v := range m {
+		if k != JSONRawTag {
+			copied[k] = v
+		}
+	}
+	return copied
+}
+
+var _ RTMLoop = (*RTMRecorder)(nil)
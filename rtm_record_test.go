@@ -0,0 +1,215 @@
+package bearychat
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testRTMLoop is an in-memory RTMLoop, messages put to rtmC are read by clients.
+type testRTMLoop struct {
+	lock   sync.Mutex
+	state  RTMLoopState
+	sent   []RTMMessage
+	callId uint64
+
+	rtmC chan RTMMessage
+	errC chan error
+}
+
+func newTestRTMLoop() *testRTMLoop {
+	return &testRTMLoop{
+		state: RTMLoopStateClosed,
+		rtmC:  make(chan RTMMessage),
+		errC:  make(chan error, 1024),
+	}
+}
+
+func (l *testRTMLoop) Start() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.state = RTMLoopStateOpen
+	return nil
+}
+
+func (l *testRTMLoop) Stop() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.state = RTMLoopStateClosed
+	return nil
+}
+
+func (l *testRTMLoop) State() RTMLoopState {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.state
+}
+
+func (l *testRTMLoop) Ping() error { return l.Send(RTMMessage{"type": RTMMessageTypePing}) }
+
+func (l *testRTMLoop) Keepalive(interval *time.Ticker) error { return nil }
+
+func (l *testRTMLoop) Send(m RTMMessage) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.state != RTMLoopStateOpen {
+		return ErrRTMLoopClosed
+	}
+	if _, hasCallId := m["call_id"]; !hasCallId {
+		l.callId = l.callId + 1
+		m["call_id"] = l.callId
+	}
+	l.sent = append(l.sent, m)
+	return nil
+}
+
+func (l *testRTMLoop) ReadC() (chan RTMMessage, error) { return l.rtmC, nil }
+
+func (l *testRTMLoop) ErrC() chan error { return l.errC }
+
+// push sends message as read from socket.
+func (l *testRTMLoop) push(raw string) {
+	m := RTMMessage{}
+	json.Unmarshal([]byte(raw), &m)
+	m[JSONRawTag] = []byte(raw)
+	l.rtmC <- m
+}
+
+func TestRTMRecorder_Replay(t *testing.T) {
+	loop := newTestRTMLoop()
+	var log bytes.Buffer
+	recorder := NewRTMRecorder(loop, &log)
+	if err := recorder.Start(); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	messageC, _ := recorder.ReadC()
+
+	// echo bot
+	echoed := make(chan struct{})
+	go func() {
+		for m := range messageC {
+			if m.IsChatMessage() {
+				recorder.Send(m.Reply("echo: " + m["text"].(string)))
+				echoed <- struct{}{}
+			}
+		}
+	}()
+
+	loop.push(`{"type":"message","uid":"=u1","vchannel_id":"=vc1","text":"a"}`)
+	<-echoed
+	loop.push(`{"type":"update_user_connection","data":{"uid":"=u1"}}`)
+	loop.push(`{"type":"message","uid":"=u1","vchannel_id":"=vc1","text":"b"}`)
+	<-echoed
+	recorder.Stop()
+
+	records, err := ReadRTMRecords(bytes.NewReader(log.Bytes()))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if len(records) != 5 || records[0].Direction != RTMRecordInbound || string(records[0].Message) != `{"type":"message","uid":"=u1","vchannel_id":"=vc1","text":"a"}` {
+		t.Fatalf("unexpected records: %s", log.String())
+	}
+
+	replayer, err := NewRTMReplayerFromRecords(records, WithRTMReplaySpeed(0))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	replayer.Start()
+	replayC, _ := replayer.ReadC()
+
+	var types []RTMMessageType
+	for {
+		select {
+		case m := <-replayC:
+			types = append(types, m.Type())
+			if _, ok := m[JSONRawTag].([]byte); !ok {
+				t.Errorf("raw message should be kept: %+v", m)
+			}
+			if m.IsChatMessage() {
+				replayer.Send(m.Reply("echo: " + m["text"].(string)))
+			}
+			continue
+		case <-replayer.Done():
+		}
+		break
+	}
+
+	expected := []RTMMessageType{RTMMessageTypeP2PMessage, RTMMessageTypeUpdateUserConnection, RTMMessageTypeP2PMessage}
+	if !reflect.DeepEqual(types, expected) {
+		t.Errorf("expected %v, got %v", expected, types)
+	}
+
+	sent, outbound := replayer.Sent(), replayer.Outbound()
+	if len(sent) != 2 || len(outbound) != 2 {
+		t.Fatalf("unexpected sent %v, outbound %v", sent, outbound)
+	}
+	for i := range sent {
+		if sent[i]["text"] != outbound[i]["text"] {
+			t.Errorf("replay differs from record: %v %v", sent[i], outbound[i])
+		}
+	}
+}
+
+func TestRTMRecorder_Stop(t *testing.T) {
+	closed := func(messageC chan RTMMessage) bool {
+		timeout := time.After(time.Second)
+		for {
+			select {
+			case _, ok := <-messageC:
+				if !ok {
+					return true
+				}
+			case <-timeout:
+				return false
+			}
+		}
+	}
+
+	// unread messages don't block stopping
+	loop := newTestRTMLoop()
+	recorder := NewRTMRecorder(loop, &bytes.Buffer{})
+	recorder.Start()
+	messageC, _ := recorder.ReadC()
+	loop.push(`{"type":"message","uid":"=u1","vchannel_id":"=vc1","text":"unread"}`)
+	recorder.Stop()
+	if !closed(messageC) {
+		t.Errorf("expected read channel closed on stop")
+	}
+
+	// closed by the wrapped loop
+	loop = newTestRTMLoop()
+	recorder = NewRTMRecorder(loop, &bytes.Buffer{})
+	recorder.Start()
+	messageC, _ = recorder.ReadC()
+	close(loop.rtmC)
+	if !closed(messageC) {
+		t.Errorf("expected read channel closed with the loop's")
+	}
+}
+
+func TestRTMReplayer_Speed(t *testing.T) {
+	now := time.Now()
+	records := []RTMRecord{
+		{Time: now, Direction: RTMRecordInbound, Message: json.RawMessage(`{"type":"ping"}`)},
+		{Time: now.Add(time.Second), Direction: RTMRecordInbound, Message: json.RawMessage(`{"type":"pong"}`)},
+	}
+
+	replayer, _ := NewRTMReplayerFromRecords(records, WithRTMReplaySpeed(20))
+	replayer.Start()
+	defer replayer.Stop()
+	replayC, _ := replayer.ReadC()
+
+	start := time.Now()
+	<-replayC
+	<-replayC
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond || elapsed > 500*time.Millisecond {
+		t.Errorf("unexpected replay duration: %s", elapsed)
+	}
+
+	if _, err := NewRTMReplayerFromRecords(records, WithRTMReplaySpeed(-1)); err == nil {
+		t.Errorf("expected error for negative speed")
+	}
+}
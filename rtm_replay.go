@@ -0,0 +1,228 @@
+package bearychat
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DEFAULT_RTM_REPLAY_SPEED = 1.0
+)
+
+// RTMReplayer implements RTMLoop by feeding inbound messages of a recorded
+// session, with the recorded intervals scaled by speed. Messages sent are
+// collected instead, check them with Sent.
+//
+//      f, _ := os.Open("session.jsonl")
+//      replayer, _ := NewRTMReplayer(f, WithRTMReplaySpeed(10))
+//      replayer.Start()
+//      messageC, _ := replayer.ReadC()
+//      go bot.Handle(replayer, messageC)
+//      <-replayer.Done()
+type RTMReplayer struct {
+	records []RTMRecord
+	speed   float64
+
+	lock  sync.Mutex // lock for properties below
+	state RTMLoopState
+	sent  []RTMMessage
+	stopC chan struct{}
+	doneC chan struct{}
+
+	rtmC chan RTMMessage
+	errC chan error
+}
+
+type rtmReplayerSetter func(*RTMReplayer) error
+
+// WithRTMReplaySpeed sets replay speed, 1 for real time, 10 for 10x as fast,
+// and 0 for no delay between messages.
+func WithRTMReplaySpeed(speed float64) rtmReplayerSetter {
+	return func(r *RTMReplayer) error {
+		if speed < 0 {
+			return errors.New("replay speed should not be negative")
+		}
+		r.speed = speed
+		return nil
+	}
+}
+
+// NewRTMReplayer creates a replayer of records read from r.
+func NewRTMReplayer(r io.Reader, setters ...rtmReplayerSetter) (*RTMReplayer, error) {
+	records, err := ReadRTMRecords(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRTMReplayerFromRecords(records, setters...)
+}
+
+// NewRTMReplayerFromRecords creates a replayer of records.
+func NewRTMReplayerFromRecords(records []RTMRecord, setters ...rtmReplayerSetter) (*RTMReplayer, error) {
+	r := &RTMReplayer{
+		records: records,
+		speed:   DEFAULT_RTM_REPLAY_SPEED,
+		state:   RTMLoopStateClosed,
+		doneC:   make(chan struct{}),
+
+		rtmC: make(chan RTMMessage),
+		errC: make(chan error, 1024),
+	}
+	for _, setter := range setters {
+		if err := setter(r); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// Start starts replaying inbound messages.
+func (r *RTMReplayer) Start() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.stopC != nil {
+		return errors.New("replayer already started")
+	}
+	r.state = RTMLoopStateOpen
+	r.stopC = make(chan struct{})
+
+	go r.replay(r.stopC)
+
+	return nil
+}
+
+func (r *RTMReplayer) Stop() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.state == RTMLoopStateClosed {
+		return nil
+	}
+	r.state = RTMLoopStateClosed
+	close(r.stopC)
+
+	return nil
+}
+
+func (r *RTMReplayer) State() RTMLoopState {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.state
+}
+
+func (r *RTMReplayer) Ping() error {
+	return r.Send(RTMMessage{"type": RTMMessageTypePing})
+}
+
+func (r *RTMReplayer) Keepalive(interval *time.Ticker) error {
+	return keepalive(r.Ping, interval)
+}
+
+// Send collects message.
+func (r *RTMReplayer) Send(m RTMMessage) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.state != RTMLoopStateOpen {
+		return ErrRTMLoopClosed
+	}
+	r.sent = append(r.sent, m)
+
+	return nil
+}
+
+func (r *RTMReplayer) ReadC() (chan RTMMessage, error) {
+	if r.State() != RTMLoopStateOpen {
+		return nil, ErrRTMLoopClosed
+	}
+
+	return r.rtmC, nil
+}
+
+func (r *RTMReplayer) ErrC() chan error {
+	return r.errC
+}
+
+// Done is closed after all inbound messages are read or replayer is stopped.
+func (r *RTMReplayer) Done() <-chan struct{} {
+	return r.doneC
+}
+
+// Sent returns messages sent during replay, pings excluded.
+func (r *RTMReplayer) Sent() []RTMMessage {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	sent := make([]RTMMessage, 0, len(r.sent))
+	for _, m := range r.sent {
+		if m.Type() != RTMMessageTypePing {
+			sent = append(sent, m)
+		}
+	}
+	return sent
+}
+
+// Outbound returns messages sent in the recorded session, pings excluded,
+// to compare with Sent.
+func (r *RTMReplayer) Outbound() []RTMMessage {
+	var outbound []RTMMessage
+	for _, record := range r.records {
+		if record.Direction != RTMRecordOutbound {
+			continue
+		}
+		m := RTMMessage{}
+		if err := json.Unmarshal(record.Message, &m); err != nil {
+			continue
+		}
+		if m.Type() != RTMMessageTypePing {
+			outbound = append(outbound, m)
+		}
+	}
+	return outbound
+}
+
+func (r *RTMReplayer) replay(stopC chan struct{}) {
+	defer close(r.doneC)
+
+	var last time.Time
+	for _, record := range r.records {
+		if record.Direction != RTMRecordInbound {
+			continue
+		}
+
+		if !last.IsZero() && r.speed > 0 {
+			delay := time.Duration(float64(record.Time.Sub(last)) / r.speed)
+			if delay > 0 {
+				select {
+				case <-stopC:
+					return
+				case <-time.After(delay):
+				}
+			}
+		}
+		last = record.Time
+
+		message := RTMMessage{}
+		if err := json.Unmarshal(record.Message, &message); err != nil {
+			r.errC <- errors.Wrap(err, "decode message failed")
+			continue
+		}
+		// store raw message for later use
+		message[JSONRawTag] = []byte(record.Message)
+
+		select {
+		case <-stopC:
+			return
+		case r.rtmC <- message:
+		}
+	}
+}
+
+var _ RTMLoop = (*RTMReplayer)(nil)
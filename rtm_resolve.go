@@ -0,0 +1,182 @@
+package bearychat
+
+import (
+	"context"
+	"strings"
+
+	"github.com/nanmu42/bearychat-go/openapi"
+	"github.com/pkg/errors"
+)
+
+// Name resolution over Directory, mapping names used in configs to ids:
+//
+//      #ops              -> channel
+//      @alice            -> user
+//      alice@example.com -> user
+//      =bw52O            -> user or channel id as is
+//
+// Errors are *openapi.ResolveError, check them with openapi.IsNotFound
+// and openapi.IsAmbiguous.
+
+// ResolveUser resolves `@name`, email or user id to user.
+func (d *Directory) ResolveUser(ref string) (*User, error) {
+	ref = strings.TrimSpace(ref)
+
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	var exact, fold []*User
+	switch {
+	case strings.HasPrefix(ref, "@"):
+		name := ref[1:]
+		for _, u := range d.users {
+			if u.Name == name {
+				exact = append(exact, u)
+			} else if strings.EqualFold(u.Name, name) {
+				fold = append(fold, u)
+			}
+		}
+	case strings.Contains(ref, "@"):
+		if u, present := d.usersByEmail[strings.ToLower(ref)]; present {
+			exact = append(exact, u)
+		}
+	default:
+		if u, present := d.users[ref]; present {
+			exact = append(exact, u)
+		}
+	}
+
+	if len(exact) == 1 {
+		user := *exact[0]
+		return &user, nil
+	}
+	candidates := append(exact, fold...)
+	switch len(candidates) {
+	case 0:
+		return nil, &openapi.ResolveError{Kind: openapi.ResolveErrorNotFound, Ref: ref}
+	case 1:
+		user := *candidates[0]
+		return &user, nil
+	}
+
+	ids := make([]string, 0, len(candidates))
+	for _, u := range candidates {
+		ids = append(ids, u.Id)
+	}
+	return nil, &openapi.ResolveError{Kind: openapi.ResolveErrorAmbiguous, Ref: ref, Candidates: ids}
+}
+
+// ResolveChannel resolves `#name`, channel id or vchannel id to channel.
+func (d *Directory) ResolveChannel(ref string) (*Channel, error) {
+	ref = strings.TrimSpace(ref)
+
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	var exact, fold []*Channel
+	if strings.HasPrefix(ref, "#") {
+		name := ref[1:]
+		for _, c := range d.channels {
+			if c.Name == name {
+				exact = append(exact, c)
+			} else if strings.EqualFold(c.Name, name) {
+				fold = append(fold, c)
+			}
+		}
+	} else if c, present := d.channels[ref]; present {
+		exact = append(exact, c)
+	} else if c, present := d.channelsByVChannel[ref]; present {
+		exact = append(exact, c)
+	}
+
+	if len(exact) == 1 {
+		channel := *exact[0]
+		return &channel, nil
+	}
+	candidates := append(exact, fold...)
+	switch len(candidates) {
+	case 0:
+		return nil, &openapi.ResolveError{Kind: openapi.ResolveErrorNotFound, Ref: ref}
+	case 1:
+		channel := *candidates[0]
+		return &channel, nil
+	}
+
+	ids := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		ids = append(ids, c.Id)
+	}
+	return nil, &openapi.ResolveError{Kind: openapi.ResolveErrorAmbiguous, Ref: ref, Candidates: ids}
+}
+
+// ResolveVChannelID resolves `#channel`, `@user`, email or id to vchannel id
+// messages can be sent to, users resolve to their p2p vchannel, which is
+// created with p2p service set by WithDirectoryP2P if not yet.
+// Unknown ids are returned as is, as they may refer to other vchannels,
+// other unknown refs are not found.
+func (d *Directory) ResolveVChannelID(ref string) (string, error) {
+	return d.ResolveVChannelIDContext(context.Background(), ref)
+}
+
+// ResolveVChannelIDContext is ResolveVChannelID creating p2p with ctx.
+func (d *Directory) ResolveVChannelIDContext(ctx context.Context, ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+
+	if strings.HasPrefix(ref, "#") {
+		channel, err := d.ResolveChannel(ref)
+		if err != nil {
+			return "", err
+		}
+		return channel.VChannelId, nil
+	}
+	if strings.Contains(ref, "@") {
+		user, err := d.ResolveUser(ref)
+		if err != nil {
+			return "", err
+		}
+		return d.p2pVChannelID(ctx, ref, user)
+	}
+
+	if channel, err := d.ResolveChannel(ref); err == nil {
+		return channel.VChannelId, nil
+	}
+	if user, err := d.ResolveUser(ref); err == nil {
+		return d.p2pVChannelID(ctx, ref, user)
+	}
+
+	if !openapi.IsID(ref) {
+		return "", &openapi.ResolveError{Kind: openapi.ResolveErrorNotFound, Ref: ref}
+	}
+	return ref, nil
+}
+
+// p2pVChannelID returns p2p vchannel id of user, creates it if not yet.
+func (d *Directory) p2pVChannelID(ctx context.Context, ref string, user *User) (string, error) {
+	if user.VChannelId != "" {
+		return user.VChannelId, nil
+	}
+
+	d.lock.RLock()
+	vchannelID, present := d.p2pVChannels[user.Id]
+	d.lock.RUnlock()
+	if present {
+		return vchannelID, nil
+	}
+
+	if d.p2p == nil {
+		return "", &openapi.ResolveError{Kind: openapi.ResolveErrorNotFound, Ref: ref}
+	}
+	p2p, _, err := d.p2p.Create(ctx, &openapi.P2PCreateOptions{UserID: user.Id})
+	if err != nil {
+		return "", errors.Wrapf(err, "create p2p with %s failed", user.Id)
+	}
+	if p2p.VChannelID == nil || *p2p.VChannelID == "" {
+		return "", &openapi.ResolveError{Kind: openapi.ResolveErrorNotFound, Ref: ref}
+	}
+
+	d.lock.Lock()
+	d.p2pVChannels[user.Id] = *p2p.VChannelID
+	d.lock.Unlock()
+
+	return *p2p.VChannelID, nil
+}
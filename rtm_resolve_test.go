@@ -0,0 +1,84 @@
+package bearychat
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nanmu42/bearychat-go/openapi"
+	"github.com/nanmu42/bearychat-go/openapi/openapimock"
+)
+
+func TestDirectory_Resolve(t *testing.T) {
+	server := newTestDirectoryServer()
+	defer server.Close()
+
+	client, _ := NewRTMClient(testRTMToken, WithRTMAPIBase(server.URL))
+	d, _ := NewDirectory(client)
+
+	if u, err := d.ResolveUser("@Alice"); err != nil || u.Id != "=bw52O" {
+		t.Errorf("unexpected user: %+v %+v", u, err)
+	}
+	if u, err := d.ResolveUser("BOB@example.com"); err != nil || u.Id != "=bw52P" {
+		t.Errorf("unexpected user: %+v %+v", u, err)
+	}
+	if _, err := d.ResolveUser("@carol"); !openapi.IsNotFound(err) {
+		t.Errorf("expected not found: %+v", err)
+	}
+	if c, err := d.ResolveChannel("#ops"); err != nil || c.Id != "=ch1" {
+		t.Errorf("unexpected channel: %+v %+v", c, err)
+	}
+
+	cases := map[string]string{
+		"#ops":            "=vc3",
+		"@bob":            "=vc2",
+		"=bw52O":          "=vc1",
+		"=ch1":            "=vc3",
+		"=unknown":        "=unknown",
+		"bob@example.com": "=vc2",
+	}
+	for ref, expected := range cases {
+		if vchannelID, err := d.ResolveVChannelID(ref); err != nil || vchannelID != expected {
+			t.Errorf("expected %s for %s, got %s %+v", expected, ref, vchannelID, err)
+		}
+	}
+	for _, ref := range []string{"#missing", "ops", "typo", "=bad id"} {
+		if _, err := d.ResolveVChannelID(ref); !openapi.IsNotFound(err) {
+			t.Errorf("expected not found for %s: %+v", ref, err)
+		}
+	}
+}
+
+func TestDirectory_ResolveVChannelID_CreateP2P(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/current_team.members":
+			w.Write([]byte(`{"code":0,"result":[{"id":"=bw52Q","name":"carol"}]}`))
+		case "/v1/current_team.channels":
+			w.Write([]byte(`{"code":0,"result":[]}`))
+		}
+	}))
+	defer server.Close()
+	client, _ := NewRTMClient(testRTMToken, WithRTMAPIBase(server.URL))
+
+	d, _ := NewDirectory(client)
+	if _, err := d.ResolveVChannelID("@carol"); !openapi.IsNotFound(err) {
+		t.Errorf("expected not found without p2p service: %+v", err)
+	}
+
+	api := openapimock.New()
+	api.P2P.CreateFunc = func(ctx context.Context, opt *openapi.P2PCreateOptions) (*openapi.P2P, *http.Response, error) {
+		vchannelID := "=vc-" + opt.UserID
+		return &openapi.P2P{VChannelID: &vchannelID}, nil, nil
+	}
+	d, _ = NewDirectory(client, WithDirectoryP2P(api.P2P))
+	for i := 0; i < 2; i++ {
+		if vchannelID, err := d.ResolveVChannelIDContext(context.Background(), "@carol"); err != nil || vchannelID != "=vc-=bw52Q" {
+			t.Errorf("unexpected vchannel id: %s %+v", vchannelID, err)
+		}
+	}
+	if calls := api.CallsOf("Create"); len(calls) != 1 {
+		t.Errorf("expected p2p created once, got %+v", calls)
+	}
+}
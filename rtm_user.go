@@ -1,6 +1,9 @@
 package bearychat
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 type RTMUserService struct {
 	rtm *RTMClient
@@ -12,8 +15,12 @@ func newRTMUserService(rtm *RTMClient) error {
 }
 
 func (s *RTMUserService) Info(userId string) (*User, error) {
+	return s.InfoContext(context.Background(), userId)
+}
+
+func (s *RTMUserService) InfoContext(ctx context.Context, userId string) (*User, error) {
 	user := new(User)
 	resource := fmt.Sprintf("v1/user.info?user_id=%s", userId)
-	_, err := s.rtm.Get(resource, user)
+	_, err := s.rtm.GetContext(ctx, resource, user)
 	return user, err
 }
@@ -0,0 +1,143 @@
+package trace_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	bearychat "github.com/nanmu42/bearychat-go"
+	"github.com/nanmu42/bearychat-go/bearychattest"
+	"github.com/nanmu42/bearychat-go/openapi"
+	"github.com/nanmu42/bearychat-go/trace"
+)
+
+func TestRTMContext(t *testing.T) {
+	server := bearychattest.NewServer()
+	defer server.Close()
+	tracer := trace.NewRecorder()
+
+	api := openapi.NewClient(
+		server.Token,
+		openapi.NewClientWithBaseURL(server.OpenAPIBaseURL()),
+		openapi.NewClientWithTracer(tracer),
+	)
+	c, err := bearychat.NewRTMContext(
+		server.Token,
+		bearychat.WithRTMContextAPIBase(server.RTMAPIBase()),
+		bearychat.WithRTMContextTracer(tracer),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	replied := make(chan error, 1)
+	c.HandleChat(func(ctx context.Context, c *bearychat.RTMContext, m bearychat.RTMMessage) {
+		if _, _, err := api.Team.Info(ctx); err != nil {
+			replied <- err
+			return
+		}
+		if _, err := c.Client().User.InfoContext(ctx, bearychattest.DEFAULT_USER_ID); err != nil {
+			replied <- err
+			return
+		}
+		replied <- c.SendContext(ctx, m.Refer("pong"))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server.Script(bearychat.RTMMessage{
+		"type":        bearychat.RTMMessageTypeP2PMessage,
+		"uid":         "=u1",
+		"vchannel_id": "=vc1",
+		"text":        "@<=bot=> ping",
+	})
+	go c.Run(ctx)
+
+	select {
+	case err := <-replied:
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected reply")
+	}
+	cancel()
+
+	handled := tracer.Find("bearychat.rtm.handle")
+	if len(handled) != 1 || handled[0].Attributes["uid"] != "=u1" || !handled[0].Ended {
+		t.Fatalf("unexpected handle spans: %+v", handled)
+	}
+	requested := tracer.Find("bearychat.openapi.request")
+	if len(requested) != 1 || requested[0].ParentID != handled[0].ID || requested[0].Attributes["endpoint"] != "team.info" {
+		t.Errorf("unexpected request spans: %+v", requested)
+	}
+	sent := tracer.Find("bearychat.rtm.send")
+	if len(sent) != 1 || sent[0].ParentID != handled[0].ID || sent[0].Err != nil {
+		t.Errorf("unexpected send spans: %+v", sent)
+	}
+	rtmRequested := tracer.Find("bearychat.rtm.request")
+	if len(rtmRequested) != 2 {
+		t.Fatalf("unexpected rtm request spans: %+v", rtmRequested)
+	}
+	if started := rtmRequested[0]; started.ParentID != 0 || started.Attributes["endpoint"] != "start" {
+		t.Errorf("unexpected rtm start span: %+v", started)
+	}
+	if info := rtmRequested[1]; info.ParentID != handled[0].ID || info.Attributes["endpoint"] != "v1/user.info" {
+		t.Errorf("unexpected rtm request span: %+v", info)
+	}
+
+	// error response has url with token
+	tracer.Reset()
+	api.Channel.Info(context.Background(), &openapi.ChannelInfoOptions{ChannelID: "=missing"})
+	requested = tracer.Find("bearychat.openapi.request")
+	if len(requested) != 1 || requested[0].Err == nil || strings.Contains(requested[0].Err.Error(), server.Token) {
+		t.Errorf("unexpected request spans: %+v", requested)
+	}
+}
+
+func TestOutgoingRouter(t *testing.T) {
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":0}`))
+	}))
+	defer webhook.Close()
+	tracer := trace.NewRecorder()
+
+	release := make(chan struct{})
+	router, _ := bearychat.NewOutgoingRouter(
+		bearychat.WithRouterTimeout(10*time.Millisecond),
+		bearychat.WithRouterAsyncClient(bearychat.NewIncomingWebhookClient(
+			webhook.URL,
+			bearychat.WithWebhookTracer(tracer),
+		)),
+		bearychat.WithRouterTracer(tracer),
+	)
+	router.Handle("!slow", func(ctx context.Context, c bearychat.OutgoingCommand) (*bearychat.Incoming, error) {
+		<-release
+		return &bearychat.Incoming{Text: "done"}, nil
+	})
+
+	router.ServeOutgoing(context.Background(), bearychat.Outgoing{TriggerWord: "!slow", ChannelName: "ops"})
+	close(release)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		handled := tracer.Find("bearychat.outgoing.handle")
+		if len(handled) == 1 && handled[0].Ended {
+			if handled[0].Attributes["trigger_word"] != "!slow" || handled[0].Attributes["timeout"] != true {
+				t.Errorf("unexpected handle span: %+v", handled[0])
+			}
+			sent := tracer.Find("bearychat.webhook.send")
+			if len(sent) != 1 || sent[0].ParentID != handled[0].ID || sent[0].Attributes["http.status_code"] != http.StatusOK {
+				t.Errorf("unexpected send spans: %+v", sent)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected handle span ended, got %+v", tracer.Spans())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,131 @@
+package trace
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Recorder is a Tracer keeping spans in memory, for tests.
+//
+//      tracer := trace.NewRecorder()
+//      client := openapi.NewClient(token, openapi.NewClientWithTracer(tracer))
+//      ...
+//      spans := tracer.Spans()
+type Recorder struct {
+	lock   sync.Mutex // lock for properties below
+	spans  []*RecordedSpan
+	nextID int
+}
+
+// RecordedSpan is a span started by Recorder.
+type RecordedSpan struct {
+	recorder *Recorder
+
+	ID int
+	// ID of parent span, 0 for root span
+	ParentID   int
+	Name       string
+	Attributes map[string]interface{}
+	Err        error
+	StartTime  time.Time
+	EndTime    time.Time
+	Ended      bool
+}
+
+type recordedSpanKey struct{}
+
+// NewRecorder creates a Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Start implements Tracer.
+func (r *Recorder) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.nextID = r.nextID + 1
+	span := &RecordedSpan{
+		recorder:   r,
+		ID:         r.nextID,
+		Name:       name,
+		Attributes: map[string]interface{}{},
+		StartTime:  time.Now(),
+	}
+	if parent, ok := ctx.Value(recordedSpanKey{}).(*RecordedSpan); ok {
+		span.ParentID = parent.ID
+	}
+	for _, attr := range attrs {
+		span.Attributes[attr.Key] = attr.Value
+	}
+	r.spans = append(r.spans, span)
+
+	return context.WithValue(ctx, recordedSpanKey{}, span), span
+}
+
+// Spans returns copies of started spans in order.
+func (r *Recorder) Spans() []RecordedSpan {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	spans := make([]RecordedSpan, 0, len(r.spans))
+	for _, span := range r.spans {
+		copied := *span
+		copied.Attributes = map[string]interface{}{}
+		for k, v := range span.Attributes {
+			copied.Attributes[k] = v
+		}
+		spans = append(spans, copied)
+	}
+	return spans
+}
+
+// Find returns copies of spans named name.
+func (r *Recorder) Find(name string) []RecordedSpan {
+	var found []RecordedSpan
+	for _, span := range r.Spans() {
+		if span.Name == name {
+			found = append(found, span)
+		}
+	}
+	return found
+}
+
+// Reset drops started spans.
+func (r *Recorder) Reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.spans = nil
+}
+
+// SetAttributes implements Span.
+func (s *RecordedSpan) SetAttributes(attrs ...Attribute) {
+	s.recorder.lock.Lock()
+	defer s.recorder.lock.Unlock()
+
+	for _, attr := range attrs {
+		s.Attributes[attr.Key] = attr.Value
+	}
+}
+
+// RecordError implements Span.
+func (s *RecordedSpan) RecordError(err error) {
+	s.recorder.lock.Lock()
+	defer s.recorder.lock.Unlock()
+
+	s.Err = err
+}
+
+// End implements Span.
+func (s *RecordedSpan) End() {
+	s.recorder.lock.Lock()
+	defer s.recorder.lock.Unlock()
+
+	if s.Ended {
+		return
+	}
+	s.Ended = true
+	s.EndTime = time.Now()
+}
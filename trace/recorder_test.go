@@ -0,0 +1,49 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder()
+
+	ctx, parent := r.Start(context.Background(), "parent", Attr("k", "v"))
+	_, child := r.Start(ctx, "child")
+	child.RecordError(errors.New("failed"))
+	child.End()
+	parent.SetAttributes(Attr("k", "updated"))
+	parent.End()
+	parent.End()
+
+	spans := r.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("unexpected spans: %+v", spans)
+	}
+	if spans[0].Name != "parent" || spans[0].ParentID != 0 || spans[0].Attributes["k"] != "updated" || !spans[0].Ended {
+		t.Errorf("unexpected parent: %+v", spans[0])
+	}
+	if spans[1].ParentID != spans[0].ID || spans[1].Err == nil || !spans[1].Ended {
+		t.Errorf("unexpected child: %+v", spans[1])
+	}
+	if found := r.Find("child"); len(found) != 1 || found[0].ID != spans[1].ID {
+		t.Errorf("unexpected found: %+v", found)
+	}
+
+	r.Reset()
+	if len(r.Spans()) != 0 {
+		t.Errorf("expected spans dropped")
+	}
+}
+
+func TestNop(t *testing.T) {
+	ctx := context.Background()
+	spanCtx, span := Nop.Start(ctx, "nop", Attr("k", "v"))
+	span.SetAttributes(Attr("k", "v"))
+	span.RecordError(errors.New("failed"))
+	span.End()
+	if spanCtx != ctx {
+		t.Errorf("expected ctx unchanged")
+	}
+}
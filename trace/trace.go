@@ -0,0 +1,53 @@
+// Package trace defines the tracer interface called by bearychat clients and
+// handlers, so a bot's work can be correlated in a tracing backend:
+//
+//      bearychat.rtm.handle           message received, handler called
+//        bearychat.openapi.request    api called by handler with its ctx
+//        bearychat.rtm.send           reply sent with RTMContext.SendContext
+//
+// Spans are linked by context.Context, adapt Tracer to a tracing library
+// like OpenTelemetry to export them. Tracers default to Nop.
+package trace
+
+import "context"
+
+// Attribute is a key value pair attached to span.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Attr creates an Attribute.
+func Attr(key string, value interface{}) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Tracer starts spans.
+type Tracer interface {
+	// Start starts a span as child of span in ctx (if any), and returns
+	// ctx with the new span.
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span is a traced operation.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	// End ends the span, calls after the first one are ignored.
+	End()
+}
+
+// Nop is a Tracer tracing nothing, used by default.
+var Nop Tracer = nop{}
+
+type nop struct{}
+
+func (nop) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttributes(attrs ...Attribute) {}
+func (nopSpan) RecordError(err error)            {}
+func (nopSpan) End()                             {}
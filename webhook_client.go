@@ -1,10 +1,17 @@
 package bearychat
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/nanmu42/bearychat-go/metrics"
+	"github.com/nanmu42/bearychat-go/trace"
 )
 
 // WebhookResponse represents a response.
@@ -31,21 +38,65 @@ type WebhookClient interface {
 	Send(payload io.Reader) (*WebhookResponse, error)
 }
 
+// WebhookContextSender is implemented by WebhookClient sending with
+// context, like the default one which traces sending.
+//
+//      if sender, ok := client.(bearychat.WebhookContextSender); ok {
+//              resp, err = sender.SendContext(ctx, payload)
+//      }
+type WebhookContextSender interface {
+	SendContext(ctx context.Context, payload io.Reader) (*WebhookResponse, error)
+}
+
 type webhookClient struct {
 	httpClient *http.Client
+	logger     Logger
+	metrics    metrics.Metrics
+	tracer     trace.Tracer
 
 	Webhook string
 }
 
+type webhookClientSetter func(*webhookClient)
+
+// WithWebhookLogger sets logger for sending, logs nothing by default.
+func WithWebhookLogger(logger Logger) webhookClientSetter {
+	return func(w *webhookClient) {
+		w.logger = logger
+	}
+}
+
+// WithWebhookMetrics sets metrics for sending, records nothing by default.
+func WithWebhookMetrics(m metrics.Metrics) webhookClientSetter {
+	return func(w *webhookClient) {
+		w.metrics = m
+	}
+}
+
+// WithWebhookTracer sets tracer for sending, traces nothing by default.
+func WithWebhookTracer(tracer trace.Tracer) webhookClientSetter {
+	return func(w *webhookClient) {
+		w.tracer = tracer
+	}
+}
+
 // Creates a new incoming webhook client.
 //
 // For full documentation, visit https://bearychat.com/integrations/incoming .
-func NewIncomingWebhookClient(webhook string) *webhookClient {
-	return &webhookClient{
+func NewIncomingWebhookClient(webhook string, setters ...webhookClientSetter) *webhookClient {
+	w := &webhookClient{
 		httpClient: http.DefaultClient,
+		logger:     nopLogger,
+		metrics:    metrics.Nop,
+		tracer:     trace.Nop,
 
 		Webhook: webhook,
 	}
+	for _, setter := range setters {
+		setter(w)
+	}
+
+	return w
 }
 
 func (w *webhookClient) SetWebhook(webhook string) WebhookClient {
@@ -59,6 +110,25 @@ func (w *webhookClient) SetHTTPClient(c *http.Client) WebhookClient {
 }
 
 func (w *webhookClient) Send(payload io.Reader) (*WebhookResponse, error) {
+	return w.SendContext(context.Background(), payload)
+}
+
+// SendContext sends webhook payload with ctx, which is also traced as parent span.
+func (w *webhookClient) SendContext(ctx context.Context, payload io.Reader) (resp *WebhookResponse, err error) {
+	ctx, span := w.tracer.Start(ctx, "bearychat.webhook.send")
+	defer func() {
+		if resp != nil {
+			span.SetAttributes(
+				trace.Attr("http.status_code", resp.StatusCode),
+				trace.Attr("code", resp.Code),
+			)
+		}
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
+	}()
+
 	if w.Webhook == "" {
 		return nil, errors.New("webhook url is required")
 	}
@@ -66,18 +136,65 @@ func (w *webhookClient) Send(payload io.Reader) (*WebhookResponse, error) {
 		return nil, errors.New("http client is required")
 	}
 
-	resp, err := w.httpClient.Post(w.Webhook, "application/json", payload)
+	// webhook url is a secret, only host is logged
+	host := ""
+	if u, err := url.Parse(w.Webhook); err == nil {
+		host = u.Host
+	}
+	w.logger.Debug("webhook request", "host", host)
+	start := time.Now()
+
+	// webhook url is in url errors, which are redacted
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.Webhook, payload)
+	if err != nil {
+		return nil, redactURLError(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	httpResp, err := w.httpClient.Do(req)
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(httpResp.StatusCode)
+	}
+	// webhook path is a secret, all webhooks share an endpoint
+	w.metrics.Observe(
+		"bearychat_http_request_duration_seconds",
+		time.Since(start).Seconds(),
+		"client", "webhook",
+		"endpoint", "incoming",
+		"status", status,
+	)
 	if err != nil {
+		// try to use context's error
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		err = redactURLError(err)
+		w.logger.Warn("webhook request failed", "host", host, "error", err)
 		return nil, err
 	}
 
-	defer resp.Body.Close()
+	defer httpResp.Body.Close()
 
 	webhookResponse := new(WebhookResponse)
-	webhookResponse.StatusCode = resp.StatusCode
-	if err := json.NewDecoder(resp.Body).Decode(webhookResponse); err != nil {
+	webhookResponse.StatusCode = httpResp.StatusCode
+	if err := json.NewDecoder(httpResp.Body).Decode(webhookResponse); err != nil {
+		w.logger.Warn("webhook response decode failed", "host", host, "status", httpResp.StatusCode, "error", err)
 		return nil, err
 	}
 
+	w.logger.Debug(
+		"webhook response",
+		"host", host,
+		"status", httpResp.StatusCode,
+		"code", webhookResponse.Code,
+		"duration", time.Since(start),
+	)
+	if !webhookResponse.IsOk() {
+		w.logger.Warn("webhook error response", "host", host, "status", httpResp.StatusCode, "code", webhookResponse.Code, "reason", webhookResponse.Error)
+	}
+
 	return webhookResponse, nil
 }
@@ -2,6 +2,8 @@ package bearychat
 
 import (
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -68,6 +70,30 @@ func TestIncomingWebhookClient_Send_WithoutHTTPClient(t *testing.T) {
 	}
 }
 
+func TestIncomingWebhookClient_Send_RedactsError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	webhook := server.URL + "/hook/secret-token"
+	server.Close()
+
+	h := NewIncomingWebhookClient(webhook)
+	_, err := h.Send(strings.NewReader("{}"))
+	if err == nil {
+		t.Fatalf("should fail to send to closed server")
+	}
+	if strings.Contains(err.Error(), "secret-token") {
+		t.Errorf("error should not contain webhook: %s", err)
+	}
+
+	h = NewIncomingWebhookClient("http://[::1/hook/secret-token")
+	_, err = h.Send(strings.NewReader("{}"))
+	if err == nil {
+		t.Fatalf("should fail to send to invalid webhook")
+	}
+	if strings.Contains(err.Error(), "secret-token") {
+		t.Errorf("error should not contain webhook: %s", err)
+	}
+}
+
 func ExampleNewIncomingWebhookClient() {
 	m := Incoming{Text: "Hello, BearyChat"}
 	payload, _ := m.Build()